@@ -0,0 +1,53 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/crossplane/crossplane-runtime/pkg/logging"
+	"gopkg.in/alecthomas/kingpin.v2"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+
+	"github.com/crossplane/terraform-provider-runtime/pkg/client"
+	"github.com/crossplane/terraform-provider-runtime/pkg/generator"
+	"github.com/crossplane/terraform-provider-runtime/pkg/options"
+)
+
+func main() {
+	var cfg generator.Config
+	app := kingpin.New(filepath.Base(os.Args[0]), "Generate managed resources for the resource types of a terraform provider.")
+	app.Flag("provider", "Name of the terraform provider, eg google.").Required().StringVar(&cfg.ProviderName)
+	app.Flag("output", "Directory the generated packages are written to.").Required().StringVar(&cfg.OutputDir)
+	app.Flag("package", "Go import path of the output directory.").Required().StringVar(&cfg.Package)
+	app.Flag("group", "API group of the generated resources.").StringVar(&cfg.Group)
+	app.Flag("api-version", "API version of the generated resources.").Default(generator.DefaultVersion).StringVar(&cfg.Version)
+	app.Flag("crd-dir", "Directory the CRDs are written to. Defaults to crds under the output directory.").StringVar(&cfg.CRDDir)
+	app.Flag("resource", "Terraform resource type to generate. Repeatable; every resource type is generated if unset.").StringsVar(&cfg.Resources)
+	flags := options.RegisterFlags(app)
+	kingpin.MustParse(app.Parse(os.Args[1:]))
+
+	opts, err := flags.Options()
+	kingpin.FatalIfError(err, "Cannot load options")
+	ropts := opts.RuntimeOptions().WithLogger(logging.NewLogrLogger(zap.New(zap.UseDevMode(opts.Logging.Debug))))
+	kingpin.FatalIfError(client.InstallMirroredPlugins(ropts, cfg.ProviderName), "Cannot install provider plugins from mirror")
+
+	schema, err := generator.ReadSchema(cfg.ProviderName, ropts)
+	kingpin.FatalIfError(err, "Cannot read the schema of provider %s", cfg.ProviderName)
+	kingpin.FatalIfError(generator.Generate(cfg, schema), "Cannot generate provider %s", cfg.ProviderName)
+}
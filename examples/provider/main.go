@@ -0,0 +1,53 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Command provider is a template for the main package of a provider built
+// on the runtime. It is not built into the runtime's binaries or images and
+// does not run as is: copy it into the provider and
+// replace the Index and ProviderInit with the ones of the provider, eg the
+// root package written by the generator and a hand written Initializer,
+// which reads the credentials of a Provider object and configures the
+// terraform provider with them:
+//
+//	idxr, err := generated.Indexer()
+//	// overlay hand written Implementations here
+//	idx, err := idxr.BuildIndex()
+//	p := &plugin.ProviderInit{
+//		ProviderName:  generated.ProviderName,
+//		SchemeBuilder: providerv1alpha1.SchemeBuilder,
+//		Initializer:   initializer,
+//	}
+//
+// controller.Run refuses to start with an empty Index or a ProviderInit
+// that cannot start providers.
+package main
+
+import (
+	"os"
+	"path/filepath"
+
+	"gopkg.in/alecthomas/kingpin.v2"
+
+	"github.com/crossplane/terraform-provider-runtime/pkg/controller"
+	"github.com/crossplane/terraform-provider-runtime/pkg/plugin"
+)
+
+func main() {
+	idx, err := plugin.NewIndexer().BuildIndex()
+	kingpin.FatalIfError(err, "Cannot build index")
+	p := &plugin.ProviderInit{}
+	kingpin.FatalIfError(controller.Run(filepath.Base(os.Args[0]), os.Args[1:], idx, p), "Cannot run provider")
+}
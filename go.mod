@@ -1,19 +1,32 @@
 module github.com/crossplane/terraform-provider-runtime
 
-go 1.13
+go 1.15
 
 require (
 	github.com/crossplane/crossplane v0.11.1
 	github.com/crossplane/crossplane-runtime v0.9.0
-	github.com/crossplane/crossplane-tools v0.0.0-20200412230150-efd0edd4565b
-	github.com/hashicorp/go-plugin v1.3.0
+	github.com/golang/protobuf v1.5.2
+	github.com/hashicorp/go-hclog v0.14.1
+	github.com/hashicorp/go-plugin v1.4.3
+	github.com/hashicorp/hcl/v2 v2.3.0
 	github.com/hashicorp/terraform v0.12.29
 	github.com/pkg/errors v0.9.1
 	github.com/zclconf/go-cty v1.5.1
+	go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc v0.25.0
+	go.opentelemetry.io/otel v1.0.1
+	go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc v1.0.1
+	go.opentelemetry.io/otel/exporters/stdout/stdouttrace v1.0.1
+	go.opentelemetry.io/otel/sdk v1.0.1
+	go.opentelemetry.io/otel/trace v1.0.1
+	golang.org/x/mod v0.2.0
+	golang.org/x/time v0.0.0-20191024005414-555d28b269f0
+	google.golang.org/api v0.29.0 // indirect; go-getter otherwise selects v0.9.0, which imports google.golang.org/grpc/naming, removed in grpc v1.41.0
+	google.golang.org/grpc v1.41.0
+	google.golang.org/protobuf v1.27.1
 	gopkg.in/alecthomas/kingpin.v2 v2.2.6
 	k8s.io/api v0.18.2
+	k8s.io/apiextensions-apiserver v0.18.2
 	k8s.io/apimachinery v0.18.2
 	sigs.k8s.io/controller-runtime v0.6.0
-	sigs.k8s.io/controller-tools v0.2.4
 	sigs.k8s.io/yaml v1.2.0
 )
@@ -0,0 +1,183 @@
+package api
+
+import (
+	"testing"
+
+	xpresource "github.com/crossplane/crossplane-runtime/pkg/resource"
+	xpfake "github.com/crossplane/crossplane-runtime/pkg/resource/fake"
+	"github.com/hashicorp/terraform/configs/configschema"
+	"github.com/hashicorp/terraform/providers"
+	"github.com/pkg/errors"
+	"github.com/zclconf/go-cty/cty"
+
+	"github.com/crossplane/terraform-provider-runtime/pkg/client/fake"
+	"github.com/crossplane/terraform-provider-runtime/pkg/plugin"
+	"github.com/crossplane/terraform-provider-runtime/pkg/plugin/plugintest"
+)
+
+const fakeResourceName = plugintest.ResourceName
+
+func schemaFixture() map[string]providers.Schema {
+	return map[string]providers.Schema{
+		fakeResourceName: {Block: &configschema.Block{
+			Attributes: map[string]*configschema.Attribute{
+				"id":   {Type: cty.String, Computed: true},
+				"name": {Type: cty.String, Required: true},
+				"size": {Type: cty.String, Optional: true},
+			},
+		}},
+	}
+}
+
+// invokerFixture returns an Invoker for the fake resource, with layers
+// Overlaid on top of the plugintest Implementation.
+func invokerFixture(t *testing.T, layers ...*plugin.Implementation) *plugin.Invoker {
+	gvk := plugintest.GVK
+	idxr := plugin.NewIndexer()
+	if err := idxr.Overlay(plugintest.Implementation()); err != nil {
+		t.Fatalf("Unexpected error from Overlay: %s", err)
+	}
+	for _, l := range layers {
+		l.GVK = gvk
+		if err := idxr.Overlay(l); err != nil {
+			t.Fatalf("Unexpected error from Overlay: %s", err)
+		}
+	}
+	idx, err := idxr.BuildIndex()
+	if err != nil {
+		t.Fatalf("Unexpected error from BuildIndex: %s", err)
+	}
+	inv, err := idx.InvokerForGVK(gvk)
+	if err != nil {
+		t.Fatalf("Unexpected error from InvokerForGVK: %s", err)
+	}
+	return inv
+}
+
+func resourceFixture(annotations map[string]string) xpresource.Managed {
+	res := &xpfake.Managed{}
+	res.SetName("test")
+	res.SetAnnotations(annotations)
+	return res
+}
+
+func TestCreateReadUpdateDelete(t *testing.T) {
+	fp := fake.NewProvider(schemaFixture())
+	p := fake.NewClientProvider("fake", fp)
+	inv := invokerFixture(t)
+
+	created, err := Create(p, inv, resourceFixture(map[string]string{"size": "small"}))
+	if err != nil {
+		t.Fatalf("Unexpected error from Create: %s", err)
+	}
+	id := created.GetAnnotations()["id"]
+	if id == "" {
+		t.Fatalf("Expected Create to decode the id assigned by the provider")
+	}
+
+	read, err := Read(p, inv, created)
+	if err != nil {
+		t.Fatalf("Unexpected error from Read: %s", err)
+	}
+	if read.GetAnnotations()["size"] != "small" {
+		t.Errorf("Expected Read to return the created state, got %v", read.GetAnnotations())
+	}
+
+	updated, err := Update(p, inv, resourceFixture(map[string]string{"id": id, "size": "large"}))
+	if err != nil {
+		t.Fatalf("Unexpected error from Update: %s", err)
+	}
+	if updated.GetAnnotations()["size"] != "large" {
+		t.Errorf("Expected Update to return the new state, got %v", updated.GetAnnotations())
+	}
+	if stored, _ := fp.Get(fakeResourceName, id); stored.GetAttr("size").AsString() != "large" {
+		t.Errorf("Expected Update to change the provider's state, got %s", stored.GoString())
+	}
+
+	if err := Delete(p, inv, updated); err != nil {
+		t.Fatalf("Unexpected error from Delete: %s", err)
+	}
+	if _, err := Read(p, inv, updated); err != ErrNotFound {
+		t.Errorf("Expected Read after Delete to return ErrNotFound, got %v", err)
+	}
+}
+
+func TestOperationsReturnProviderDiagnostics(t *testing.T) {
+	fp := fake.NewProvider(schemaFixture())
+	p := fake.NewClientProvider("fake", fp)
+	inv := invokerFixture(t)
+
+	fp.ScriptError("ApplyResourceChange", "quota exceeded")
+	if _, err := Create(p, inv, resourceFixture(nil)); err == nil {
+		t.Errorf("Expected Create to fail with the provider's error diagnostic")
+	}
+	fp.ScriptError("ReadResource", "permission denied")
+	if _, err := Read(p, inv, resourceFixture(map[string]string{"id": "fake-1"})); err == nil || err == ErrNotFound {
+		t.Errorf("Expected Read to fail with the provider's error diagnostic, got %v", err)
+	}
+	fp.ScriptError("GetSchema", "plugin crashed")
+	if _, err := SchemaForInvoker(p, inv); err == nil {
+		t.Errorf("Expected SchemaForInvoker to fail with the provider's error diagnostic")
+	}
+	if _, err := Create(p, inv, resourceFixture(nil)); err != nil {
+		t.Errorf("Expected scripted diagnostics to only apply to a single call, got %s", err)
+	}
+}
+
+func TestHooksRunAroundOperations(t *testing.T) {
+	fp := fake.NewProvider(schemaFixture())
+	p := fake.NewClientProvider("fake", fp)
+	var ops []plugin.Operation
+	var applied []string
+	veto := errors.New("protected")
+	inv := invokerFixture(t, &plugin.Implementation{Hooks: plugin.Hooks{
+		BeforeEncode: []plugin.BeforeEncodeHook{func(op plugin.Operation, r xpresource.Managed) error {
+			ops = append(ops, op)
+			// a server side default the generated code does not know about
+			if _, ok := r.GetAnnotations()["size"]; !ok {
+				r.SetAnnotations(map[string]string{"id": r.GetAnnotations()["id"], "size": "default"})
+			}
+			return nil
+		}},
+	}}, &plugin.Implementation{Hooks: plugin.Hooks{
+		AfterEncode: []plugin.AfterEncodeHook{func(op plugin.Operation, r xpresource.Managed, v cty.Value, s *providers.Schema) (cty.Value, error) {
+			if r.GetAnnotations()["size"] != "default" {
+				t.Errorf("Expected AfterEncode to see the resource changed by BeforeEncode in %s", op)
+			}
+			attrs := v.AsValueMap()
+			attrs["name"] = cty.StringVal("tagged-" + attrs["name"].AsString())
+			return cty.ObjectVal(attrs), nil
+		}},
+		AfterApply: []plugin.AfterApplyHook{func(op plugin.Operation, r xpresource.Managed, newState cty.Value) error {
+			applied = append(applied, string(op)+":"+newState.GetAttr("name").AsString())
+			return nil
+		}},
+		BeforeDelete: []plugin.BeforeDeleteHook{func(r xpresource.Managed) error {
+			return veto
+		}},
+	}})
+
+	local := resourceFixture(nil)
+	created, err := Create(p, inv, local)
+	if err != nil {
+		t.Fatalf("Unexpected error from Create: %s", err)
+	}
+	if _, ok := local.GetAnnotations()["size"]; ok {
+		t.Errorf("Expected BeforeEncode to change a copy of the resource, not the resource itself")
+	}
+	stored, _ := fp.Get(fakeResourceName, created.GetAnnotations()["id"])
+	if stored.GetAttr("size").AsString() != "default" || stored.GetAttr("name").AsString() != "tagged-test" {
+		t.Errorf("Expected the hooks to change what is sent to the provider, got %s", stored.GoString())
+	}
+	if len(ops) != 1 || ops[0] != plugin.OperationCreate || len(applied) != 1 || applied[0] != "Create:tagged-test" {
+		t.Errorf("Unexpected hook calls, before encode %v, after apply %v", ops, applied)
+	}
+
+	err = Delete(p, inv, created)
+	if !plugin.IsHookError(err) || errors.Cause(err) != veto {
+		t.Errorf("Expected BeforeDelete to veto the deletion with a HookError, got %v", err)
+	}
+	if _, ok := fp.Get(fakeResourceName, created.GetAnnotations()["id"]); !ok {
+		t.Errorf("Expected a vetoed Delete to leave the resource alone")
+	}
+}
@@ -12,11 +12,18 @@ import (
 // TODO: If `id` is unset for a new resource, how do we figure out
 // what value needs to be used as the id?
 func Create(p *client.Provider, inv *plugin.Invoker, res resource.Managed) (resource.Managed, error) {
+	span := startSpan(p, "Create", inv, res)
+	created, err := create(p, inv, res)
+	span.end(err)
+	return created, err
+}
+
+func create(p *client.Provider, inv *plugin.Invoker, res resource.Managed) (resource.Managed, error) {
 	s, err := SchemaForInvoker(p, inv)
 	if err != nil {
 		return nil, err
 	}
-	encoded, err := inv.EncodeCty(res, s)
+	encoded, err := encode(inv, plugin.OperationCreate, res, s)
 	if err != nil {
 		return nil, err
 	}
@@ -32,8 +39,13 @@ func Create(p *client.Provider, inv *plugin.Invoker, res resource.Managed) (reso
 		PlannedState: encoded,
 	}
 	resp := p.GRPCProvider.ApplyResourceChange(req)
+	observeDiagnostics(p, resp.Diagnostics)
 	if resp.Diagnostics.HasErrors() {
 		return res, resp.Diagnostics.NonFatalErr()
 	}
-	return inv.DecodeCty(res, resp.NewState, s)
+	created, err := inv.DecodeCty(res, resp.NewState, s)
+	if err != nil {
+		return nil, err
+	}
+	return created, inv.AfterApply(plugin.OperationCreate, created, resp.NewState)
 }
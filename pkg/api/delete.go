@@ -12,11 +12,21 @@ import (
 // In terraform slang this is expressed as asking the provider
 // to act on a Nil planned state.
 func Delete(p *client.Provider, inv *plugin.Invoker, res resource.Managed) error {
+	span := startSpan(p, "Delete", inv, res)
+	err := del(p, inv, res)
+	span.end(err)
+	return err
+}
+
+func del(p *client.Provider, inv *plugin.Invoker, res resource.Managed) error {
 	s, err := SchemaForInvoker(p, inv)
 	if err != nil {
 		return err
 	}
-	encoded, err := inv.EncodeCty(res, s)
+	if err := inv.BeforeDelete(res); err != nil {
+		return err
+	}
+	encoded, err := encode(inv, plugin.OperationDelete, res, s)
 	if err != nil {
 		return err
 	}
@@ -30,6 +40,7 @@ func Delete(p *client.Provider, inv *plugin.Invoker, res resource.Managed) error
 		PlannedState: cty.NullVal(s.Block.ImpliedType()),
 	}
 	resp := p.GRPCProvider.ApplyResourceChange(req)
+	observeDiagnostics(p, resp.Diagnostics)
 	if resp.Diagnostics.HasErrors() {
 		return resp.Diagnostics.NonFatalErr()
 	}
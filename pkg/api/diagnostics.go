@@ -0,0 +1,37 @@
+package api
+
+import (
+	"github.com/crossplane/terraform-provider-runtime/pkg/client"
+	"github.com/hashicorp/terraform/tfdiags"
+)
+
+// A Diagnostic is a warning or an error reported by a provider.
+type Diagnostic struct {
+	Error   bool
+	Summary string
+	Detail  string
+	// Path is the attribute the diagnostic is about, if any, like
+	// boot_disk.size.
+	Path string
+}
+
+// Diagnostics converts terraform diagnostics.
+func Diagnostics(diags tfdiags.Diagnostics) []Diagnostic {
+	out := make([]Diagnostic, 0, len(diags))
+	for _, d := range diags {
+		desc := d.Description()
+		out = append(out, Diagnostic{
+			Error:   d.Severity() == tfdiags.Error,
+			Summary: desc.Summary,
+			Detail:  desc.Detail,
+			Path:    formatPath(tfdiags.GetAttribute(d)),
+		})
+	}
+	return out
+}
+
+func observeDiagnostics(p *client.Provider, diags tfdiags.Diagnostics) {
+	if p.DiagnosticsObserver != nil && len(diags) > 0 {
+		p.DiagnosticsObserver(diags)
+	}
+}
@@ -0,0 +1,22 @@
+package api
+
+import (
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+	"github.com/crossplane/terraform-provider-runtime/pkg/plugin"
+	"github.com/hashicorp/terraform/providers"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// encode encodes res to send to the provider in op, running the encode
+// hooks of its Implementation around the CtyEncoder.
+func encode(inv *plugin.Invoker, op plugin.Operation, res resource.Managed, s *providers.Schema) (cty.Value, error) {
+	hooked, err := inv.BeforeEncode(op, res)
+	if err != nil {
+		return cty.NilVal, err
+	}
+	encoded, err := inv.EncodeCty(hooked, s)
+	if err != nil {
+		return cty.NilVal, err
+	}
+	return inv.AfterEncode(op, hooked, encoded, s)
+}
@@ -0,0 +1,104 @@
+package api
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+	"github.com/crossplane/terraform-provider-runtime/pkg/client"
+	"github.com/crossplane/terraform-provider-runtime/pkg/plugin"
+	"github.com/hashicorp/terraform/plans/objchange"
+	"github.com/hashicorp/terraform/providers"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// Planned is what the provider expects a resource to look like once it is
+// applied.
+type Planned struct {
+	// Resource holds the planned state. Values the provider only knows
+	// after applying, like generated ids, are left empty.
+	Resource resource.Managed
+	// RequiresReplace lists the attributes whose change forces the
+	// resource to be deleted and created again.
+	RequiresReplace []string
+}
+
+// Plan asks the provider to plan the change from prior to res. A nil prior
+// plans the creation of res.
+func Plan(p *client.Provider, inv *plugin.Invoker, prior, res resource.Managed) (*Planned, error) {
+	span := startSpan(p, "Plan", inv, res)
+	planned, err := plan(p, inv, prior, res)
+	span.end(err)
+	return planned, err
+}
+
+func plan(p *client.Provider, inv *plugin.Invoker, prior, res resource.Managed) (*Planned, error) {
+	s, err := SchemaForInvoker(p, inv)
+	if err != nil {
+		return nil, err
+	}
+	encoded, err := encode(inv, plugin.OperationPlan, res, s)
+	if err != nil {
+		return nil, err
+	}
+	priorEncoded := cty.NullVal(s.Block.ImpliedType())
+	if prior != nil {
+		priorEncoded, err = inv.EncodeCty(prior, s)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	req := providers.PlanResourceChangeRequest{
+		TypeName:         inv.TerraformResourceName(),
+		PriorState:       priorEncoded,
+		ProposedNewState: objchange.ProposedNewObject(s.Block, priorEncoded, encoded),
+		Config:           encoded,
+	}
+	resp := p.GRPCProvider.PlanResourceChange(req)
+	observeDiagnostics(p, resp.Diagnostics)
+	if resp.Diagnostics.HasErrors() {
+		return nil, resp.Diagnostics.NonFatalErr()
+	}
+	planned, err := inv.DecodeCty(res.DeepCopyObject().(resource.Managed), unknownAsNull(resp.PlannedState), s)
+	if err != nil {
+		return nil, err
+	}
+	replace := make([]string, len(resp.RequiresReplace))
+	for i, path := range resp.RequiresReplace {
+		replace[i] = formatPath(path)
+	}
+	return &Planned{Resource: planned, RequiresReplace: replace}, nil
+}
+
+// unknownAsNull replaces the unknown values of a plan with nulls, which
+// decoders already handle.
+func unknownAsNull(v cty.Value) cty.Value {
+	v, _ = cty.Transform(v, func(_ cty.Path, v cty.Value) (cty.Value, error) {
+		if !v.IsKnown() {
+			return cty.NullVal(v.Type()), nil
+		}
+		return v, nil
+	})
+	return v
+}
+
+func formatPath(path cty.Path) string {
+	var b strings.Builder
+	for _, step := range path {
+		switch s := step.(type) {
+		case cty.GetAttrStep:
+			if b.Len() > 0 {
+				b.WriteString(".")
+			}
+			b.WriteString(s.Name)
+		case cty.IndexStep:
+			if s.Key.Type() == cty.String {
+				fmt.Fprintf(&b, "[%q]", s.Key.AsString())
+			} else {
+				fmt.Fprintf(&b, "[%s]", s.Key.AsBigFloat().String())
+			}
+		}
+	}
+	return b.String()
+}
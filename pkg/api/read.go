@@ -14,17 +14,28 @@ var ErrNotFound = errors.New("Resource not found")
 // TODO: If `id` is unset for a new resource, how do we figure out
 // what value needs to be used as the id?
 func Read(p *client.Provider, inv *plugin.Invoker, res resource.Managed) (resource.Managed, error) {
+	span := startSpan(p, "Read", inv, res)
+	observed, err := read(p, inv, res)
+	span.end(err)
+	return observed, err
+}
+
+func read(p *client.Provider, inv *plugin.Invoker, res resource.Managed) (resource.Managed, error) {
 	s, err := SchemaForInvoker(p, inv)
 	if err != nil {
 		return nil, err
 	}
-	encoded, err := inv.EncodeCty(res, s)
+	encoded, err := encode(inv, plugin.OperationRead, res, s)
+	if err != nil {
+		return nil, err
+	}
 	req := providers.ReadResourceRequest{
 		TypeName:   inv.TerraformResourceName(),
 		PriorState: encoded,
 		Private:    nil,
 	}
 	resp := p.GRPCProvider.ReadResource(req)
+	observeDiagnostics(p, resp.Diagnostics)
 	if resp.Diagnostics.HasErrors() {
 		return res, resp.Diagnostics.NonFatalErr()
 	}
@@ -10,6 +10,13 @@ import (
 )
 
 func GetSchema(p *client.Provider) (map[string]providers.Schema, error) {
+	span := startSpan(p, "GetSchema", nil, nil)
+	schema, err := getSchema(p)
+	span.end(err)
+	return schema, err
+}
+
+func getSchema(p *client.Provider) (map[string]providers.Schema, error) {
 	resp := p.GRPCProvider.GetSchema()
 	if resp.Diagnostics.HasErrors() {
 		return nil, resp.Diagnostics.NonFatalErr()
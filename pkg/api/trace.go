@@ -0,0 +1,36 @@
+package api
+
+import (
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/crossplane/terraform-provider-runtime/pkg/client"
+	"github.com/crossplane/terraform-provider-runtime/pkg/plugin"
+	"github.com/crossplane/terraform-provider-runtime/pkg/tracing"
+)
+
+// opSpan is the span of an api operation. The provider's gRPC calls are
+// traced under it until it ends.
+type opSpan struct {
+	span    trace.Span
+	restore func()
+}
+
+// startSpan starts the span of the named operation under the provider's
+// trace context.
+func startSpan(p *client.Provider, name string, inv *plugin.Invoker, res resource.Managed) *opSpan {
+	attrs := tracing.ResourceAttributes(res)
+	if inv != nil {
+		attrs = append(attrs, tracing.AttributeTerraformType.String(inv.TerraformResourceName()))
+	}
+	ctx, span := tracing.Start(p.TraceContext(), "api."+name, attrs...)
+	return &opSpan{span: span, restore: p.SetTraceContext(ctx)}
+}
+
+func (s *opSpan) end(err error) {
+	s.restore()
+	if err == ErrNotFound {
+		err = nil
+	}
+	tracing.End(s.span, err)
+}
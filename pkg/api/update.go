@@ -9,11 +9,18 @@ import (
 
 // Update syncs with an existing resource and modifies mutable values
 func Update(p *client.Provider, inv *plugin.Invoker, res resource.Managed) (resource.Managed, error) {
+	span := startSpan(p, "Update", inv, res)
+	updated, err := update(p, inv, res)
+	span.end(err)
+	return updated, err
+}
+
+func update(p *client.Provider, inv *plugin.Invoker, res resource.Managed) (resource.Managed, error) {
 	s, err := SchemaForInvoker(p, inv)
 	if err != nil {
 		return nil, err
 	}
-	encoded, err := inv.EncodeCty(res, s)
+	encoded, err := encode(inv, plugin.OperationUpdate, res, s)
 	if err != nil {
 		return nil, err
 	}
@@ -39,9 +46,14 @@ func Update(p *client.Provider, inv *plugin.Invoker, res resource.Managed) (reso
 		PlannedState: encoded,
 	}
 	resp := p.GRPCProvider.ApplyResourceChange(req)
+	observeDiagnostics(p, resp.Diagnostics)
 	if resp.Diagnostics.HasErrors() {
 		return res, resp.Diagnostics.NonFatalErr()
 	}
 
-	return inv.DecodeCty(res, resp.NewState, s)
+	updated, err := inv.DecodeCty(res, resp.NewState, s)
+	if err != nil {
+		return nil, err
+	}
+	return updated, inv.AfterApply(plugin.OperationUpdate, updated, resp.NewState)
 }
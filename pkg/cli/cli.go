@@ -0,0 +1,257 @@
+// Package cli plans, applies, reads and deletes a single managed resource
+// manifest against a terraform provider, without Kubernetes. It lets
+// provider developers iterate on a resource type without a cluster:
+//
+//	func main() {
+//		c := cli.New(generated.Index(), "google")
+//		kingpin.FatalIfError(c.Run(os.Args[1:]), "")
+//	}
+package cli
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/crossplane/crossplane-runtime/pkg/logging"
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+	"github.com/pkg/errors"
+	"github.com/zclconf/go-cty/cty"
+	"gopkg.in/alecthomas/kingpin.v2"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+	"sigs.k8s.io/yaml"
+
+	"github.com/crossplane/terraform-provider-runtime/pkg/api"
+	"github.com/crossplane/terraform-provider-runtime/pkg/client"
+	"github.com/crossplane/terraform-provider-runtime/pkg/options"
+	"github.com/crossplane/terraform-provider-runtime/pkg/plugin"
+)
+
+// ProviderFactory starts a configured provider, like client.NewProvider.
+type ProviderFactory func(providerName string, ropts *client.RuntimeOptions, cfg map[string]cty.Value) (*client.Provider, error)
+
+// CLI runs manifests of the resource types in Index against the terraform
+// provider ProviderName.
+type CLI struct {
+	Index        *plugin.Index
+	ProviderName string
+	// NewProvider defaults to client.NewProvider.
+	NewProvider ProviderFactory
+	// Out receives the resulting objects as YAML, defaulting to stdout.
+	Out io.Writer
+	// Err receives progress messages, defaulting to stderr.
+	Err io.Writer
+}
+
+// New returns a CLI for the resource types in idx.
+func New(idx *plugin.Index, providerName string) *CLI {
+	return &CLI{
+		Index:        idx,
+		ProviderName: providerName,
+		NewProvider:  client.NewProvider,
+		Out:          os.Stdout,
+		Err:          os.Stderr,
+	}
+}
+
+// Run parses args and runs the selected command. Besides the runtime
+// flags from pkg/options, it takes the provider config file and one of:
+//
+//	plan MANIFEST    print the planned object
+//	apply MANIFEST   create or update the resource, print the result
+//	read MANIFEST    print the observed object
+//	delete MANIFEST  delete the resource
+//	describe [TYPE]  describe the Implementations, without a provider
+func (c *CLI) Run(args []string) error {
+	app := kingpin.New(filepath.Base(os.Args[0]), "Run managed resource manifests against the "+c.ProviderName+" terraform provider, without Kubernetes.")
+	flags := options.RegisterFlags(app)
+	providerConfig := app.Flag("provider-config", "YAML file with the terraform provider's config block.").
+		Envar(options.EnvPrefix + "PROVIDER_CONFIG").ExistingFile()
+
+	commands := map[string]func(*client.Provider, *plugin.Invoker, resource.Managed) error{
+		"plan":   c.plan,
+		"apply":  c.apply,
+		"read":   c.read,
+		"delete": c.delete,
+	}
+	help := map[string]string{
+		"plan":   "Print the object the provider plans for the manifest.",
+		"apply":  "Create or update the resource and print the result.",
+		"read":   "Print the resource as observed by the provider.",
+		"delete": "Delete the resource.",
+	}
+	manifests := make(map[string]*string, len(commands))
+	for _, name := range []string{"plan", "apply", "read", "delete"} {
+		manifests[name] = app.Command(name, help[name]).Arg("manifest", "Managed resource manifest.").Required().ExistingFile()
+	}
+	describe := app.Command("describe", "Print the layers making up the Implementation of each resource type.")
+	describeType := describe.Arg("type", "Only describe this terraform resource type, eg google_compute_instance.").String()
+
+	command, err := app.Parse(args)
+	if err != nil {
+		return err
+	}
+	if command == describe.FullCommand() {
+		return c.describe(*describeType)
+	}
+	if *providerConfig == "" {
+		return errors.New("required flag --provider-config not provided")
+	}
+	opts, err := flags.Options()
+	if err != nil {
+		return err
+	}
+
+	inv, res, err := c.load(*manifests[command])
+	if err != nil {
+		return err
+	}
+	p, err := c.startProvider(opts, *providerConfig)
+	if err != nil {
+		return err
+	}
+	defer p.Close()
+	return commands[command](p, inv, res)
+}
+
+// load reads a manifest, decoding it with the Implementation of its GVK.
+func (c *CLI) load(path string) (*plugin.Invoker, resource.Managed, error) {
+	manifest, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	tm := metav1.TypeMeta{}
+	if err := yaml.Unmarshal(manifest, &tm); err != nil {
+		return nil, nil, errors.Wrapf(err, "Cannot read apiVersion and kind from %s", path)
+	}
+	inv, err := c.Index.InvokerForGVK(tm.GroupVersionKind())
+	if err != nil {
+		return nil, nil, err
+	}
+	res, err := inv.UnmarshalResourceYaml(manifest)
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "Cannot decode %s", path)
+	}
+	return inv, res, nil
+}
+
+func (c *CLI) startProvider(opts *options.Options, configPath string) (*client.Provider, error) {
+	cfg, err := client.ReadProviderConfigFile(configPath)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Cannot read provider config %s", configPath)
+	}
+	log := logging.NewLogrLogger(zap.New(zap.UseDevMode(opts.Logging.Debug), zap.WriteTo(c.Err)))
+	ropts := opts.RuntimeOptions().WithLogger(log)
+	if err := client.InstallMirroredPlugins(ropts, c.ProviderName); err != nil {
+		return nil, errors.Wrap(err, "Cannot install provider plugins from mirror")
+	}
+	p, err := c.NewProvider(c.ProviderName, ropts, cfg.Values())
+	if err != nil {
+		// a provider that failed to configure is still running
+		p.Close()
+		return nil, errors.Wrapf(err, "Cannot start provider %s", c.ProviderName)
+	}
+	// catch Implementations built against another version of the provider
+	// before touching any resources
+	schema, err := api.GetSchema(p)
+	if err == nil {
+		err = c.Index.ValidateSchema(schema)
+	}
+	if err != nil {
+		p.Close()
+		return nil, errors.Wrapf(err, "Cannot validate the schema of provider %s", c.ProviderName)
+	}
+	return p, nil
+}
+
+// describe prints the Description of the Implementation of a terraform
+// resource type, or of every Implementation if tfName is empty.
+func (c *CLI) describe(tfName string) error {
+	var out interface{} = c.Index.Descriptions()
+	if tfName != "" {
+		gvk, ok := c.Index.GVKForTerraformName(tfName)
+		if !ok {
+			return fmt.Errorf("No Implementation for terraform resource %s", tfName)
+		}
+		out, _ = c.Index.Describe(gvk)
+	}
+	b, err := yaml.Marshal(out)
+	if err != nil {
+		return err
+	}
+	_, err = c.Out.Write(b)
+	return err
+}
+
+// observe returns the resource as the provider sees it, or nil if it does
+// not exist yet.
+func observe(p *client.Provider, inv *plugin.Invoker, res resource.Managed) (resource.Managed, error) {
+	observed, err := api.Read(p, inv, res)
+	if err == api.ErrNotFound {
+		return nil, nil
+	}
+	return observed, err
+}
+
+func (c *CLI) plan(p *client.Provider, inv *plugin.Invoker, res resource.Managed) error {
+	prior, err := observe(p, inv, res)
+	if err != nil {
+		return err
+	}
+	planned, err := api.Plan(p, inv, prior, res)
+	if err != nil {
+		return err
+	}
+	if len(planned.RequiresReplace) > 0 {
+		fmt.Fprintf(c.Out, "# changes to %s require replacing the resource\n", strings.Join(planned.RequiresReplace, ", "))
+	}
+	return c.print(inv, planned.Resource)
+}
+
+func (c *CLI) apply(p *client.Provider, inv *plugin.Invoker, res resource.Managed) error {
+	prior, err := observe(p, inv, res)
+	if err != nil {
+		return err
+	}
+	var applied resource.Managed
+	if prior == nil {
+		fmt.Fprintf(c.Err, "Creating %s %s\n", inv.TerraformResourceName(), res.GetName())
+		applied, err = api.Create(p, inv, res)
+	} else {
+		fmt.Fprintf(c.Err, "Updating %s %s\n", inv.TerraformResourceName(), res.GetName())
+		applied, err = api.Update(p, inv, res)
+	}
+	if err != nil {
+		return err
+	}
+	return c.print(inv, applied)
+}
+
+func (c *CLI) read(p *client.Provider, inv *plugin.Invoker, res resource.Managed) error {
+	observed, err := api.Read(p, inv, res)
+	if err != nil {
+		return err
+	}
+	return c.print(inv, observed)
+}
+
+func (c *CLI) delete(p *client.Provider, inv *plugin.Invoker, res resource.Managed) error {
+	if err := api.Delete(p, inv, res); err != nil {
+		return err
+	}
+	fmt.Fprintf(c.Err, "Deleted %s %s\n", inv.TerraformResourceName(), res.GetName())
+	return nil
+}
+
+func (c *CLI) print(inv *plugin.Invoker, res resource.Managed) error {
+	b, err := inv.MarshalResourceYaml(res)
+	if err != nil {
+		return err
+	}
+	_, err = c.Out.Write(b)
+	return err
+}
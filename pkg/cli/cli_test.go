@@ -0,0 +1,120 @@
+package cli
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform/configs/configschema"
+	"github.com/hashicorp/terraform/providers"
+	"github.com/zclconf/go-cty/cty"
+
+	"github.com/crossplane/terraform-provider-runtime/pkg/client"
+	"github.com/crossplane/terraform-provider-runtime/pkg/client/fake"
+	"github.com/crossplane/terraform-provider-runtime/pkg/plugin"
+	"github.com/crossplane/terraform-provider-runtime/pkg/plugin/plugintest"
+)
+
+const fakeResourceName = plugintest.ResourceName
+
+func cliFixture(t *testing.T, fp *fake.Provider) *CLI {
+	idxr := plugin.NewIndexer()
+	if err := idxr.Overlay(plugintest.Implementation()); err != nil {
+		t.Fatalf("Unexpected error from Overlay: %s", err)
+	}
+	idx, err := idxr.BuildIndex()
+	if err != nil {
+		t.Fatalf("Unexpected error from BuildIndex: %s", err)
+	}
+	c := New(idx, "fake")
+	c.NewProvider = func(name string, _ *client.RuntimeOptions, cfg map[string]cty.Value) (*client.Provider, error) {
+		if cfg["region"].AsString() != "moon" {
+			t.Errorf("Expected the provider config file to be passed to the provider, got %v", cfg)
+		}
+		return fake.NewClientProvider(name, fp), nil
+	}
+	c.Err = ioutil.Discard
+	return c
+}
+
+func TestApplyReadDelete(t *testing.T) {
+	dir, err := ioutil.TempDir("", "provider-cli")
+	if err != nil {
+		t.Fatalf("Unexpected error creating temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Unexpected error writing %s: %s", name, err)
+		}
+		return path
+	}
+	config := write("provider.yaml", "config:\n  region: moon\n")
+	desired := write("desired.yaml", `
+apiVersion: test.crossplane.io/v1alpha1
+kind: FakeResource
+metadata:
+  name: test
+  annotations:
+    size: small
+`)
+
+	fp := fake.NewProvider(map[string]providers.Schema{
+		fakeResourceName: {Block: &configschema.Block{
+			Attributes: map[string]*configschema.Attribute{
+				"id":   {Type: cty.String, Computed: true},
+				"size": {Type: cty.String, Optional: true},
+			},
+		}},
+	})
+	c := cliFixture(t, fp)
+	out := &bytes.Buffer{}
+	c.Out = out
+
+	if err := c.Run([]string{"--provider-config", config, "apply", desired}); err != nil {
+		t.Fatalf("Unexpected error from apply: %s", err)
+	}
+	created, err := plugintest.Resource{}.UnmarshalResourceYAML(out.Bytes())
+	if err != nil {
+		t.Fatalf("Expected apply to print a manifest, got %q: %s", out.String(), err)
+	}
+	id := created.GetAnnotations()["id"]
+	if _, ok := fp.Get(fakeResourceName, id); !ok || id == "" {
+		t.Fatalf("Expected apply to create the resource, printed id %q", id)
+	}
+
+	applied := write("applied.yaml", out.String())
+	out.Reset()
+	if err := c.Run([]string{"--provider-config", config, "read", applied}); err != nil {
+		t.Fatalf("Unexpected error from read: %s", err)
+	}
+	if !strings.Contains(out.String(), "size: small") {
+		t.Errorf("Expected read to print the observed object, got:\n%s", out.String())
+	}
+
+	if err := c.Run([]string{"--provider-config", config, "delete", applied}); err != nil {
+		t.Fatalf("Unexpected error from delete: %s", err)
+	}
+	if _, ok := fp.Get(fakeResourceName, id); ok {
+		t.Errorf("Expected delete to remove the resource")
+	}
+}
+
+func TestDescribeNeedsNoProvider(t *testing.T) {
+	c := cliFixture(t, nil)
+	out := &bytes.Buffer{}
+	c.Out = out
+	if err := c.Run([]string{"describe", fakeResourceName}); err != nil {
+		t.Fatalf("Unexpected error from describe: %s", err)
+	}
+	if !strings.Contains(out.String(), "value: plugintest.Resource") {
+		t.Errorf("Expected describe to print the callbacks' types, got:\n%s", out.String())
+	}
+	if err := c.Run([]string{"describe", "fake_missing"}); err == nil {
+		t.Errorf("Expected an error describing a resource type without an Implementation")
+	}
+}
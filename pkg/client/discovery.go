@@ -0,0 +1,90 @@
+package client
+
+import (
+	"fmt"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/terraform/plugin/discovery"
+)
+
+// PluginPlatform is the OS/arch subdirectory name terraform uses to lay out
+// plugins for the current platform, eg linux_amd64.
+var PluginPlatform = runtime.GOOS + "_" + runtime.GOARCH
+
+// ExpandPluginSearchPath returns the list of directories searched for
+// plugins: each of the given directories, followed by its OS/arch
+// subdirectory and the packages unpacked into it in terraform's layout,
+// <hostname>/<namespace>/<type>/<version>/<os>_<arch>. Empty and duplicate
+// entries are dropped.
+func ExpandPluginSearchPath(dirs []string) []string {
+	seen := make(map[string]bool)
+	expanded := make([]string, 0, len(dirs)*2)
+	for _, dir := range dirs {
+		if dir == "" {
+			continue
+		}
+		// Glob only fails on malformed patterns
+		packages, _ := filepath.Glob(filepath.Join(dir, "*", "*", "*", "*", PluginPlatform))
+		for _, d := range append([]string{dir, filepath.Join(dir, PluginPlatform)}, packages...) {
+			d = filepath.Clean(d)
+			if seen[d] {
+				continue
+			}
+			seen[d] = true
+			expanded = append(expanded, d)
+		}
+	}
+	return expanded
+}
+
+// FindProviderPlugin searches dirs for the newest plugin binary for
+// providerName with a version allowed by constraint. An empty constraint
+// allows any version. When nothing matches, the error lists every candidate
+// that was found so it is clear why none of them were picked.
+func FindProviderPlugin(providerName string, dirs []string, constraint string) (discovery.PluginMeta, error) {
+	allowed := discovery.AllVersions
+	if constraint != "" {
+		c, err := discovery.ConstraintStr(constraint).Parse()
+		if err != nil {
+			return discovery.PluginMeta{}, fmt.Errorf("Invalid version constraint %q for provider %s: %s", constraint, providerName, err)
+		}
+		allowed = c
+	}
+
+	searchPath := ExpandPluginSearchPath(dirs)
+	// name and version are just parsed out of the provider file name ({name}_v{version})
+	found := discovery.FindPlugins(ProviderPluginType, searchPath).WithName(providerName)
+	if found.Count() < 1 {
+		return discovery.PluginMeta{}, fmt.Errorf("Failed to find plugin: %s. Plugin binary was not found in any of the plugin directories (%s)", providerName, strings.Join(searchPath, ", "))
+	}
+
+	valid, _ := found.ValidateVersions()
+	matching := make(discovery.PluginMetaSet)
+	for meta := range valid {
+		v, _ := meta.Version.Parse()
+		if allowed.Allows(v) {
+			matching.Add(meta)
+		}
+	}
+	if matching.Count() < 1 {
+		return discovery.PluginMeta{}, fmt.Errorf("No version of plugin %s matches the version constraint %q. Candidates found: %s", providerName, constraint, describeCandidates(found))
+	}
+	// this is just comparing semvers to find the highest one
+	return matching.Newest(), nil
+}
+
+func describeCandidates(metas discovery.PluginMetaSet) string {
+	candidates := make([]string, 0, metas.Count())
+	for meta := range metas {
+		version := string(meta.Version)
+		if _, err := meta.Version.Parse(); err != nil {
+			version = fmt.Sprintf("%s (invalid version)", version)
+		}
+		candidates = append(candidates, fmt.Sprintf("%s %s (%s)", meta.Name, version, meta.Path))
+	}
+	sort.Strings(candidates)
+	return strings.Join(candidates, ", ")
+}
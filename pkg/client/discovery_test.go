@@ -0,0 +1,74 @@
+package client
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func pluginDirFixture(t *testing.T, files map[string][]string) string {
+	root, err := ioutil.TempDir("", "plugin-discovery")
+	if err != nil {
+		t.Fatalf("Unexpected error creating temp dir: %s", err)
+	}
+	for dir, names := range files {
+		d := filepath.Join(root, dir)
+		if err := os.MkdirAll(d, 0755); err != nil {
+			t.Fatalf("Unexpected error creating plugin dir: %s", err)
+		}
+		for _, name := range names {
+			if err := ioutil.WriteFile(filepath.Join(d, name), []byte{}, 0755); err != nil {
+				t.Fatalf("Unexpected error creating plugin fixture: %s", err)
+			}
+		}
+	}
+	return root
+}
+
+func TestFindProviderPluginSearchesPlatformSubdirectories(t *testing.T) {
+	root := pluginDirFixture(t, map[string][]string{
+		"a":                   {"terraform-provider-google_v3.20.0_x4"},
+		"b/" + PluginPlatform: {"terraform-provider-google_v3.31.0_x4"},
+		"b/registry.terraform.io/hashicorp/google/4.0.0/" + PluginPlatform: {"terraform-provider-google_v4.0.0_x5"},
+	})
+	defer os.RemoveAll(root)
+	dirs := []string{filepath.Join(root, "a"), filepath.Join(root, "b")}
+
+	meta, err := FindProviderPlugin("google", dirs, "")
+	if err != nil {
+		t.Fatalf("Unexpected error from FindProviderPlugin: %s", err)
+	}
+	if meta.Version != "4.0.0" {
+		t.Errorf("Expected the newest version, from an unpacked package, to be picked with no constraint, got %s", meta.Version)
+	}
+
+	meta, err = FindProviderPlugin("google", dirs, "~> 3.30")
+	if err != nil {
+		t.Fatalf("Unexpected error from FindProviderPlugin: %s", err)
+	}
+	if meta.Version != "3.31.0" {
+		t.Errorf("Expected the newest version allowed by '~> 3.30', got %s", meta.Version)
+	}
+}
+
+func TestFindProviderPluginListsCandidates(t *testing.T) {
+	root := pluginDirFixture(t, map[string][]string{
+		".": {"terraform-provider-google_v3.20.0_x4"},
+	})
+	defer os.RemoveAll(root)
+
+	_, err := FindProviderPlugin("google", []string{root}, ">= 4.0")
+	if err == nil {
+		t.Fatalf("Expected an error when no plugin satisfies the constraint")
+	}
+	if !strings.Contains(err.Error(), "google 3.20.0") {
+		t.Errorf("Expected error to list the candidates that were found, err=%s", err)
+	}
+
+	_, err = FindProviderPlugin("aws", []string{root}, "")
+	if err == nil {
+		t.Errorf("Expected an error when no plugin with the given name exists")
+	}
+}
@@ -0,0 +1,297 @@
+// Package fake provides an in-process providers.Interface for testing code
+// that talks to terraform providers, without spawning a plugin subprocess.
+package fake
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/hashicorp/terraform/configs/configschema"
+	"github.com/hashicorp/terraform/providers"
+	"github.com/hashicorp/terraform/tfdiags"
+	"github.com/zclconf/go-cty/cty"
+	ctyjson "github.com/zclconf/go-cty/cty/json"
+
+	"github.com/crossplane/terraform-provider-runtime/pkg/client"
+)
+
+// IDAttribute is the attribute the fake Provider uses to identify resources.
+// Every resource schema served by the fake must have it.
+const IDAttribute = "id"
+
+// Provider is a providers.Interface that serves a configurable schema and
+// keeps resource state in memory, keyed by resource type and id. Diagnostics
+// can be scripted for any method with Script.
+type Provider struct {
+	// Schema is returned from GetSchema.
+	Schema providers.GetSchemaResponse
+	// Calls records the name of every method called, in order.
+	Calls []string
+
+	mu       sync.Mutex
+	state    map[string]map[string]cty.Value
+	scripted map[string][]tfdiags.Diagnostics
+	nextID   int
+}
+
+// NewProvider returns a fake Provider serving the given resource schemas.
+func NewProvider(resourceTypes map[string]providers.Schema) *Provider {
+	return &Provider{
+		Schema: providers.GetSchemaResponse{
+			Provider:      providers.Schema{Block: &configschema.Block{}},
+			ResourceTypes: resourceTypes,
+		},
+		state:    make(map[string]map[string]cty.Value),
+		scripted: make(map[string][]tfdiags.Diagnostics),
+	}
+}
+
+// NewClientProvider wraps a fake Provider in a client.Provider, as the pool
+// would hand it out.
+func NewClientProvider(name string, fp *Provider) *client.Provider {
+	return &client.Provider{GRPCProvider: fp, Name: name, ProtocolVersion: 5}
+}
+
+// Script queues diagnostics to be returned by the next call to the named
+// method, eg "ApplyResourceChange". Calls that return errors leave the
+// in-memory state untouched.
+func (fp *Provider) Script(method string, diags tfdiags.Diagnostics) {
+	fp.mu.Lock()
+	defer fp.mu.Unlock()
+	fp.scripted[method] = append(fp.scripted[method], diags)
+}
+
+// ScriptError queues a single error diagnostic for the named method.
+func (fp *Provider) ScriptError(method, summary string) {
+	var diags tfdiags.Diagnostics
+	fp.Script(method, diags.Append(tfdiags.Sourceless(tfdiags.Error, summary, "")))
+}
+
+// Get returns the stored state of a resource.
+func (fp *Provider) Get(typeName, id string) (cty.Value, bool) {
+	fp.mu.Lock()
+	defer fp.mu.Unlock()
+	v, ok := fp.state[typeName][id]
+	return v, ok
+}
+
+// Put stores the state of a resource, as if it had been created out of band.
+func (fp *Provider) Put(typeName, id string, v cty.Value) {
+	fp.mu.Lock()
+	defer fp.mu.Unlock()
+	fp.put(typeName, id, v)
+}
+
+func (fp *Provider) put(typeName, id string, v cty.Value) {
+	if fp.state[typeName] == nil {
+		fp.state[typeName] = make(map[string]cty.Value)
+	}
+	fp.state[typeName][id] = v
+}
+
+// call records the method and pops its scripted diagnostics. fp.mu must be held.
+func (fp *Provider) call(method string) tfdiags.Diagnostics {
+	fp.Calls = append(fp.Calls, method)
+	queue := fp.scripted[method]
+	if len(queue) == 0 {
+		return nil
+	}
+	fp.scripted[method] = queue[1:]
+	return queue[0]
+}
+
+func (fp *Provider) GetSchema() providers.GetSchemaResponse {
+	fp.mu.Lock()
+	defer fp.mu.Unlock()
+	resp := fp.Schema
+	resp.Diagnostics = fp.call("GetSchema")
+	return resp
+}
+
+func (fp *Provider) PrepareProviderConfig(req providers.PrepareProviderConfigRequest) providers.PrepareProviderConfigResponse {
+	fp.mu.Lock()
+	defer fp.mu.Unlock()
+	return providers.PrepareProviderConfigResponse{PreparedConfig: req.Config, Diagnostics: fp.call("PrepareProviderConfig")}
+}
+
+func (fp *Provider) ValidateResourceTypeConfig(providers.ValidateResourceTypeConfigRequest) providers.ValidateResourceTypeConfigResponse {
+	fp.mu.Lock()
+	defer fp.mu.Unlock()
+	return providers.ValidateResourceTypeConfigResponse{Diagnostics: fp.call("ValidateResourceTypeConfig")}
+}
+
+func (fp *Provider) ValidateDataSourceConfig(providers.ValidateDataSourceConfigRequest) providers.ValidateDataSourceConfigResponse {
+	fp.mu.Lock()
+	defer fp.mu.Unlock()
+	return providers.ValidateDataSourceConfigResponse{Diagnostics: fp.call("ValidateDataSourceConfig")}
+}
+
+func (fp *Provider) UpgradeResourceState(req providers.UpgradeResourceStateRequest) providers.UpgradeResourceStateResponse {
+	fp.mu.Lock()
+	defer fp.mu.Unlock()
+	resp := providers.UpgradeResourceStateResponse{Diagnostics: fp.call("UpgradeResourceState")}
+	if resp.Diagnostics.HasErrors() {
+		return resp
+	}
+	s, ok := fp.Schema.ResourceTypes[req.TypeName]
+	if !ok {
+		resp.Diagnostics = resp.Diagnostics.Append(unknownType(req.TypeName))
+		return resp
+	}
+	v, err := ctyjson.Unmarshal(req.RawStateJSON, s.Block.ImpliedType())
+	if err != nil {
+		resp.Diagnostics = resp.Diagnostics.Append(err)
+		return resp
+	}
+	resp.UpgradedState = v
+	return resp
+}
+
+func (fp *Provider) Configure(providers.ConfigureRequest) providers.ConfigureResponse {
+	fp.mu.Lock()
+	defer fp.mu.Unlock()
+	return providers.ConfigureResponse{Diagnostics: fp.call("Configure")}
+}
+
+func (fp *Provider) Stop() error {
+	fp.mu.Lock()
+	defer fp.mu.Unlock()
+	fp.call("Stop")
+	return nil
+}
+
+// ReadResource returns the stored state for the id in the prior state, or a
+// null state if there is none, which is how providers report a resource
+// that no longer exists.
+func (fp *Provider) ReadResource(req providers.ReadResourceRequest) providers.ReadResourceResponse {
+	fp.mu.Lock()
+	defer fp.mu.Unlock()
+	resp := providers.ReadResourceResponse{Diagnostics: fp.call("ReadResource"), Private: req.Private}
+	if resp.Diagnostics.HasErrors() {
+		return resp
+	}
+	s, ok := fp.Schema.ResourceTypes[req.TypeName]
+	if !ok {
+		resp.Diagnostics = resp.Diagnostics.Append(unknownType(req.TypeName))
+		return resp
+	}
+	resp.NewState = cty.NullVal(s.Block.ImpliedType())
+	if v, ok := fp.state[req.TypeName][idOf(req.PriorState)]; ok {
+		resp.NewState = v
+	}
+	return resp
+}
+
+func (fp *Provider) PlanResourceChange(req providers.PlanResourceChangeRequest) providers.PlanResourceChangeResponse {
+	fp.mu.Lock()
+	defer fp.mu.Unlock()
+	return providers.PlanResourceChangeResponse{
+		PlannedState:   req.ProposedNewState,
+		PlannedPrivate: req.PriorPrivate,
+		Diagnostics:    fp.call("PlanResourceChange"),
+	}
+}
+
+// ApplyResourceChange creates, updates or deletes a resource in memory. A
+// null planned state deletes the resource, a null prior state creates it,
+// assigning an id if the planned state has none. Unknown values are stored
+// as nulls.
+func (fp *Provider) ApplyResourceChange(req providers.ApplyResourceChangeRequest) providers.ApplyResourceChangeResponse {
+	fp.mu.Lock()
+	defer fp.mu.Unlock()
+	resp := providers.ApplyResourceChangeResponse{Diagnostics: fp.call("ApplyResourceChange"), Private: req.PlannedPrivate}
+	if resp.Diagnostics.HasErrors() {
+		return resp
+	}
+	if _, ok := fp.Schema.ResourceTypes[req.TypeName]; !ok {
+		resp.Diagnostics = resp.Diagnostics.Append(unknownType(req.TypeName))
+		return resp
+	}
+
+	if req.PlannedState.IsNull() {
+		delete(fp.state[req.TypeName], idOf(req.PriorState))
+		resp.NewState = req.PlannedState
+		return resp
+	}
+
+	id := idOf(req.PlannedState)
+	if id == "" {
+		id = idOf(req.PriorState)
+	}
+	if id == "" {
+		fp.nextID++
+		id = fmt.Sprintf("fake-%d", fp.nextID)
+	}
+	newState, err := withID(unknownsToNull(req.PlannedState), id)
+	if err != nil {
+		resp.Diagnostics = resp.Diagnostics.Append(err)
+		return resp
+	}
+	fp.put(req.TypeName, id, newState)
+	resp.NewState = newState
+	return resp
+}
+
+// ImportResourceState imports a resource that was stored with Put.
+func (fp *Provider) ImportResourceState(req providers.ImportResourceStateRequest) providers.ImportResourceStateResponse {
+	fp.mu.Lock()
+	defer fp.mu.Unlock()
+	resp := providers.ImportResourceStateResponse{Diagnostics: fp.call("ImportResourceState")}
+	if resp.Diagnostics.HasErrors() {
+		return resp
+	}
+	v, ok := fp.state[req.TypeName][req.ID]
+	if !ok {
+		resp.Diagnostics = resp.Diagnostics.Append(fmt.Errorf("Cannot import non-existent %s with id %s", req.TypeName, req.ID))
+		return resp
+	}
+	resp.ImportedResources = []providers.ImportedResource{{TypeName: req.TypeName, State: v}}
+	return resp
+}
+
+func (fp *Provider) ReadDataSource(req providers.ReadDataSourceRequest) providers.ReadDataSourceResponse {
+	fp.mu.Lock()
+	defer fp.mu.Unlock()
+	return providers.ReadDataSourceResponse{State: req.Config, Diagnostics: fp.call("ReadDataSource")}
+}
+
+func (fp *Provider) Close() error {
+	fp.mu.Lock()
+	defer fp.mu.Unlock()
+	fp.call("Close")
+	return nil
+}
+
+func unknownType(typeName string) error {
+	return fmt.Errorf("Fake provider does not have a schema for resource type %s", typeName)
+}
+
+func idOf(v cty.Value) string {
+	if v.IsNull() || !v.IsKnown() || !v.Type().IsObjectType() || !v.Type().HasAttribute(IDAttribute) {
+		return ""
+	}
+	id := v.GetAttr(IDAttribute)
+	if id.IsNull() || !id.IsKnown() || id.Type() != cty.String {
+		return ""
+	}
+	return id.AsString()
+}
+
+func withID(v cty.Value, id string) (cty.Value, error) {
+	if !v.Type().HasAttribute(IDAttribute) {
+		return cty.NilVal, fmt.Errorf("Fake provider requires resources to have an %q attribute", IDAttribute)
+	}
+	attrs := v.AsValueMap()
+	attrs[IDAttribute] = cty.StringVal(id)
+	return cty.ObjectVal(attrs), nil
+}
+
+func unknownsToNull(v cty.Value) cty.Value {
+	v, _ = cty.Transform(v, func(_ cty.Path, v cty.Value) (cty.Value, error) {
+		if !v.IsKnown() {
+			return cty.NullVal(v.Type()), nil
+		}
+		return v, nil
+	})
+	return v
+}
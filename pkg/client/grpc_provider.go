@@ -2,37 +2,46 @@ package client
 
 import (
 	"fmt"
+	"os"
+	"strings"
 
 	plugin "github.com/hashicorp/go-plugin"
 	tfplugin "github.com/hashicorp/terraform/plugin"
-	"github.com/hashicorp/terraform/plugin/discovery"
+	"github.com/hashicorp/terraform/providers"
+
+	"github.com/crossplane/terraform-provider-runtime/pkg/tracing"
 )
 
 const ProviderPluginType = "provider"
 
 // NewGRPCProvider creates a new GRPCClient instance.
-func NewGRPCProvider(providerName, pluginDir string) (*tfplugin.GRPCProvider, error) {
-	// 1. find plugins in the filesystem
-	// name and version are just parsed out of the provider file name ({name}_v{version})
-	pluginMetaSet := discovery.FindPlugins(ProviderPluginType, []string{pluginDir}).WithName(providerName)
-	if pluginMetaSet.Count() < 1 {
-		return nil, fmt.Errorf("Failed to find plugin: %s. Plugin binary was not found in the plugin directory(%s)", providerName, pluginDir)
+// The newest plugin for providerName allowed by the version constraint is
+// picked from the plugin directories; see FindProviderPlugin. If a plugin
+// lock file is configured, the plugin must match its locked version and hashes.
+// If a reattach config is set for the provider, either in the RuntimeOptions or
+// in TF_REATTACH_PROVIDERS, the already-running provider is used instead.
+// Plugin logs are forwarded to ropts.Logger at the provider's plugin log level.
+// Both version 5 and version 6 of the plugin protocol are negotiated; either
+// way the provider is returned behind the providers.Interface.
+func NewGRPCProvider(providerName string, ropts *RuntimeOptions) (providers.Interface, error) {
+	rc, err := reattachConfigFor(providerName, ropts)
+	if err != nil {
+		return nil, err
+	}
+	var cfg *plugin.ClientConfig
+	if rc != nil {
+		cfg, err = newReattachClientConfig(rc)
+	} else {
+		cfg, err = newPluginClientConfig(providerName, ropts)
 	}
-	// this is just comparing semvers to find the highest one
-	pluginMeta := pluginMetaSet.Newest()
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Logger, err = newPluginLogger(providerName, ropts); err != nil {
+		return nil, err
+	}
+	cfg.GRPCDialOptions = tracing.DialOptions(ropts.logScope.traceContext)
 
-	// plugin.NewClient returns a client that knows how to spawn a provider subprocess and set up the grpc connection
-	cfg := tfplugin.ClientConfig(pluginMeta)
-	// this discards the noisy debug logs that we get back from go-plugin
-	// note that if we want to add options to display these logs w/ verbosity config, setting Output: nil
-	// will use the default stdout writer and hclog Levels line up with the standard logging lib.
-	/*
-		cfg.Logger = hclog.New(&hclog.LoggerOptions{
-			Output: ioutil.Discard,
-			Level:  hclog.Trace,
-			Name:   "plugin",
-		})
-	*/
 	pluginClient := plugin.NewClient(cfg)
 	// 2. Spawn the chosen plugin binary as a subprocess, connect to its stdout and parse the grpc connection configuration
 	// the connection to the client is set up at this point in the negotiation process, but the protobuf client
@@ -52,14 +61,86 @@ func NewGRPCProvider(providerName, pluginDir string) (*tfplugin.GRPCProvider, er
 		return nil, fmt.Errorf("Failed to dispense GRPC plugin: %s", err)
 	}
 
-	// 4. Finally we type assert that we received a GRPCProvider so that we can rely on
-	// TODO: add to our list of issues that handling all the different flavors of provider negotiation adds complexity to our system
-	// can we draw a line and only support post .12 plugins which support the modern plugin protocol?
-	provider, ok := raw.(*tfplugin.GRPCProvider)
-	if !ok {
-		return nil, fmt.Errorf("Did not get a plugin provider of type GRPCProvider from client.Dispense")
+	// 4. Finally we type assert that we received one of the GRPCProvider flavors we negotiated.
+	// Pre 0.12 (protocol 4) plugins are not supported.
+	switch provider := raw.(type) {
+	case *tfplugin.GRPCProvider:
+		provider.PluginClient = pluginClient
+		return provider, nil
+	case *GRPCProviderV6:
+		provider.PluginClient = pluginClient
+		return provider, nil
 	}
-	provider.PluginClient = pluginClient
+	pluginClient.Kill()
+	return nil, fmt.Errorf("Did not get a plugin provider of type GRPCProvider from client.Dispense (negotiated protocol version %d)", pluginClient.NegotiatedVersion())
+}
+
+// VersionedPlugins are the plugin protocol versions the runtime can speak,
+// offered to the provider during the go-plugin handshake. The provider picks
+// the highest version both sides support.
+var VersionedPlugins = map[int]plugin.PluginSet{
+	5: {
+		tfplugin.ProviderPluginName: &tfplugin.GRPCProviderPlugin{},
+	},
+	6: {
+		tfplugin.ProviderPluginName: &GRPCProviderPluginV6{},
+	},
+}
+
+// ProtocolVersion reports which plugin protocol version a provider speaks.
+func ProtocolVersion(p providers.Interface) int {
+	if _, ok := p.(*GRPCProviderV6); ok {
+		return 6
+	}
+	return 5
+}
 
-	return provider, nil
+// newPluginClientConfig finds the plugin binary for the provider and builds
+// the go-plugin config used to spawn it.
+func newPluginClientConfig(providerName string, ropts *RuntimeOptions) (*plugin.ClientConfig, error) {
+	var lock *ProviderLock
+	if path := ropts.PluginLockFile; path != "" {
+		lf, err := ReadLockFile(path)
+		if err != nil {
+			return nil, err
+		}
+		var ok bool
+		if lock, ok = lf.Lock(providerName); !ok {
+			return nil, fmt.Errorf("Provider %s does not have an entry in the plugin lock file %s", providerName, path)
+		}
+	}
+
+	// 1. find plugins in the filesystem
+	constraint := ropts.GetVersionConstraint(providerName)
+	if lock != nil {
+		constraint = joinConstraints(constraint, "= "+lock.Version)
+	}
+	pluginMeta, err := FindProviderPlugin(providerName, ropts.GetPluginDirectories(), constraint)
+	if err != nil {
+		return nil, err
+	}
+	if lock != nil {
+		if err := lock.Verify(pluginMeta, ropts.PluginArchives[pluginMeta.Path]); err != nil {
+			return nil, err
+		}
+	}
+
+	// plugin.NewClient returns a client that knows how to spawn a provider subprocess and set up the grpc connection
+	cfg := tfplugin.ClientConfig(pluginMeta)
+	cfg.VersionedPlugins = VersionedPlugins
+	// plugins built on the terraform SDK only log at the level they find in TF_LOG
+	if level := ropts.PluginLogLevels[providerName]; level != "" {
+		cfg.Cmd.Env = append(os.Environ(), PluginLogLevelEnv+"="+level)
+	}
+	return cfg, nil
+}
+
+func joinConstraints(constraints ...string) string {
+	nonEmpty := make([]string, 0, len(constraints))
+	for _, c := range constraints {
+		if c != "" {
+			nonEmpty = append(nonEmpty, c)
+		}
+	}
+	return strings.Join(nonEmpty, ", ")
 }
@@ -0,0 +1,529 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	plugin "github.com/hashicorp/go-plugin"
+	"github.com/hashicorp/terraform/configs/configschema"
+	"github.com/hashicorp/terraform/providers"
+	"github.com/hashicorp/terraform/tfdiags"
+	"github.com/zclconf/go-cty/cty"
+	ctyjson "github.com/zclconf/go-cty/cty/json"
+	"github.com/zclconf/go-cty/cty/msgpack"
+	"google.golang.org/grpc"
+
+	"github.com/crossplane/terraform-provider-runtime/pkg/client/tfplugin6"
+)
+
+// GRPCProviderPluginV6 implements plugin.GRPCPlugin for providers that speak
+// version 6 of the terraform plugin protocol. Only the client side is
+// implemented; the runtime never serves providers.
+type GRPCProviderPluginV6 struct {
+	plugin.Plugin
+}
+
+func (p *GRPCProviderPluginV6) GRPCClient(ctx context.Context, broker *plugin.GRPCBroker, c *grpc.ClientConn) (interface{}, error) {
+	return &GRPCProviderV6{
+		client: tfplugin6.NewProviderClient(c),
+		ctx:    ctx,
+	}, nil
+}
+
+func (p *GRPCProviderPluginV6) GRPCServer(*plugin.GRPCBroker, *grpc.Server) error {
+	return errors.New("terraform-provider-runtime does not serve protocol 6 providers")
+}
+
+// GRPCProviderV6 adapts a protocol 6 provider to providers.Interface, the
+// same interface the protocol 5 tfplugin.GRPCProvider implements, so the
+// rest of the runtime does not need to know which protocol was negotiated.
+// Protocol 6 nested attribute types are folded into the attribute's cty
+// type, which is how they are encoded on the wire.
+type GRPCProviderV6 struct {
+	// PluginClient controls the plugin process, and is used to shut it down.
+	PluginClient *plugin.Client
+
+	client tfplugin6.ProviderClient
+	// this context is created by the plugin package, and is canceled when the
+	// plugin process ends.
+	ctx context.Context
+
+	mu      sync.Mutex
+	schemas providers.GetSchemaResponse
+}
+
+var _ providers.Interface = &GRPCProviderV6{}
+
+func (p *GRPCProviderV6) GetSchema() (resp providers.GetSchemaResponse) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.schemas.Provider.Block != nil {
+		return p.schemas
+	}
+
+	resp.ResourceTypes = make(map[string]providers.Schema)
+	resp.DataSources = make(map[string]providers.Schema)
+	// same as the protocol 5 client, large providers exceed the 4MB default
+	const maxRecvSize = 64 << 20
+	protoResp, err := p.client.GetProviderSchema(p.ctx, new(tfplugin6.GetProviderSchema_Request), grpc.MaxCallRecvMsgSize(maxRecvSize))
+	if err != nil {
+		resp.Diagnostics = resp.Diagnostics.Append(err)
+		return resp
+	}
+	resp.Diagnostics = resp.Diagnostics.Append(protoToDiagnosticsV6(protoResp.Diagnostics))
+	if protoResp.Provider == nil {
+		resp.Diagnostics = resp.Diagnostics.Append(errors.New("missing provider schema"))
+		return resp
+	}
+
+	if resp.Provider, err = protoToSchemaV6(protoResp.Provider); err != nil {
+		resp.Diagnostics = resp.Diagnostics.Append(err)
+		return resp
+	}
+	for name, res := range protoResp.ResourceSchemas {
+		if resp.ResourceTypes[name], err = protoToSchemaV6(res); err != nil {
+			resp.Diagnostics = resp.Diagnostics.Append(fmt.Errorf("resource %s: %s", name, err))
+			return resp
+		}
+	}
+	for name, data := range protoResp.DataSourceSchemas {
+		if resp.DataSources[name], err = protoToSchemaV6(data); err != nil {
+			resp.Diagnostics = resp.Diagnostics.Append(fmt.Errorf("data source %s: %s", name, err))
+			return resp
+		}
+	}
+	p.schemas = resp
+	return resp
+}
+
+// schemaFor returns the cached schema, fetching it if GetSchema has not been
+// called yet.
+func (p *GRPCProviderV6) schemaFor(kind, name string) (providers.Schema, error) {
+	schemas := p.GetSchema()
+	if schemas.Diagnostics.HasErrors() {
+		return providers.Schema{}, schemas.Diagnostics.Err()
+	}
+	var s providers.Schema
+	var ok bool
+	switch kind {
+	case "provider":
+		return schemas.Provider, nil
+	case "data":
+		s, ok = schemas.DataSources[name]
+	default:
+		s, ok = schemas.ResourceTypes[name]
+	}
+	if !ok {
+		return s, fmt.Errorf("unknown %s type %s", kind, name)
+	}
+	return s, nil
+}
+
+func (p *GRPCProviderV6) PrepareProviderConfig(r providers.PrepareProviderConfigRequest) (resp providers.PrepareProviderConfigResponse) {
+	// protocol 6 validates config without returning a prepared copy
+	resp.PreparedConfig = r.Config
+	s, err := p.schemaFor("provider", "")
+	if err != nil {
+		resp.Diagnostics = resp.Diagnostics.Append(err)
+		return resp
+	}
+	config, err := marshalDynamicV6(r.Config, s)
+	if err != nil {
+		resp.Diagnostics = resp.Diagnostics.Append(err)
+		return resp
+	}
+	protoResp, err := p.client.ValidateProviderConfig(p.ctx, &tfplugin6.ValidateProviderConfig_Request{Config: config})
+	if err != nil {
+		resp.Diagnostics = resp.Diagnostics.Append(err)
+		return resp
+	}
+	resp.Diagnostics = resp.Diagnostics.Append(protoToDiagnosticsV6(protoResp.Diagnostics))
+	return resp
+}
+
+func (p *GRPCProviderV6) ValidateResourceTypeConfig(r providers.ValidateResourceTypeConfigRequest) (resp providers.ValidateResourceTypeConfigResponse) {
+	s, err := p.schemaFor("resource", r.TypeName)
+	if err != nil {
+		resp.Diagnostics = resp.Diagnostics.Append(err)
+		return resp
+	}
+	config, err := marshalDynamicV6(r.Config, s)
+	if err != nil {
+		resp.Diagnostics = resp.Diagnostics.Append(err)
+		return resp
+	}
+	protoResp, err := p.client.ValidateResourceConfig(p.ctx, &tfplugin6.ValidateResourceConfig_Request{TypeName: r.TypeName, Config: config})
+	if err != nil {
+		resp.Diagnostics = resp.Diagnostics.Append(err)
+		return resp
+	}
+	resp.Diagnostics = resp.Diagnostics.Append(protoToDiagnosticsV6(protoResp.Diagnostics))
+	return resp
+}
+
+func (p *GRPCProviderV6) ValidateDataSourceConfig(r providers.ValidateDataSourceConfigRequest) (resp providers.ValidateDataSourceConfigResponse) {
+	s, err := p.schemaFor("data", r.TypeName)
+	if err != nil {
+		resp.Diagnostics = resp.Diagnostics.Append(err)
+		return resp
+	}
+	config, err := marshalDynamicV6(r.Config, s)
+	if err != nil {
+		resp.Diagnostics = resp.Diagnostics.Append(err)
+		return resp
+	}
+	protoResp, err := p.client.ValidateDataResourceConfig(p.ctx, &tfplugin6.ValidateDataResourceConfig_Request{TypeName: r.TypeName, Config: config})
+	if err != nil {
+		resp.Diagnostics = resp.Diagnostics.Append(err)
+		return resp
+	}
+	resp.Diagnostics = resp.Diagnostics.Append(protoToDiagnosticsV6(protoResp.Diagnostics))
+	return resp
+}
+
+func (p *GRPCProviderV6) UpgradeResourceState(r providers.UpgradeResourceStateRequest) (resp providers.UpgradeResourceStateResponse) {
+	s, err := p.schemaFor("resource", r.TypeName)
+	if err != nil {
+		resp.Diagnostics = resp.Diagnostics.Append(err)
+		return resp
+	}
+	protoReq := &tfplugin6.UpgradeResourceState_Request{
+		TypeName: r.TypeName,
+		Version:  r.Version,
+		RawState: &tfplugin6.RawState{Json: r.RawStateJSON, Flatmap: r.RawStateFlatmap},
+	}
+	protoResp, err := p.client.UpgradeResourceState(p.ctx, protoReq)
+	if err != nil {
+		resp.Diagnostics = resp.Diagnostics.Append(err)
+		return resp
+	}
+	resp.Diagnostics = resp.Diagnostics.Append(protoToDiagnosticsV6(protoResp.Diagnostics))
+	resp.UpgradedState, err = unmarshalDynamicV6(protoResp.UpgradedState, s)
+	if err != nil {
+		resp.Diagnostics = resp.Diagnostics.Append(err)
+	}
+	return resp
+}
+
+func (p *GRPCProviderV6) Configure(r providers.ConfigureRequest) (resp providers.ConfigureResponse) {
+	s, err := p.schemaFor("provider", "")
+	if err != nil {
+		resp.Diagnostics = resp.Diagnostics.Append(err)
+		return resp
+	}
+	config, err := marshalDynamicV6(r.Config, s)
+	if err != nil {
+		resp.Diagnostics = resp.Diagnostics.Append(err)
+		return resp
+	}
+	protoReq := &tfplugin6.ConfigureProvider_Request{TerraformVersion: r.TerraformVersion, Config: config}
+	protoResp, err := p.client.ConfigureProvider(p.ctx, protoReq)
+	if err != nil {
+		resp.Diagnostics = resp.Diagnostics.Append(err)
+		return resp
+	}
+	resp.Diagnostics = resp.Diagnostics.Append(protoToDiagnosticsV6(protoResp.Diagnostics))
+	return resp
+}
+
+func (p *GRPCProviderV6) Stop() error {
+	resp, err := p.client.StopProvider(p.ctx, new(tfplugin6.StopProvider_Request))
+	if err != nil {
+		return err
+	}
+	if resp.Error != "" {
+		return errors.New(resp.Error)
+	}
+	return nil
+}
+
+func (p *GRPCProviderV6) ReadResource(r providers.ReadResourceRequest) (resp providers.ReadResourceResponse) {
+	s, err := p.schemaFor("resource", r.TypeName)
+	if err != nil {
+		resp.Diagnostics = resp.Diagnostics.Append(err)
+		return resp
+	}
+	current, err := marshalDynamicV6(r.PriorState, s)
+	if err != nil {
+		resp.Diagnostics = resp.Diagnostics.Append(err)
+		return resp
+	}
+	protoReq := &tfplugin6.ReadResource_Request{TypeName: r.TypeName, CurrentState: current, Private: r.Private}
+	protoResp, err := p.client.ReadResource(p.ctx, protoReq)
+	if err != nil {
+		resp.Diagnostics = resp.Diagnostics.Append(err)
+		return resp
+	}
+	resp.Diagnostics = resp.Diagnostics.Append(protoToDiagnosticsV6(protoResp.Diagnostics))
+	if resp.NewState, err = unmarshalDynamicV6(protoResp.NewState, s); err != nil {
+		resp.Diagnostics = resp.Diagnostics.Append(err)
+		return resp
+	}
+	resp.Private = protoResp.Private
+	return resp
+}
+
+func (p *GRPCProviderV6) PlanResourceChange(r providers.PlanResourceChangeRequest) (resp providers.PlanResourceChangeResponse) {
+	s, err := p.schemaFor("resource", r.TypeName)
+	if err != nil {
+		resp.Diagnostics = resp.Diagnostics.Append(err)
+		return resp
+	}
+	protoReq := &tfplugin6.PlanResourceChange_Request{TypeName: r.TypeName, PriorPrivate: r.PriorPrivate}
+	if protoReq.PriorState, err = marshalDynamicV6(r.PriorState, s); err != nil {
+		resp.Diagnostics = resp.Diagnostics.Append(err)
+		return resp
+	}
+	if protoReq.ProposedNewState, err = marshalDynamicV6(r.ProposedNewState, s); err != nil {
+		resp.Diagnostics = resp.Diagnostics.Append(err)
+		return resp
+	}
+	if protoReq.Config, err = marshalDynamicV6(r.Config, s); err != nil {
+		resp.Diagnostics = resp.Diagnostics.Append(err)
+		return resp
+	}
+	protoResp, err := p.client.PlanResourceChange(p.ctx, protoReq)
+	if err != nil {
+		resp.Diagnostics = resp.Diagnostics.Append(err)
+		return resp
+	}
+	resp.Diagnostics = resp.Diagnostics.Append(protoToDiagnosticsV6(protoResp.Diagnostics))
+	if resp.PlannedState, err = unmarshalDynamicV6(protoResp.PlannedState, s); err != nil {
+		resp.Diagnostics = resp.Diagnostics.Append(err)
+		return resp
+	}
+	for _, ap := range protoResp.RequiresReplace {
+		resp.RequiresReplace = append(resp.RequiresReplace, attributePathToPathV6(ap))
+	}
+	resp.PlannedPrivate = protoResp.PlannedPrivate
+	return resp
+}
+
+func (p *GRPCProviderV6) ApplyResourceChange(r providers.ApplyResourceChangeRequest) (resp providers.ApplyResourceChangeResponse) {
+	s, err := p.schemaFor("resource", r.TypeName)
+	if err != nil {
+		resp.Diagnostics = resp.Diagnostics.Append(err)
+		return resp
+	}
+	protoReq := &tfplugin6.ApplyResourceChange_Request{TypeName: r.TypeName, PlannedPrivate: r.PlannedPrivate}
+	if protoReq.PriorState, err = marshalDynamicV6(r.PriorState, s); err != nil {
+		resp.Diagnostics = resp.Diagnostics.Append(err)
+		return resp
+	}
+	if protoReq.PlannedState, err = marshalDynamicV6(r.PlannedState, s); err != nil {
+		resp.Diagnostics = resp.Diagnostics.Append(err)
+		return resp
+	}
+	if protoReq.Config, err = marshalDynamicV6(r.Config, s); err != nil {
+		resp.Diagnostics = resp.Diagnostics.Append(err)
+		return resp
+	}
+	protoResp, err := p.client.ApplyResourceChange(p.ctx, protoReq)
+	if err != nil {
+		resp.Diagnostics = resp.Diagnostics.Append(err)
+		return resp
+	}
+	resp.Diagnostics = resp.Diagnostics.Append(protoToDiagnosticsV6(protoResp.Diagnostics))
+	if resp.NewState, err = unmarshalDynamicV6(protoResp.NewState, s); err != nil {
+		resp.Diagnostics = resp.Diagnostics.Append(err)
+		return resp
+	}
+	resp.Private = protoResp.Private
+	return resp
+}
+
+func (p *GRPCProviderV6) ImportResourceState(r providers.ImportResourceStateRequest) (resp providers.ImportResourceStateResponse) {
+	protoReq := &tfplugin6.ImportResourceState_Request{TypeName: r.TypeName, Id: r.ID}
+	protoResp, err := p.client.ImportResourceState(p.ctx, protoReq)
+	if err != nil {
+		resp.Diagnostics = resp.Diagnostics.Append(err)
+		return resp
+	}
+	resp.Diagnostics = resp.Diagnostics.Append(protoToDiagnosticsV6(protoResp.Diagnostics))
+	for _, imported := range protoResp.ImportedResources {
+		s, err := p.schemaFor("resource", imported.TypeName)
+		if err != nil {
+			resp.Diagnostics = resp.Diagnostics.Append(err)
+			return resp
+		}
+		state, err := unmarshalDynamicV6(imported.State, s)
+		if err != nil {
+			resp.Diagnostics = resp.Diagnostics.Append(err)
+			return resp
+		}
+		resp.ImportedResources = append(resp.ImportedResources, providers.ImportedResource{
+			TypeName: imported.TypeName,
+			State:    state,
+			Private:  imported.Private,
+		})
+	}
+	return resp
+}
+
+func (p *GRPCProviderV6) ReadDataSource(r providers.ReadDataSourceRequest) (resp providers.ReadDataSourceResponse) {
+	s, err := p.schemaFor("data", r.TypeName)
+	if err != nil {
+		resp.Diagnostics = resp.Diagnostics.Append(err)
+		return resp
+	}
+	config, err := marshalDynamicV6(r.Config, s)
+	if err != nil {
+		resp.Diagnostics = resp.Diagnostics.Append(err)
+		return resp
+	}
+	protoResp, err := p.client.ReadDataSource(p.ctx, &tfplugin6.ReadDataSource_Request{TypeName: r.TypeName, Config: config})
+	if err != nil {
+		resp.Diagnostics = resp.Diagnostics.Append(err)
+		return resp
+	}
+	resp.Diagnostics = resp.Diagnostics.Append(protoToDiagnosticsV6(protoResp.Diagnostics))
+	if resp.State, err = unmarshalDynamicV6(protoResp.State, s); err != nil {
+		resp.Diagnostics = resp.Diagnostics.Append(err)
+	}
+	return resp
+}
+
+// Close shuts down the plugin process.
+func (p *GRPCProviderV6) Close() error {
+	if p.PluginClient == nil {
+		return nil
+	}
+	p.PluginClient.Kill()
+	return nil
+}
+
+func marshalDynamicV6(v cty.Value, s providers.Schema) (*tfplugin6.DynamicValue, error) {
+	mp, err := msgpack.Marshal(v, s.Block.ImpliedType())
+	if err != nil {
+		return nil, err
+	}
+	return &tfplugin6.DynamicValue{Msgpack: mp}, nil
+}
+
+func unmarshalDynamicV6(dv *tfplugin6.DynamicValue, s providers.Schema) (cty.Value, error) {
+	ty := s.Block.ImpliedType()
+	switch {
+	case dv == nil:
+		return cty.NullVal(ty), nil
+	case len(dv.Msgpack) > 0:
+		return msgpack.Unmarshal(dv.Msgpack, ty)
+	case len(dv.Json) > 0:
+		return ctyjson.Unmarshal(dv.Json, ty)
+	}
+	return cty.NullVal(ty), nil
+}
+
+func protoToSchemaV6(s *tfplugin6.Schema) (providers.Schema, error) {
+	block, err := protoToBlockV6(s.Block)
+	if err != nil {
+		return providers.Schema{}, err
+	}
+	return providers.Schema{Version: s.Version, Block: block}, nil
+}
+
+func protoToBlockV6(b *tfplugin6.Schema_Block) (*configschema.Block, error) {
+	block := &configschema.Block{
+		Attributes: make(map[string]*configschema.Attribute),
+		BlockTypes: make(map[string]*configschema.NestedBlock),
+	}
+	if b == nil {
+		return block, nil
+	}
+	for _, a := range b.Attributes {
+		ty, err := attributeTypeV6(a)
+		if err != nil {
+			return nil, fmt.Errorf("attribute %s: %s", a.Name, err)
+		}
+		block.Attributes[a.Name] = &configschema.Attribute{
+			Type:        ty,
+			Description: a.Description,
+			Required:    a.Required,
+			Optional:    a.Optional,
+			Computed:    a.Computed,
+			Sensitive:   a.Sensitive,
+		}
+	}
+	for _, nb := range b.BlockTypes {
+		nested, err := protoToBlockV6(nb.Block)
+		if err != nil {
+			return nil, fmt.Errorf("block %s: %s", nb.TypeName, err)
+		}
+		block.BlockTypes[nb.TypeName] = &configschema.NestedBlock{
+			Block:    *nested,
+			Nesting:  blockNestingModesV6[nb.Nesting],
+			MinItems: int(nb.MinItems),
+			MaxItems: int(nb.MaxItems),
+		}
+	}
+	return block, nil
+}
+
+var blockNestingModesV6 = map[tfplugin6.Schema_NestedBlock_NestingMode]configschema.NestingMode{
+	tfplugin6.Schema_NestedBlock_SINGLE: configschema.NestingSingle,
+	tfplugin6.Schema_NestedBlock_GROUP:  configschema.NestingGroup,
+	tfplugin6.Schema_NestedBlock_LIST:   configschema.NestingList,
+	tfplugin6.Schema_NestedBlock_SET:    configschema.NestingSet,
+	tfplugin6.Schema_NestedBlock_MAP:    configschema.NestingMap,
+}
+
+// attributeTypeV6 returns the cty type of an attribute. Attributes with a
+// nested type have no type of their own; their type is the object described
+// by the nested attributes, wrapped according to the nesting mode.
+func attributeTypeV6(a *tfplugin6.Schema_Attribute) (cty.Type, error) {
+	if a.NestedType == nil {
+		return ctyjson.UnmarshalType(a.Type)
+	}
+	attrTypes := make(map[string]cty.Type, len(a.NestedType.Attributes))
+	for _, nested := range a.NestedType.Attributes {
+		ty, err := attributeTypeV6(nested)
+		if err != nil {
+			return cty.NilType, fmt.Errorf("nested attribute %s: %s", nested.Name, err)
+		}
+		attrTypes[nested.Name] = ty
+	}
+	obj := cty.Object(attrTypes)
+	switch a.NestedType.Nesting {
+	case tfplugin6.Schema_Object_SINGLE:
+		return obj, nil
+	case tfplugin6.Schema_Object_LIST:
+		return cty.List(obj), nil
+	case tfplugin6.Schema_Object_SET:
+		return cty.Set(obj), nil
+	case tfplugin6.Schema_Object_MAP:
+		return cty.Map(obj), nil
+	}
+	return cty.NilType, fmt.Errorf("invalid nesting mode %d", a.NestedType.Nesting)
+}
+
+func protoToDiagnosticsV6(ds []*tfplugin6.Diagnostic) tfdiags.Diagnostics {
+	var diags tfdiags.Diagnostics
+	for _, d := range ds {
+		severity := tfdiags.Error
+		if d.Severity == tfplugin6.Diagnostic_WARNING {
+			severity = tfdiags.Warning
+		}
+		if d.Attribute != nil {
+			diags = diags.Append(tfdiags.AttributeValue(severity, d.Summary, d.Detail, attributePathToPathV6(d.Attribute)))
+			continue
+		}
+		diags = diags.Append(tfdiags.WholeContainingBody(severity, d.Summary, d.Detail))
+	}
+	return diags
+}
+
+func attributePathToPathV6(ap *tfplugin6.AttributePath) cty.Path {
+	var p cty.Path
+	for _, step := range ap.Steps {
+		switch selector := step.Selector.(type) {
+		case *tfplugin6.AttributePath_Step_AttributeName:
+			p = p.GetAttr(selector.AttributeName)
+		case *tfplugin6.AttributePath_Step_ElementKeyString:
+			p = p.Index(cty.StringVal(selector.ElementKeyString))
+		case *tfplugin6.AttributePath_Step_ElementKeyInt:
+			p = p.Index(cty.NumberIntVal(selector.ElementKeyInt))
+		}
+	}
+	return p
+}
@@ -0,0 +1,66 @@
+package client
+
+import (
+	"testing"
+
+	proto "github.com/golang/protobuf/proto"
+	"github.com/zclconf/go-cty/cty"
+
+	"github.com/crossplane/terraform-provider-runtime/pkg/client/tfplugin6"
+)
+
+func TestProtoToSchemaV6NestedAttributes(t *testing.T) {
+	sent := &tfplugin6.GetProviderSchema_Response{
+		Provider: &tfplugin6.Schema{Block: &tfplugin6.Schema_Block{}},
+		ResourceSchemas: map[string]*tfplugin6.Schema{
+			"fake_resource": {
+				Version: 2,
+				Block: &tfplugin6.Schema_Block{
+					Attributes: []*tfplugin6.Schema_Attribute{
+						{Name: "id", Type: []byte(`"string"`), Computed: true},
+						{
+							Name:     "rules",
+							Optional: true,
+							NestedType: &tfplugin6.Schema_Object{
+								Nesting: tfplugin6.Schema_Object_LIST,
+								Attributes: []*tfplugin6.Schema_Attribute{
+									{Name: "port", Type: []byte(`"number"`), Required: true},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		Diagnostics: []*tfplugin6.Diagnostic{{
+			Severity:  tfplugin6.Diagnostic_WARNING,
+			Summary:   "deprecated",
+			Attribute: &tfplugin6.AttributePath{Steps: []*tfplugin6.AttributePath_Step{{Selector: &tfplugin6.AttributePath_Step_AttributeName{AttributeName: "rules"}}}},
+		}},
+	}
+	b, err := proto.Marshal(sent)
+	if err != nil {
+		t.Fatalf("Unexpected error marshaling GetProviderSchema response: %s", err)
+	}
+	received := &tfplugin6.GetProviderSchema_Response{}
+	if err := proto.Unmarshal(b, received); err != nil {
+		t.Fatalf("Unexpected error unmarshaling GetProviderSchema response: %s", err)
+	}
+
+	s, err := protoToSchemaV6(received.ResourceSchemas["fake_resource"])
+	if err != nil {
+		t.Fatalf("Unexpected error converting protocol 6 schema: %s", err)
+	}
+	expected := cty.Object(map[string]cty.Type{
+		"id":    cty.String,
+		"rules": cty.List(cty.Object(map[string]cty.Type{"port": cty.Number})),
+	})
+	if !s.Block.ImpliedType().Equals(expected) || s.Version != 2 {
+		t.Errorf("Unexpected schema from protocol 6 nested attributes, version=%d type=%s", s.Version, s.Block.ImpliedType().GoString())
+	}
+
+	diags := protoToDiagnosticsV6(received.Diagnostics)
+	if len(diags) != 1 || diags.HasErrors() || diags[0].Description().Summary != "deprecated" {
+		t.Errorf("Expected a single warning diagnostic to survive the round trip, got %v", diags)
+	}
+}
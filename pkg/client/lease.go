@@ -0,0 +1,32 @@
+package client
+
+import (
+	"sync"
+)
+
+// A Lease is a provider borrowed from a ProviderPool. Whoever holds the
+// Lease owns the provider until Release is called.
+type Lease struct {
+	Provider *Provider
+	pool     *ProviderPool
+	once     sync.Once
+	released chan struct{}
+}
+
+// Release returns the provider to the pool it was borrowed from. It is safe
+// to call Release more than once, only the first call has an effect.
+func (l *Lease) Release() {
+	l.once.Do(func() {
+		l.pool.Return(l.Provider)
+		close(l.released)
+	})
+}
+
+// Released is closed once the Lease has been released.
+func (l *Lease) Released() <-chan struct{} {
+	return l.released
+}
+
+func newLease(pool *ProviderPool, provider *Provider) *Lease {
+	return &Lease{Provider: provider, pool: pool, released: make(chan struct{})}
+}
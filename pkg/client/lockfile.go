@@ -0,0 +1,215 @@
+package client
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsimple"
+	"github.com/hashicorp/terraform/plugin/discovery"
+	"golang.org/x/mod/sumdb/dirhash"
+)
+
+const (
+	// HashSchemeDir is terraform's "h1:" hash, a hash over the unpacked
+	// contents of a provider package.
+	HashSchemeDir = "h1:"
+	// HashSchemeZip is terraform's "zh:" hash, the hex SHA256 of the
+	// provider package zip archive.
+	HashSchemeZip = "zh:"
+)
+
+// A LockFile pins provider plugins to a version and a set of acceptable
+// hashes. It is read from the same HCL format terraform uses for
+// .terraform.lock.hcl, eg:
+//
+//	provider "registry.terraform.io/hashicorp/google" {
+//	  version = "3.30.0"
+//	  hashes = [
+//	    "h1:...",
+//	    "zh:...",
+//	  ]
+//	}
+//
+// Hashes are a flat list covering every platform the provider is locked
+// for; a plugin binary is accepted if it matches any of them.
+type LockFile struct {
+	providers map[string]*ProviderLock
+}
+
+// ProviderLock is the lock file entry for a single provider.
+type ProviderLock struct {
+	// Source is the provider source address, eg registry.terraform.io/hashicorp/google
+	Source string `hcl:"source,label"`
+	// Version is the exact version the provider is locked to.
+	Version string `hcl:"version"`
+	// Constraints records the constraint the version was selected with.
+	Constraints string `hcl:"constraints,optional"`
+	// Hashes are the acceptable package hashes, prefixed with their scheme.
+	Hashes []string `hcl:"hashes,optional"`
+}
+
+type lockFileHCL struct {
+	Providers []*ProviderLock `hcl:"provider,block"`
+	Remain    hcl.Body        `hcl:",remain"`
+}
+
+// ReadLockFile parses a provider lock file.
+func ReadLockFile(path string) (*LockFile, error) {
+	parsed := lockFileHCL{}
+	if err := hclsimple.DecodeFile(path, nil, &parsed); err != nil {
+		return nil, fmt.Errorf("Failed to parse provider lock file %s: %s", path, err)
+	}
+	lf := &LockFile{providers: make(map[string]*ProviderLock)}
+	for _, pl := range parsed.Providers {
+		name := pl.Name()
+		if _, ok := lf.providers[name]; ok {
+			return nil, fmt.Errorf("Provider lock file %s has more than one entry for provider %s", path, name)
+		}
+		lf.providers[name] = pl
+	}
+	return lf, nil
+}
+
+// Lock returns the lock entry for a provider by its short name, eg google.
+func (lf *LockFile) Lock(providerName string) (*ProviderLock, bool) {
+	if lf == nil {
+		return nil, false
+	}
+	pl, ok := lf.providers[providerName]
+	return pl, ok
+}
+
+// Name is the provider's short name, the last segment of its source address.
+func (pl *ProviderLock) Name() string {
+	return path.Base(pl.Source)
+}
+
+// Verify checks that the plugin is the locked version and that its package
+// matches one of the locked hashes. "h1:" hashes are checked against the
+// package the plugin binary was unpacked into, see HashPluginPackage. "zh:"
+// hashes can only be checked when archivePath, the archive the package was
+// unpacked from, is known; the unpacked package must then still match it.
+// The package is hashed on every call, so a plugin replaced between spawns
+// is always caught.
+func (pl *ProviderLock) Verify(meta discovery.PluginMeta, archivePath string) error {
+	if string(meta.Version) != pl.Version {
+		return fmt.Errorf("Plugin %s at %s is version %s, but the lock file requires version %s", meta.Name, meta.Path, meta.Version, pl.Version)
+	}
+	dirHashes := pl.hashes(HashSchemeDir)
+	zipHashes := pl.hashes(HashSchemeZip)
+	if len(dirHashes) == 0 && (len(zipHashes) == 0 || archivePath == "") {
+		return fmt.Errorf("Lock file entry for provider %s has no %s hashes, and no package archive to check its %s hashes against, cannot verify plugin binary %s",
+			pl.Source, HashSchemeDir, HashSchemeZip, meta.Path)
+	}
+	actual, err := HashPluginPackage(meta)
+	if err != nil {
+		return fmt.Errorf("Failed to hash plugin package of %s: %s", meta.Path, err)
+	}
+	if contains(dirHashes, actual) {
+		return nil
+	}
+	checked := []string{actual}
+	if len(zipHashes) > 0 && archivePath != "" {
+		zh, err := HashArchive(archivePath)
+		if err != nil {
+			return fmt.Errorf("Failed to hash plugin archive %s: %s", archivePath, err)
+		}
+		unpacked, err := hashArchiveContents(archivePath)
+		if err != nil {
+			return fmt.Errorf("Failed to hash plugin archive %s: %s", archivePath, err)
+		}
+		// the archive only vouches for the plugin if it was unpacked unchanged
+		if contains(zipHashes, zh) && unpacked == actual {
+			return nil
+		}
+		checked = append(checked, zh)
+	}
+	return fmt.Errorf("Refusing to start plugin %s: hashes %s of %s do not match any of the hashes locked for %s %s (%s)",
+		meta.Name, strings.Join(checked, ", "), meta.Path, pl.Source, pl.Version, strings.Join(append(dirHashes, zipHashes...), ", "))
+}
+
+// hashes returns the locked hashes of a scheme.
+func (pl *ProviderLock) hashes(scheme string) []string {
+	hashes := make([]string, 0, len(pl.Hashes))
+	for _, h := range pl.Hashes {
+		if strings.HasPrefix(h, scheme) {
+			hashes = append(hashes, h)
+		}
+	}
+	return hashes
+}
+
+func contains(list []string, s string) bool {
+	for _, e := range list {
+		if e == s {
+			return true
+		}
+	}
+	return false
+}
+
+// PluginPackageDir returns the directory of the provider package a plugin
+// binary was unpacked into, in terraform's unpacked layout
+// <hostname>/<namespace>/<type>/<version>/<os>_<arch>. It is empty for
+// binaries in a flat plugin directory, which holds other plugins too.
+func PluginPackageDir(meta discovery.PluginMeta) string {
+	dir := filepath.Dir(meta.Path)
+	if filepath.Base(dir) != PluginPlatform || filepath.Base(filepath.Dir(dir)) != string(meta.Version) {
+		return ""
+	}
+	return dir
+}
+
+// HashPluginPackage computes terraform's "h1:" hash for the package of a
+// plugin: its package directory when it has one, otherwise a package
+// holding nothing but the binary.
+func HashPluginPackage(meta discovery.PluginMeta) (string, error) {
+	if dir := PluginPackageDir(meta); dir != "" {
+		return HashPackageDir(dir)
+	}
+	return HashPluginBinary(meta.Path)
+}
+
+// HashPackageDir computes terraform's "h1:" hash of an unpacked provider
+// package, covering every file in it like terraform's PackageHashV1.
+func HashPackageDir(dir string) (string, error) {
+	return dirhash.HashDir(dir, "", dirhash.Hash1)
+}
+
+// HashPluginBinary computes terraform's "h1:" hash for a provider package
+// that holds only the given plugin binary. This is what terraform records for
+// provider release archives that contain nothing but the executable.
+func HashPluginBinary(binPath string) (string, error) {
+	return dirhash.Hash1([]string{filepath.Base(binPath)}, func(string) (io.ReadCloser, error) {
+		return os.Open(binPath)
+	})
+}
+
+func hashFile(path string) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return nil, err
+	}
+	return h.Sum(nil), nil
+}
+
+// HashArchive computes terraform's "zh:" hash of a provider package archive.
+func HashArchive(archivePath string) (string, error) {
+	h, err := hashFile(archivePath)
+	if err != nil {
+		return "", err
+	}
+	return HashSchemeZip + hex.EncodeToString(h), nil
+}
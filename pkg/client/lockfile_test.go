@@ -0,0 +1,149 @@
+package client
+
+import (
+	"archive/zip"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform/plugin/discovery"
+)
+
+func TestLockFileVerify(t *testing.T) {
+	root, err := ioutil.TempDir("", "plugin-lockfile")
+	if err != nil {
+		t.Fatalf("Unexpected error creating temp dir: %s", err)
+	}
+	defer os.RemoveAll(root)
+	bin := filepath.Join(root, "terraform-provider-google_v3.30.0_x4")
+	if err := ioutil.WriteFile(bin, []byte("not really a plugin"), 0755); err != nil {
+		t.Fatalf("Unexpected error writing plugin fixture: %s", err)
+	}
+	hash, err := HashPluginBinary(bin)
+	if err != nil {
+		t.Fatalf("Unexpected error hashing plugin fixture: %s", err)
+	}
+
+	lockPath := filepath.Join(root, ".terraform.lock.hcl")
+	lock := fmt.Sprintf(`
+provider "registry.terraform.io/hashicorp/google" {
+  version     = "3.30.0"
+  constraints = "~> 3.30"
+  hashes = [
+    "h1:bm90IHRoZSByaWdodCBoYXNo",
+    "%s",
+    "zh:0123456789abcdef",
+  ]
+}
+`, hash)
+	if err := ioutil.WriteFile(lockPath, []byte(lock), 0644); err != nil {
+		t.Fatalf("Unexpected error writing lock file fixture: %s", err)
+	}
+	lf, err := ReadLockFile(lockPath)
+	if err != nil {
+		t.Fatalf("Unexpected error from ReadLockFile: %s", err)
+	}
+	pl, ok := lf.Lock("google")
+	if !ok {
+		t.Fatalf("Expected to find a lock entry for provider google")
+	}
+
+	meta := discovery.PluginMeta{Name: "google", Version: "3.30.0", Path: bin}
+	if err := pl.Verify(meta, ""); err != nil {
+		t.Errorf("Unexpected error verifying a plugin matching the lock file: %s", err)
+	}
+
+	meta.Version = "3.31.0"
+	if err := pl.Verify(meta, ""); err == nil {
+		t.Errorf("Expected an error verifying a plugin with a version other than the locked one")
+	}
+
+	meta.Version = "3.30.0"
+	if err := ioutil.WriteFile(bin, []byte("tampered"), 0755); err != nil {
+		t.Fatalf("Unexpected error writing plugin fixture: %s", err)
+	}
+	err = pl.Verify(meta, "")
+	if err == nil || !strings.Contains(err.Error(), "Refusing to start plugin") {
+		t.Errorf("Expected Verify to refuse a plugin binary that does not match the locked hashes, err=%v", err)
+	}
+}
+
+// packageFixture unpacks a provider package holding the plugin and a
+// LICENSE into terraform's layout under root, and also writes it as an
+// archive, like terraform providers mirror does.
+func packageFixture(t *testing.T, root string) (discovery.PluginMeta, string) {
+	files := map[string]string{"terraform-provider-google_v3.30.0_x5": "plugin", "LICENSE": "license"}
+	dir := filepath.Join(root, "registry.terraform.io", "hashicorp", "google", "3.30.0", PluginPlatform)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatalf("Unexpected error creating package dir: %s", err)
+	}
+	archivePath := filepath.Join(root, "google.zip")
+	f, err := os.Create(archivePath)
+	if err != nil {
+		t.Fatalf("Unexpected error creating archive fixture: %s", err)
+	}
+	zw := zip.NewWriter(f)
+	for name, content := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0755); err != nil {
+			t.Fatalf("Unexpected error writing package fixture: %s", err)
+		}
+		w, _ := zw.Create(name)
+		w.Write([]byte(content))
+	}
+	zw.Close()
+	f.Close()
+	return discovery.PluginMeta{Name: "google", Version: "3.30.0", Path: filepath.Join(dir, "terraform-provider-google_v3.30.0_x5")}, archivePath
+}
+
+func TestLockFileVerifyPackage(t *testing.T) {
+	root, err := ioutil.TempDir("", "plugin-package")
+	if err != nil {
+		t.Fatalf("Unexpected error creating temp dir: %s", err)
+	}
+	defer os.RemoveAll(root)
+	meta, archivePath := packageFixture(t, root)
+
+	h1, err := hashArchiveContents(archivePath)
+	if err != nil {
+		t.Fatalf("Unexpected error hashing archive fixture: %s", err)
+	}
+	if dirHash, _ := HashPackageDir(PluginPackageDir(meta)); dirHash != h1 {
+		t.Errorf("Expected the unpacked package to hash like the archive contents, got %s and %s", dirHash, h1)
+	}
+	binHash, _ := HashPluginBinary(meta.Path)
+	if binHash == h1 {
+		t.Errorf("Expected the package hash to cover the LICENSE as well as the binary")
+	}
+
+	pl := &ProviderLock{Source: "registry.terraform.io/hashicorp/google", Version: "3.30.0", Hashes: []string{h1}}
+	if err := pl.Verify(meta, ""); err != nil {
+		t.Errorf("Unexpected error verifying a package matching its h1 hash: %s", err)
+	}
+
+	zh, err := HashArchive(archivePath)
+	if err != nil {
+		t.Fatalf("Unexpected error hashing archive fixture: %s", err)
+	}
+	// a lock file made on another platform only has zh hashes for this one
+	pl.Hashes = []string{"h1:bm90IHRoZSByaWdodCBoYXNo", zh}
+	if err := pl.Verify(meta, ""); err == nil || !strings.Contains(err.Error(), "Refusing to start plugin") {
+		t.Errorf("Expected Verify to refuse a package it has no hash for, err=%v", err)
+	}
+	if err := pl.Verify(meta, archivePath); err != nil {
+		t.Errorf("Unexpected error verifying a package unpacked from an archive matching its zh hash: %s", err)
+	}
+	pl.Hashes = []string{zh}
+	if err := pl.Verify(meta, ""); err == nil || !strings.Contains(err.Error(), "no package archive") {
+		t.Errorf("Expected Verify to report that zh hashes need the archive, err=%v", err)
+	}
+
+	if err := ioutil.WriteFile(filepath.Join(PluginPackageDir(meta), "LICENSE"), []byte("changed"), 0644); err != nil {
+		t.Fatalf("Unexpected error writing package fixture: %s", err)
+	}
+	if err := pl.Verify(meta, archivePath); err == nil {
+		t.Errorf("Expected Verify to refuse a package modified after it was unpacked")
+	}
+}
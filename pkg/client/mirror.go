@@ -0,0 +1,310 @@
+package client
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/terraform/plugin/discovery"
+	"golang.org/x/mod/sumdb/dirhash"
+)
+
+// MirrorInstaller installs provider plugins from a filesystem mirror laid out
+// like the output of `terraform providers mirror`:
+//
+//	<mirror>/<hostname>/<namespace>/<type>/index.json
+//	<mirror>/<hostname>/<namespace>/<type>/<version>.json
+//	<mirror>/<hostname>/<namespace>/<type>/terraform-provider-<type>_<version>_<os>_<arch>.zip
+//
+// Packages are unpacked whole into a plugin directory, in terraform's
+// <hostname>/<namespace>/<type>/<version>/<os>_<arch> layout, where
+// NewGRPCProvider can find them and check them against the lock file.
+type MirrorInstaller struct {
+	mirrorDir string
+	pluginDir string
+	platform  string
+	lock      *LockFile
+}
+
+// mirrorIndex is the index.json listing every version in the mirror.
+type mirrorIndex struct {
+	Versions map[string]struct{} `json:"versions"`
+}
+
+// mirrorVersion is the <version>.json listing one archive per platform.
+type mirrorVersion struct {
+	Archives map[string]mirrorArchive `json:"archives"`
+}
+
+type mirrorArchive struct {
+	URL    string   `json:"url"`
+	Hashes []string `json:"hashes"`
+}
+
+// Install picks the newest version of providerName in the mirror allowed by
+// constraint and unpacks its archive for the current platform into the plugin
+// directory. Nothing is unpacked if that version is already installed.
+func (mi *MirrorInstaller) Install(providerName, constraint string) (discovery.PluginMeta, error) {
+	meta, _, err := mi.install(providerName, constraint)
+	return meta, err
+}
+
+// install is Install, also returning the archive the plugin was unpacked
+// from.
+func (mi *MirrorInstaller) install(providerName, constraint string) (discovery.PluginMeta, string, error) {
+	providerDir, err := mi.providerDir(providerName)
+	if err != nil {
+		return discovery.PluginMeta{}, "", err
+	}
+	version, err := mi.selectVersion(providerDir, constraint)
+	if err != nil {
+		return discovery.PluginMeta{}, "", fmt.Errorf("Failed to select a version of %s from plugin mirror %s: %s", providerName, providerDir, err)
+	}
+	vf := mirrorVersion{}
+	if err := readJSONFile(filepath.Join(providerDir, version+".json"), &vf); err != nil {
+		return discovery.PluginMeta{}, "", err
+	}
+	archive, ok := vf.Archives[mi.platform]
+	if !ok {
+		return discovery.PluginMeta{}, "", fmt.Errorf("Plugin mirror %s has no %s %s archive for platform %s", providerDir, providerName, version, mi.platform)
+	}
+	// the mirror only produces relative urls, which are relative to the index
+	archivePath := filepath.Join(providerDir, filepath.FromSlash(archive.URL))
+	source, err := filepath.Rel(mi.mirrorDir, providerDir)
+	if err != nil {
+		return discovery.PluginMeta{}, "", err
+	}
+	packageDir := filepath.Join(mi.pluginDir, source, version, mi.platform)
+
+	if meta, err := FindProviderPlugin(providerName, []string{packageDir}, "= "+version); err == nil {
+		return meta, archivePath, nil
+	}
+
+	hashes := archive.Hashes
+	if len(hashes) == 0 {
+		// terraform providers mirror always records hashes, but a hand made
+		// mirror may rely on the lock file instead
+		if pl, ok := mi.lock.Lock(providerName); ok && pl.Version == version {
+			hashes = pl.Hashes
+		}
+	}
+	if err := verifyArchive(archivePath, hashes); err != nil {
+		return discovery.PluginMeta{}, "", err
+	}
+	if err := unpackPackage(archivePath, packageDir); err != nil {
+		return discovery.PluginMeta{}, "", fmt.Errorf("Failed to unpack plugin archive %s: %s", archivePath, err)
+	}
+
+	meta, err := FindProviderPlugin(providerName, []string{packageDir}, "= "+version)
+	return meta, archivePath, err
+}
+
+// providerDir finds <hostname>/<namespace>/<type> for the named provider.
+func (mi *MirrorInstaller) providerDir(providerName string) (string, error) {
+	matches, err := filepath.Glob(filepath.Join(mi.mirrorDir, "*", "*", providerName, "index.json"))
+	if err != nil {
+		return "", err
+	}
+	switch len(matches) {
+	case 0:
+		return "", fmt.Errorf("Provider %s was not found in plugin mirror %s", providerName, mi.mirrorDir)
+	case 1:
+		return filepath.Dir(matches[0]), nil
+	default:
+		return "", fmt.Errorf("Provider name %s is ambiguous in plugin mirror %s, found: %s", providerName, mi.mirrorDir, strings.Join(matches, ", "))
+	}
+}
+
+func (mi *MirrorInstaller) selectVersion(providerDir, constraint string) (string, error) {
+	allowed := discovery.AllVersions
+	if constraint != "" {
+		c, err := discovery.ConstraintStr(constraint).Parse()
+		if err != nil {
+			return "", err
+		}
+		allowed = c
+	}
+	idx := mirrorIndex{}
+	if err := readJSONFile(filepath.Join(providerDir, "index.json"), &idx); err != nil {
+		return "", err
+	}
+
+	candidates := make([]string, 0, len(idx.Versions))
+	var newest *discovery.Version
+	for vs := range idx.Versions {
+		candidates = append(candidates, vs)
+		v, err := discovery.VersionStr(vs).Parse()
+		if err != nil || !allowed.Allows(v) {
+			continue
+		}
+		if newest == nil || v.NewerThan(*newest) {
+			newest = &v
+		}
+	}
+	if newest == nil {
+		sort.Strings(candidates)
+		return "", fmt.Errorf("no version matches the version constraint %q. Candidates found: %s", constraint, strings.Join(candidates, ", "))
+	}
+	return newest.String(), nil
+}
+
+func readJSONFile(path string, v interface{}) error {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(content, v); err != nil {
+		return fmt.Errorf("Failed to parse %s: %s", path, err)
+	}
+	return nil
+}
+
+// verifyArchive checks the archive against the hashes the mirror, or the
+// lock file, recorded for it. "zh:" hashes cover the archive itself and
+// "h1:" hashes its contents. Archives without any hashes are refused.
+func verifyArchive(archivePath string, hashes []string) error {
+	if len(hashes) == 0 {
+		return fmt.Errorf("Refusing to install plugin archive %s: neither the plugin mirror nor the lock file has hashes for it", archivePath)
+	}
+	zh, err := HashArchive(archivePath)
+	if err != nil {
+		return err
+	}
+	h1, err := hashArchiveContents(archivePath)
+	if err != nil {
+		return err
+	}
+	for _, h := range hashes {
+		if h == zh || h == h1 {
+			return nil
+		}
+	}
+	return fmt.Errorf("Refusing to install plugin archive %s: hashes %s and %s do not match any of the hashes recorded for it (%s)",
+		archivePath, zh, h1, strings.Join(hashes, ", "))
+}
+
+// hashArchiveContents computes the "h1:" hash of the files in a zip archive,
+// which is the same as the hash of the directory it unpacks to.
+func hashArchiveContents(archivePath string) (string, error) {
+	return dirhash.HashZip(archivePath, dirhash.Hash1)
+}
+
+// unpackPackage extracts every file of a provider archive into dir, so
+// that the unpacked package hashes like the archive contents. The package
+// is unpacked next to dir and renamed into place, so a concurrent discovery
+// never sees a partially written plugin.
+func unpackPackage(archivePath, dir string) error {
+	zr, err := zip.OpenReader(archivePath)
+	if err != nil {
+		return err
+	}
+	defer zr.Close()
+	if err := os.MkdirAll(filepath.Dir(dir), 0755); err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempDir(filepath.Dir(dir), ".installing-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmp)
+
+	prefix := "terraform-" + ProviderPluginType + "-"
+	plugins := 0
+	for _, f := range zr.File {
+		if strings.HasSuffix(f.Name, "/") {
+			continue
+		}
+		name := filepath.FromSlash(f.Name)
+		if filepath.IsAbs(name) || strings.HasPrefix(filepath.Clean(name), "..") {
+			return fmt.Errorf("archive entry %s is outside of the package", f.Name)
+		}
+		if err := unpackFile(f, filepath.Join(tmp, name)); err != nil {
+			return err
+		}
+		if strings.HasPrefix(filepath.Base(name), prefix) {
+			plugins++
+		}
+	}
+	if plugins == 0 {
+		return fmt.Errorf("archive does not contain a provider plugin executable")
+	}
+	if err := os.RemoveAll(dir); err != nil {
+		return err
+	}
+	return os.Rename(tmp, dir)
+}
+
+func unpackFile(f *zip.File, dest string) error {
+	r, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return err
+	}
+	mode := f.Mode().Perm()
+	// zip tools that do not record permissions would leave the plugin
+	// impossible to run
+	if strings.HasPrefix(filepath.Base(dest), "terraform-"+ProviderPluginType+"-") {
+		mode |= 0755
+	}
+	w, err := os.OpenFile(dest, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(w, r); err != nil {
+		w.Close()
+		return err
+	}
+	return w.Close()
+}
+
+// NewMirrorInstaller creates a MirrorInstaller that installs plugins for the
+// current platform from mirrorDir into pluginDir.
+func NewMirrorInstaller(mirrorDir, pluginDir string) *MirrorInstaller {
+	return &MirrorInstaller{
+		mirrorDir: mirrorDir,
+		pluginDir: pluginDir,
+		platform:  PluginPlatform,
+	}
+}
+
+// WithLockFile lets archives the mirror has no hashes for be installed if
+// the lock file has hashes for them.
+func (mi *MirrorInstaller) WithLockFile(lf *LockFile) *MirrorInstaller {
+	mi.lock = lf
+	return mi
+}
+
+// InstallMirroredPlugins installs the named providers from the plugin mirror
+// configured in the RuntimeOptions, honoring their version constraints, and
+// records the archive each was installed from so that lock file "zh:"
+// hashes can be checked. It does nothing if no mirror is configured.
+func InstallMirroredPlugins(ropts *RuntimeOptions, providerNames ...string) error {
+	if ropts.PluginMirrorDirectory == "" {
+		return nil
+	}
+	mi := NewMirrorInstaller(ropts.PluginMirrorDirectory, ropts.GetPluginDirectory())
+	if ropts.PluginLockFile != "" {
+		lf, err := ReadLockFile(ropts.PluginLockFile)
+		if err != nil {
+			return err
+		}
+		mi.WithLockFile(lf)
+	}
+	for _, name := range providerNames {
+		meta, archivePath, err := mi.install(name, ropts.GetVersionConstraint(name))
+		if err != nil {
+			return err
+		}
+		ropts.WithPluginArchive(meta.Path, archivePath)
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+package client
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeMirrorFixture(t *testing.T, providerDir, version string, hashes func(archive string) []string) {
+	archiveName := fmt.Sprintf("terraform-provider-google_%s_%s.zip", version, PluginPlatform)
+	archivePath := filepath.Join(providerDir, archiveName)
+	f, err := os.Create(archivePath)
+	if err != nil {
+		t.Fatalf("Unexpected error creating archive fixture: %s", err)
+	}
+	zw := zip.NewWriter(f)
+	for _, name := range []string{"terraform-provider-google_v" + version + "_x5", "LICENSE"} {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatalf("Unexpected error writing archive fixture: %s", err)
+		}
+		w.Write([]byte(name))
+	}
+	zw.Close()
+	f.Close()
+
+	vf := mirrorVersion{Archives: map[string]mirrorArchive{
+		PluginPlatform: {URL: archiveName, Hashes: hashes(archivePath)},
+	}}
+	content, _ := json.Marshal(vf)
+	if err := ioutil.WriteFile(filepath.Join(providerDir, version+".json"), content, 0644); err != nil {
+		t.Fatalf("Unexpected error writing version fixture: %s", err)
+	}
+}
+
+func TestMirrorInstallerInstall(t *testing.T) {
+	root, err := ioutil.TempDir("", "plugin-mirror")
+	if err != nil {
+		t.Fatalf("Unexpected error creating temp dir: %s", err)
+	}
+	defer os.RemoveAll(root)
+	mirrorDir := filepath.Join(root, "mirror")
+	pluginDir := filepath.Join(root, "plugins")
+	providerDir := filepath.Join(mirrorDir, "registry.terraform.io", "hashicorp", "google")
+	if err := os.MkdirAll(providerDir, 0755); err != nil {
+		t.Fatalf("Unexpected error creating mirror dir: %s", err)
+	}
+	index := `{"versions": {"3.30.0": {}, "3.31.0": {}, "4.0.0": {}, "5.0.0": {}}}`
+	if err := ioutil.WriteFile(filepath.Join(providerDir, "index.json"), []byte(index), 0644); err != nil {
+		t.Fatalf("Unexpected error writing index fixture: %s", err)
+	}
+	contentHash := func(archive string) []string {
+		h, err := hashArchiveContents(archive)
+		if err != nil {
+			t.Fatalf("Unexpected error hashing archive fixture: %s", err)
+		}
+		return []string{h}
+	}
+	badHash := func(string) []string {
+		return []string{"zh:0123456789abcdef"}
+	}
+	noHash := func(string) []string {
+		return nil
+	}
+	writeMirrorFixture(t, providerDir, "3.30.0", contentHash)
+	writeMirrorFixture(t, providerDir, "3.31.0", contentHash)
+	writeMirrorFixture(t, providerDir, "4.0.0", badHash)
+	writeMirrorFixture(t, providerDir, "5.0.0", noHash)
+
+	mi := NewMirrorInstaller(mirrorDir, pluginDir)
+	meta, err := mi.Install("google", "~> 3.30")
+	if err != nil {
+		t.Fatalf("Unexpected error from Install: %s", err)
+	}
+	if meta.Version != "3.31.0" {
+		t.Errorf("Expected the newest version allowed by '~> 3.30' to be installed, got %s", meta.Version)
+	}
+	// the whole package is unpacked, so that it hashes like terraform's
+	packageDir := PluginPackageDir(meta)
+	if want := filepath.Join(pluginDir, "registry.terraform.io", "hashicorp", "google", "3.31.0", PluginPlatform); packageDir != want {
+		t.Errorf("Expected the package to be unpacked into %s, got plugin %s", want, meta.Path)
+	}
+	if _, err := os.Stat(filepath.Join(packageDir, "LICENSE")); err != nil {
+		t.Errorf("Expected every file in the archive to be unpacked: %s", err)
+	}
+	h1, _ := HashPackageDir(packageDir)
+	if want := contentHash(filepath.Join(providerDir, "terraform-provider-google_3.31.0_"+PluginPlatform+".zip")); h1 != want[0] {
+		t.Errorf("Expected the unpacked package to match the archive's h1 hash %s, got %s", want[0], h1)
+	}
+
+	if _, err := mi.Install("google", "~> 4.0"); err == nil {
+		t.Errorf("Expected Install to refuse an archive that does not match the mirror's hashes")
+	}
+	if _, err := mi.Install("google", ">= 5.0"); err == nil {
+		t.Errorf("Expected Install to refuse an archive without hashes")
+	}
+	if _, err := mi.Install("google", ">= 6.0"); err == nil {
+		t.Errorf("Expected Install to fail when no mirrored version matches the constraint")
+	}
+
+	// a lock file can vouch for an archive the mirror has no hashes for
+	zh, _ := HashArchive(filepath.Join(providerDir, "terraform-provider-google_5.0.0_"+PluginPlatform+".zip"))
+	lf := &LockFile{providers: map[string]*ProviderLock{"google": {Source: "registry.terraform.io/hashicorp/google", Version: "5.0.0", Hashes: []string{zh}}}}
+	if _, err := mi.WithLockFile(lf).Install("google", ">= 5.0"); err != nil {
+		t.Errorf("Unexpected error installing an archive covered by the lock file: %s", err)
+	}
+}
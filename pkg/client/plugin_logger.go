@@ -0,0 +1,262 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/crossplane/crossplane-runtime/pkg/logging"
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+	hclog "github.com/hashicorp/go-hclog"
+)
+
+// PluginLogLevelEnv is the environment variable terraform and provider
+// plugins read their log level from. It is used as the plugin log level for
+// providers that do not have one configured, and is passed on to plugins
+// that do.
+const PluginLogLevelEnv = "TF_LOG"
+
+// DefaultPluginLogLevel is the plugin log level used when neither the
+// RuntimeOptions nor TF_LOG set one.
+const DefaultPluginLogLevel = "WARN"
+
+// pluginLogOff is more severe than any hclog level, so it suppresses them all.
+const pluginLogOff = hclog.Error + 1
+
+// ParsePluginLogLevel parses a TF_LOG style log level: TRACE, DEBUG, INFO,
+// WARN, ERROR or OFF, in any case.
+func ParsePluginLogLevel(level string) (hclog.Level, error) {
+	if strings.EqualFold(strings.TrimSpace(level), "off") {
+		return pluginLogOff, nil
+	}
+	l := hclog.LevelFromString(level)
+	if l == hclog.NoLevel {
+		return hclog.NoLevel, fmt.Errorf("Invalid plugin log level %q, expected one of TRACE, DEBUG, INFO, WARN, ERROR or OFF", level)
+	}
+	return l, nil
+}
+
+// pluginLogScope identifies where in the ProviderPool a plugin process lives
+// and which resource it is currently serving. Providers are reused across
+// resources, so the GVK changes as the provider is borrowed and returned.
+// The plugin's gRPC calls are traced under the scope's trace context.
+type pluginLogScope struct {
+	slot  int
+	gvk   atomic.Value
+	trace atomic.Value
+}
+
+// traceContext wraps the contexts stored in pluginLogScope.trace, which
+// must all have the same concrete type.
+type traceContext struct {
+	ctx context.Context
+}
+
+func newPluginLogScope(slot int) *pluginLogScope {
+	s := &pluginLogScope{slot: slot}
+	s.gvk.Store("")
+	s.trace.Store(traceContext{ctx: context.Background()})
+	return s
+}
+
+// setTraceContext sets the context the plugin's gRPC calls are traced
+// under, or clears it when ctx is nil.
+func (s *pluginLogScope) setTraceContext(ctx context.Context) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	s.trace.Store(traceContext{ctx: ctx})
+}
+
+func (s *pluginLogScope) traceContext() context.Context {
+	if s == nil {
+		return context.Background()
+	}
+	return s.trace.Load().(traceContext).ctx
+}
+
+// setResource records the resource the provider has been borrowed for, or
+// clears it when res is nil.
+func (s *pluginLogScope) setResource(res resource.Managed) {
+	gvk := ""
+	if res != nil {
+		gvk = res.GetObjectKind().GroupVersionKind().String()
+	}
+	s.gvk.Store(gvk)
+}
+
+func (s *pluginLogScope) values() []interface{} {
+	if s == nil {
+		return nil
+	}
+	return []interface{}{"slot", s.slot, "gvk", s.gvk.Load().(string)}
+}
+
+// pluginLogger is an hclog.Logger that forwards go-plugin's logs, and the
+// provider plugin's stderr which go-plugin relays, to a crossplane
+// logging.Logger. TRACE and DEBUG are logged with Debug, everything more
+// severe with Info; the hclog level is kept in the "level" value.
+type pluginLogger struct {
+	log   logging.Logger
+	scope *pluginLogScope
+	name  string
+	args  []interface{}
+	// level is shared with sub-loggers so that SetLevel affects them all
+	level *int32
+}
+
+// newPluginLogger returns the hclog.Logger for the named provider's plugin
+// client, logging to ropts.Logger at the provider's configured level.
+func newPluginLogger(providerName string, ropts *RuntimeOptions) (hclog.Logger, error) {
+	level, err := ParsePluginLogLevel(ropts.GetPluginLogLevel(providerName))
+	if err != nil {
+		return nil, err
+	}
+	base := ropts.Logger
+	if base == nil {
+		base = logging.NewNopLogger()
+	}
+	l := int32(level)
+	return &pluginLogger{
+		log:   base.WithValues("provider", providerName),
+		scope: ropts.logScope,
+		level: &l,
+	}, nil
+}
+
+func (pl *pluginLogger) emit(level hclog.Level, msg string, args []interface{}) {
+	if level < hclog.Level(atomic.LoadInt32(pl.level)) {
+		return
+	}
+	kv := make([]interface{}, 0, len(pl.args)+len(args)+8)
+	kv = append(kv, "level", strings.ToUpper(levelName(level)))
+	if pl.name != "" {
+		kv = append(kv, "plugin", pl.name)
+	}
+	kv = append(kv, pl.scope.values()...)
+	kv = append(kv, pl.args...)
+	kv = append(kv, pairs(args)...)
+	if level <= hclog.Debug {
+		pl.log.Debug(msg, kv...)
+		return
+	}
+	pl.log.Info(msg, kv...)
+}
+
+func (pl *pluginLogger) Log(level hclog.Level, msg string, args ...interface{}) {
+	pl.emit(level, msg, args)
+}
+
+func (pl *pluginLogger) Trace(msg string, args ...interface{}) { pl.emit(hclog.Trace, msg, args) }
+func (pl *pluginLogger) Debug(msg string, args ...interface{}) { pl.emit(hclog.Debug, msg, args) }
+func (pl *pluginLogger) Info(msg string, args ...interface{})  { pl.emit(hclog.Info, msg, args) }
+func (pl *pluginLogger) Warn(msg string, args ...interface{})  { pl.emit(hclog.Warn, msg, args) }
+func (pl *pluginLogger) Error(msg string, args ...interface{}) { pl.emit(hclog.Error, msg, args) }
+
+func (pl *pluginLogger) enabled(level hclog.Level) bool {
+	return level >= hclog.Level(atomic.LoadInt32(pl.level))
+}
+
+func (pl *pluginLogger) IsTrace() bool { return pl.enabled(hclog.Trace) }
+func (pl *pluginLogger) IsDebug() bool { return pl.enabled(hclog.Debug) }
+func (pl *pluginLogger) IsInfo() bool  { return pl.enabled(hclog.Info) }
+func (pl *pluginLogger) IsWarn() bool  { return pl.enabled(hclog.Warn) }
+func (pl *pluginLogger) IsError() bool { return pl.enabled(hclog.Error) }
+
+func (pl *pluginLogger) With(args ...interface{}) hclog.Logger {
+	sub := *pl
+	sub.args = append(append([]interface{}{}, pl.args...), pairs(args)...)
+	return &sub
+}
+
+func (pl *pluginLogger) ImpliedArgs() []interface{} {
+	return pl.args
+}
+
+func (pl *pluginLogger) Name() string {
+	return pl.name
+}
+
+func (pl *pluginLogger) Named(name string) hclog.Logger {
+	sub := *pl
+	if sub.name != "" {
+		name = sub.name + "." + name
+	}
+	sub.name = name
+	return &sub
+}
+
+func (pl *pluginLogger) ResetNamed(name string) hclog.Logger {
+	sub := *pl
+	sub.name = name
+	return &sub
+}
+
+func (pl *pluginLogger) SetLevel(level hclog.Level) {
+	atomic.StoreInt32(pl.level, int32(level))
+}
+
+func (pl *pluginLogger) StandardLogger(opts *hclog.StandardLoggerOptions) *log.Logger {
+	return log.New(pl.StandardWriter(opts), "", 0)
+}
+
+func (pl *pluginLogger) StandardWriter(opts *hclog.StandardLoggerOptions) io.Writer {
+	infer := opts != nil && opts.InferLevels
+	return &pluginLogWriter{logger: pl, inferLevels: infer}
+}
+
+// pluginLogWriter adapts a pluginLogger to an io.Writer for the standard
+// library logger, optionally picking the level from a [LEVEL] prefix.
+type pluginLogWriter struct {
+	mu          sync.Mutex
+	logger      *pluginLogger
+	inferLevels bool
+}
+
+func (w *pluginLogWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	line := strings.TrimSpace(string(p))
+	level := hclog.Info
+	if w.inferLevels {
+		for _, l := range []hclog.Level{hclog.Trace, hclog.Debug, hclog.Info, hclog.Warn, hclog.Error} {
+			prefix := "[" + strings.ToUpper(levelName(l)) + "]"
+			if strings.HasPrefix(line, prefix) {
+				level = l
+				line = strings.TrimSpace(strings.TrimPrefix(line, prefix))
+				break
+			}
+		}
+	}
+	w.logger.emit(level, line, nil)
+	return len(p), nil
+}
+
+func levelName(level hclog.Level) string {
+	switch level {
+	case hclog.Trace:
+		return "trace"
+	case hclog.Debug:
+		return "debug"
+	case hclog.Info:
+		return "info"
+	case hclog.Warn:
+		return "warn"
+	case hclog.Error:
+		return "error"
+	}
+	return "off"
+}
+
+// pairs makes hclog's key/value arguments safe for logging.Logger, which
+// requires an even number of them, the same way hclog itself does.
+func pairs(args []interface{}) []interface{} {
+	if len(args)%2 == 0 {
+		return args
+	}
+	return append(args[:len(args)-1:len(args)-1], "EXTRA_VALUE_AT_END", args[len(args)-1])
+}
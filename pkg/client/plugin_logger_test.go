@@ -0,0 +1,75 @@
+package client
+
+import (
+	"testing"
+
+	"github.com/crossplane/crossplane-runtime/pkg/logging"
+	"github.com/crossplane/crossplane-runtime/pkg/resource/fake"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+type recordedLog struct {
+	debug bool
+	msg   string
+	kv    []interface{}
+}
+
+type recordingLogger struct {
+	logs *[]recordedLog
+	kv   []interface{}
+}
+
+func (r recordingLogger) Info(msg string, kv ...interface{}) {
+	*r.logs = append(*r.logs, recordedLog{msg: msg, kv: append(r.kv, kv...)})
+}
+
+func (r recordingLogger) Debug(msg string, kv ...interface{}) {
+	*r.logs = append(*r.logs, recordedLog{debug: true, msg: msg, kv: append(r.kv, kv...)})
+}
+
+func (r recordingLogger) WithValues(kv ...interface{}) logging.Logger {
+	return recordingLogger{logs: r.logs, kv: append(append([]interface{}{}, r.kv...), kv...)}
+}
+
+func valueOf(kv []interface{}, key string) interface{} {
+	for i := 0; i+1 < len(kv); i += 2 {
+		if kv[i] == key {
+			return kv[i+1]
+		}
+	}
+	return nil
+}
+
+func TestPluginLoggerForwardsToLogger(t *testing.T) {
+	logs := make([]recordedLog, 0)
+	scope := newPluginLogScope(2)
+	res := &fake.Managed{}
+	res.GetObjectKind().SetGroupVersionKind(schema.GroupVersionKind{Group: "compute.gcp.terraform-plugin.crossplane.io", Version: "v1alpha1", Kind: "ComputeInstance"})
+	scope.setResource(res)
+	ropts := NewRuntimeOptions().WithPluginLogLevel("google", "debug").withLogScope(scope, recordingLogger{logs: &logs})
+	hl, err := newPluginLogger("google", ropts)
+	if err != nil {
+		t.Fatalf("Unexpected error creating plugin logger: %s", err)
+	}
+
+	hl.Trace("dropped below the configured level")
+	hl.Named("terraform-provider-google").Debug("starting plugin", "pid")
+	hl.Error("plugin exited", "code", 1)
+	if len(logs) != 2 {
+		t.Fatalf("Expected trace logs to be dropped at DEBUG, got %d logs", len(logs))
+	}
+	if !logs[0].debug || logs[1].debug {
+		t.Errorf("Expected DEBUG to go to Debug and ERROR to Info")
+	}
+	kv := logs[0].kv
+	if valueOf(kv, "provider") != "google" || valueOf(kv, "slot") != 2 || valueOf(kv, "gvk") != res.GetObjectKind().GroupVersionKind().String() {
+		t.Errorf("Expected plugin logs to carry the provider, slot and gvk, got %v", kv)
+	}
+	if valueOf(kv, "plugin") != "terraform-provider-google" || valueOf(kv, "EXTRA_VALUE_AT_END") != "pid" {
+		t.Errorf("Expected the plugin name and an even number of values, got %v", kv)
+	}
+
+	if _, err := newPluginLogger("google", NewRuntimeOptions().WithPluginLogLevel("google", "loud")); err == nil {
+		t.Errorf("Expected an invalid plugin log level to be rejected")
+	}
+}
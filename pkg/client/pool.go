@@ -2,51 +2,338 @@ package client
 
 import (
 	"context"
+	"sync"
+	"time"
 
+	"github.com/crossplane/crossplane-runtime/pkg/logging"
 	"github.com/crossplane/crossplane-runtime/pkg/resource"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	kubeclient "sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/crossplane/terraform-provider-runtime/pkg/tracing"
+)
+
+// DefaultPoolReapInterval is how often the pool checks for idle providers
+// when an IdleTimeout is configured.
+var DefaultPoolReapInterval = 30 * time.Second
+
+// Attributes of ProviderPool.Borrow spans: how long the borrow waited for a
+// free slot, and whether it spawned a new provider.
+const (
+	attributePoolWait    = attribute.Key("pool.wait_ms")
+	attributePoolSpawned = attribute.Key("pool.spawned")
 )
 
+// DefaultLeaseLeakThreshold is how long a provider can be borrowed before
+// the pool reports it as a possible leak.
+var DefaultLeaseLeakThreshold = 5 * time.Minute
+
+// idleProvider is a provider sitting in the pool, waiting to be borrowed.
+type idleProvider struct {
+	provider *Provider
+	since    time.Time
+}
+
+// borrowRecord tracks who is holding a borrowed provider, so that
+// providers held for too long can be reported as leaks.
+type borrowRecord struct {
+	since    time.Time
+	holder   string
+	reported bool
+}
+
+// ProviderPool hands out provider plugin processes to reconcilers.
+// Plugins are spawned lazily, only when no idle provider is available, so the
+// pool grows under contention up to maxSize. Providers that sit idle for
+// longer than idleTimeout are shut down, but the pool never shrinks below
+// minSize. With leader election, nothing is spawned until the pool is
+// started on the elected leader.
 type ProviderPool struct {
-	size               int
-	indexFifo          chan int
-	providers          []*Provider
-	reverseMap         map[*Provider]int
+	minSize            int
+	maxSize            int
+	idleTimeout        time.Duration
+	reapInterval       time.Duration
+	leakThreshold      time.Duration
+	slots              chan struct{}
+	mu                 sync.Mutex
+	idle               []idleProvider
+	borrowed           map[*Provider]*borrowRecord
+	logScopes          map[*Provider]*pluginLogScope
+	slotInUse          []bool
 	initializeProvider Initializer
 	runtimeOptions     *RuntimeOptions
+	logger             logging.Logger
+	limiter            *RateLimiter
+	leaderElection     bool
+	elected            chan struct{}
+	electedOnce        sync.Once
 }
 
+// Borrow returns an idle provider if one is available, otherwise spawns a new
+// one. When maxSize providers are already borrowed, Borrow blocks until one
+// is returned or the context is done.
 func (pp *ProviderPool) Borrow(ctx context.Context, res resource.Managed, kube kubeclient.Client) (*Provider, error) {
-	index := <-pp.indexFifo
-	if pp.providers[index] == nil {
-		provider, err := pp.initializeProvider(ctx, res, pp.runtimeOptions, kube)
-		if err != nil {
-			pp.indexFifo <- index
-			return provider, err
+	ctx, span := tracing.Start(ctx, "ProviderPool.Borrow", tracing.ResourceAttributes(res)...)
+	provider, err := pp.borrow(ctx, res, kube)
+	tracing.End(span, err)
+	return provider, err
+}
+
+func (pp *ProviderPool) borrow(ctx context.Context, res resource.Managed, kube kubeclient.Client) (*Provider, error) {
+	span := trace.SpanFromContext(ctx)
+	waitStart := time.Now()
+	if pp.leaderElection {
+		select {
+		case <-pp.elected:
+		case <-ctx.Done():
+			return nil, ctx.Err()
 		}
-		pp.reverseMap[provider] = index
-		pp.providers[index] = provider
 	}
-	return pp.providers[index], nil
+	select {
+	case pp.slots <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	span.SetAttributes(attributePoolWait.Int64(time.Since(waitStart).Milliseconds()))
+
+	pp.mu.Lock()
+	// idle is used as a stack so that recently used providers stay warm and
+	// the ones at the bottom age out when demand drops.
+	if n := len(pp.idle); n > 0 {
+		provider := pp.idle[n-1].provider
+		pp.idle = pp.idle[:n-1]
+		pp.borrowed[provider] = newBorrowRecord(res)
+		pp.logScopes[provider].setResource(res)
+		pp.mu.Unlock()
+		span.SetAttributes(attributePoolSpawned.Bool(false))
+		return provider, nil
+	}
+	scope := newPluginLogScope(pp.claimSlot())
+	pp.mu.Unlock()
+
+	span.SetAttributes(attributePoolSpawned.Bool(true))
+	scope.setResource(res)
+	// the calls made while spawning and configuring the plugin are part of
+	// the borrow
+	scope.setTraceContext(ctx)
+	provider, err := pp.initializeProvider(ctx, res, pp.runtimeOptions.withLogScope(scope, pp.logger), kube)
+	scope.setTraceContext(nil)
+	if err != nil {
+		pp.mu.Lock()
+		pp.slotInUse[scope.slot] = false
+		pp.mu.Unlock()
+		<-pp.slots
+		return provider, err
+	}
+	provider.scope = scope
+	pp.mu.Lock()
+	pp.borrowed[provider] = newBorrowRecord(res)
+	pp.logScopes[provider] = scope
+	pp.mu.Unlock()
+	return provider, nil
 }
 
+// Throttle takes a token from the rate limits of the Provider object res
+// uses and of its terraform resource type, before a provider is borrowed
+// for it. It returns a ThrottledError if either has none left.
+func (pp *ProviderPool) Throttle(res resource.Managed, resourceType string) error {
+	providerName := ""
+	if ref := res.GetProviderReference(); ref != nil {
+		providerName = ref.Name
+	}
+	return pp.limiter.Take(providerName, resourceType, time.Now())
+}
+
+// Lease borrows a provider from the pool and wraps it in a Lease, which
+// must be released once the caller is done with the provider.
+func (pp *ProviderPool) Lease(ctx context.Context, res resource.Managed, kube kubeclient.Client) (*Lease, error) {
+	provider, err := pp.Borrow(ctx, res, kube)
+	if err != nil {
+		return nil, err
+	}
+	return newLease(pp, provider), nil
+}
+
+// Return puts a borrowed provider back into the pool. Providers that were
+// not borrowed from this pool are ignored.
 func (pp *ProviderPool) Return(p *Provider) {
-	index := pp.reverseMap[p]
-	pp.indexFifo <- index
+	pp.mu.Lock()
+	if _, ok := pp.borrowed[p]; !ok {
+		pp.mu.Unlock()
+		return
+	}
+	delete(pp.borrowed, p)
+	// the holder's observer must not see the next borrower's diagnostics
+	p.DiagnosticsObserver = nil
+	pp.logScopes[p].setResource(nil)
+	pp.logScopes[p].setTraceContext(nil)
+	pp.idle = append(pp.idle, idleProvider{provider: p, since: time.Now()})
+	pp.mu.Unlock()
+	<-pp.slots
 }
 
-func NewProviderPool(initializer Initializer, ropts *RuntimeOptions) *ProviderPool {
-	size := ropts.PoolSize
+// Size returns the number of live providers, idle and borrowed.
+func (pp *ProviderPool) Size() int {
+	pp.mu.Lock()
+	defer pp.mu.Unlock()
+	return len(pp.idle) + len(pp.borrowed)
+}
+
+// Borrowed returns the number of providers currently borrowed.
+func (pp *ProviderPool) Borrowed() int {
+	pp.mu.Lock()
+	defer pp.mu.Unlock()
+	return len(pp.borrowed)
+}
+
+// Reap shuts down providers that have been idle for longer than the
+// configured idle timeout, keeping at least minSize providers alive.
+func (pp *ProviderPool) Reap(now time.Time) {
+	if pp.idleTimeout <= 0 {
+		return
+	}
+	pp.mu.Lock()
+	expired := make([]*Provider, 0)
+	live := len(pp.idle) + len(pp.borrowed)
+	// the oldest idle providers are at the bottom of the stack
+	keep := 0
+	for keep < len(pp.idle) && live > pp.minSize && now.Sub(pp.idle[keep].since) > pp.idleTimeout {
+		expired = append(expired, pp.idle[keep].provider)
+		pp.releaseSlot(pp.idle[keep].provider)
+		keep++
+		live--
+	}
+	pp.idle = append(pp.idle[:0], pp.idle[keep:]...)
+	pp.mu.Unlock()
+
+	for _, p := range expired {
+		p.Close()
+	}
+}
+
+// ReportLeaks logs every provider that has been borrowed for longer than
+// the lease leak threshold. Each borrow is only reported once.
+func (pp *ProviderPool) ReportLeaks(now time.Time) {
+	pp.mu.Lock()
+	defer pp.mu.Unlock()
+	for _, br := range pp.borrowed {
+		held := now.Sub(br.since)
+		if br.reported || held < pp.leakThreshold {
+			continue
+		}
+		br.reported = true
+		pp.logger.Info("Provider has been borrowed from the ProviderPool for longer than expected, it may have leaked",
+			"holder", br.holder, "held", held.String())
+	}
+}
+
+// Start runs the idle provider reaper and the lease leak detector until stop
+// is closed, at which point all idle providers are shut down. It satisfies the controller-runtime
+// manager.Runnable interface so the pool can be added to a manager.
+func (pp *ProviderPool) Start(stop <-chan struct{}) error {
+	pp.electedOnce.Do(func() { close(pp.elected) })
+	ticker := time.NewTicker(pp.reapInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case now := <-ticker.C:
+			pp.Reap(now)
+			pp.ReportLeaks(now)
+		case <-stop:
+			pp.Close()
+			return nil
+		}
+	}
+}
+
+// NeedLeaderElection reports that the pool is only started on the elected
+// leader, so that standby replicas never spawn plugins.
+func (pp *ProviderPool) NeedLeaderElection() bool {
+	return true
+}
+
+// Close shuts down all idle providers. Borrowed providers are left alone.
+func (pp *ProviderPool) Close() {
+	pp.mu.Lock()
+	idle := pp.idle
+	pp.idle = make([]idleProvider, 0)
+	for _, ip := range idle {
+		pp.releaseSlot(ip.provider)
+	}
+	pp.mu.Unlock()
+	for _, ip := range idle {
+		ip.provider.Close()
+	}
+}
+
+// claimSlot returns the lowest pool slot not used by a live provider. Only
+// one provider is spawned per borrowed slot, so a free one always exists.
+// pp.mu must be held.
+func (pp *ProviderPool) claimSlot() int {
+	for i, used := range pp.slotInUse {
+		if !used {
+			pp.slotInUse[i] = true
+			return i
+		}
+	}
+	pp.slotInUse = append(pp.slotInUse, true)
+	return len(pp.slotInUse) - 1
+}
+
+// releaseSlot frees the slot of a provider that is being shut down.
+// pp.mu must be held.
+func (pp *ProviderPool) releaseSlot(p *Provider) {
+	if scope, ok := pp.logScopes[p]; ok {
+		pp.slotInUse[scope.slot] = false
+		delete(pp.logScopes, p)
+	}
+}
+
+func newBorrowRecord(res resource.Managed) *borrowRecord {
+	br := &borrowRecord{since: time.Now()}
+	if res != nil {
+		br.holder = res.GetObjectKind().GroupVersionKind().Kind + "/" + res.GetName()
+	}
+	return br
+}
+
+func NewProviderPool(initializer Initializer, ropts *RuntimeOptions, log logging.Logger) *ProviderPool {
+	maxSize := ropts.PoolSize
+	if maxSize < 1 {
+		maxSize = DefaultProviderPoolSize
+	}
+	minSize := ropts.MinPoolSize
+	if minSize > maxSize {
+		minSize = maxSize
+	}
+	reapInterval := DefaultPoolReapInterval
+	if ropts.IdleTimeout > 0 && ropts.IdleTimeout < reapInterval {
+		reapInterval = ropts.IdleTimeout
+	}
+	leakThreshold := ropts.LeaseLeakThreshold
+	if leakThreshold <= 0 {
+		leakThreshold = DefaultLeaseLeakThreshold
+	}
 	pool := &ProviderPool{
-		size:               size,
-		indexFifo:          make(chan int, size),
-		providers:          make([]*Provider, size, size),
-		reverseMap:         make(map[*Provider]int),
+		minSize:            minSize,
+		maxSize:            maxSize,
+		idleTimeout:        ropts.IdleTimeout,
+		reapInterval:       reapInterval,
+		leakThreshold:      leakThreshold,
+		slots:              make(chan struct{}, maxSize),
+		idle:               make([]idleProvider, 0),
+		borrowed:           make(map[*Provider]*borrowRecord),
+		logScopes:          make(map[*Provider]*pluginLogScope),
+		slotInUse:          make([]bool, maxSize),
 		initializeProvider: initializer,
 		runtimeOptions:     ropts,
+		logger:             log,
+		leaderElection:     ropts.LeaderElection,
+		elected:            make(chan struct{}),
 	}
-	for i := 0; i < size; i++ {
-		pool.indexFifo <- i
+	if len(ropts.ProviderRateLimits) > 0 || len(ropts.ResourceRateLimits) > 0 {
+		pool.limiter = NewRateLimiter(ropts.ProviderRateLimits, ropts.ResourceRateLimits)
 	}
 
 	return pool
@@ -0,0 +1,157 @@
+package client
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/crossplane/crossplane-runtime/pkg/logging"
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+	"github.com/crossplane/crossplane-runtime/pkg/resource/fake"
+	kubeclient "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func countingInitializer(count *int) Initializer {
+	return func(context.Context, resource.Managed, *RuntimeOptions, kubeclient.Client) (*Provider, error) {
+		*count++
+		return &Provider{}, nil
+	}
+}
+
+func TestProviderPoolReusesIdleProviders(t *testing.T) {
+	spawned := 0
+	pool := NewProviderPool(countingInitializer(&spawned), NewRuntimeOptions().WithPoolSize(3), logging.NewNopLogger())
+	ctx := context.Background()
+	for i := 0; i < 5; i++ {
+		p, err := pool.Borrow(ctx, &fake.Managed{}, nil)
+		if err != nil {
+			t.Fatalf("Unexpected error from Borrow: %s", err)
+		}
+		pool.Return(p)
+	}
+	if spawned != 1 {
+		t.Errorf("Expected sequential borrows to reuse a single provider, spawned=%d", spawned)
+	}
+}
+
+func TestProviderPoolGrowsToMaxUnderContention(t *testing.T) {
+	spawned := 0
+	pool := NewProviderPool(countingInitializer(&spawned), NewRuntimeOptions().WithPoolSize(2), logging.NewNopLogger())
+	ctx := context.Background()
+	p1, _ := pool.Borrow(ctx, &fake.Managed{}, nil)
+	p2, _ := pool.Borrow(ctx, &fake.Managed{}, nil)
+	if spawned != 2 || pool.Size() != 2 {
+		t.Errorf("Expected concurrent borrows to grow the pool to 2, spawned=%d size=%d", spawned, pool.Size())
+	}
+
+	waitCtx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	if _, err := pool.Borrow(waitCtx, &fake.Managed{}, nil); err != context.DeadlineExceeded {
+		t.Errorf("Expected Borrow beyond max size to block until the context expired, err=%v", err)
+	}
+	pool.Return(p1)
+	pool.Return(p2)
+}
+
+func TestProviderPoolOnlySpawnsOnLeader(t *testing.T) {
+	spawned := 0
+	pool := NewProviderPool(countingInitializer(&spawned), NewRuntimeOptions().WithLeaderElection(), logging.NewNopLogger())
+	ctx := context.Background()
+
+	waitCtx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	if _, err := pool.Borrow(waitCtx, &fake.Managed{}, nil); err != context.DeadlineExceeded {
+		t.Errorf("Expected Borrow before the pool was started to block, err=%v", err)
+	}
+	if spawned != 0 {
+		t.Errorf("Expected no provider to be spawned on a standby replica, spawned=%d", spawned)
+	}
+
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		_ = pool.Start(stop)
+		close(done)
+	}()
+	p, err := pool.Borrow(ctx, &fake.Managed{}, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error from Borrow once elected: %s", err)
+	}
+	if spawned != 1 {
+		t.Errorf("Expected the elected pool to spawn a provider, spawned=%d", spawned)
+	}
+	pool.Return(p)
+	close(stop)
+	<-done
+}
+
+func TestProviderPoolReapsIdleProviders(t *testing.T) {
+	spawned := 0
+	ropts := NewRuntimeOptions().WithPoolSize(3).WithMinPoolSize(1).WithIdleTimeout(time.Minute)
+	pool := NewProviderPool(countingInitializer(&spawned), ropts, logging.NewNopLogger())
+	ctx := context.Background()
+	providers := make([]*Provider, 0)
+	for i := 0; i < 3; i++ {
+		p, _ := pool.Borrow(ctx, &fake.Managed{}, nil)
+		providers = append(providers, p)
+	}
+	for _, p := range providers {
+		pool.Return(p)
+	}
+
+	pool.Reap(time.Now())
+	if pool.Size() != 3 {
+		t.Errorf("Expected recently returned providers to survive Reap, size=%d", pool.Size())
+	}
+	pool.Reap(time.Now().Add(2 * time.Minute))
+	if pool.Size() != 1 {
+		t.Errorf("Expected Reap to shrink the pool down to MinPoolSize, size=%d", pool.Size())
+	}
+}
+
+func TestLeaseReleaseIsIdempotent(t *testing.T) {
+	spawned := 0
+	pool := NewProviderPool(countingInitializer(&spawned), NewRuntimeOptions().WithPoolSize(1), logging.NewNopLogger())
+	ctx := context.Background()
+	lease, err := pool.Lease(ctx, &fake.Managed{}, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error from Lease: %s", err)
+	}
+	lease.Release()
+	lease.Release()
+
+	// with a pool of 1, a second Release returning the slot twice would let
+	// both of these through, and the third would not block.
+	l1, _ := pool.Lease(ctx, &fake.Managed{}, nil)
+	waitCtx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	if _, err := pool.Lease(waitCtx, &fake.Managed{}, nil); err != context.DeadlineExceeded {
+		t.Errorf("Expected a double Release to only return the provider once, err=%v", err)
+	}
+	l1.Release()
+}
+
+func TestReportLeaksReportsOnce(t *testing.T) {
+	spawned := 0
+	ropts := NewRuntimeOptions().WithLeaseLeakThreshold(time.Minute)
+	pool := NewProviderPool(countingInitializer(&spawned), ropts, logging.NewNopLogger())
+	p, err := pool.Borrow(context.Background(), &fake.Managed{}, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error from Borrow: %s", err)
+	}
+	reported := func() bool {
+		pool.mu.Lock()
+		defer pool.mu.Unlock()
+		return pool.borrowed[p].reported
+	}
+
+	pool.ReportLeaks(time.Now())
+	if reported() {
+		t.Errorf("Expected a provider borrowed within the threshold not to be reported")
+	}
+	pool.ReportLeaks(time.Now().Add(2 * time.Minute))
+	if !reported() {
+		t.Errorf("Expected a provider borrowed beyond the threshold to be reported")
+	}
+	pool.Return(p)
+}
@@ -1,27 +1,45 @@
 package client
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"fmt"
 	"io/ioutil"
+	"os"
+	"time"
 
+	"github.com/crossplane/crossplane-runtime/pkg/logging"
 	"github.com/crossplane/crossplane-runtime/pkg/resource"
 	"github.com/hashicorp/terraform/configs/configschema"
-	tfplugin "github.com/hashicorp/terraform/plugin"
 	"github.com/hashicorp/terraform/providers"
+	"github.com/hashicorp/terraform/tfdiags"
 	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/convert"
+	ctyjson "github.com/zclconf/go-cty/cty/json"
 	kubeclient "sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/yaml"
 )
 
-// FakeTerraformVersion is a nice lie we tell to providers to keep them happy
-// TODO: is there a more sane way to negotiate version compat w/ providers?
-const FakeTerraformVersion string = "v0.12.26"
-
 // Provider wraps grpcProvider with some additional metadata like the provider name
 type Provider struct {
-	GRPCProvider *tfplugin.GRPCProvider
+	// GRPCProvider speaks to the plugin process, through either the
+	// protocol 5 or the protocol 6 client.
+	GRPCProvider providers.Interface
 	Name         string
 	Config       ProviderConfig
+	// ProtocolVersion is the plugin protocol version negotiated with the
+	// plugin, 5 or 6.
+	ProtocolVersion int
+	// TerraformVersion is the terraform version reported to the provider in
+	// Configure. When empty, it is picked from TerraformVersionCompatibility.
+	TerraformVersion string
+	// DiagnosticsObserver, when set, is passed the diagnostics of every
+	// resource operation the api package runs with this Provider.
+	DiagnosticsObserver func(tfdiags.Diagnostics)
+
+	// scope is set by the ProviderPool the Provider was spawned by
+	scope *pluginLogScope
 }
 
 // ProviderConfig models the on-disk yaml config for providers
@@ -29,6 +47,45 @@ type ProviderConfig struct {
 	TerraformConfig cty.Value `json:"config"`
 }
 
+// UnmarshalJSON decodes the config block into a cty object, with types
+// implied by the JSON values. Configure converts them to the types in the
+// provider schema.
+func (pc *ProviderConfig) UnmarshalJSON(b []byte) error {
+	raw := struct {
+		Config json.RawMessage `json:"config"`
+	}{}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&raw); err != nil {
+		return err
+	}
+	if len(raw.Config) == 0 || string(raw.Config) == "null" {
+		pc.TerraformConfig = cty.EmptyObjectVal
+		return nil
+	}
+	ty, err := ctyjson.ImpliedType(raw.Config)
+	if err != nil {
+		return err
+	}
+	if !ty.IsObjectType() {
+		return fmt.Errorf("provider config must be an object, got %s", ty.FriendlyName())
+	}
+	pc.TerraformConfig, err = ctyjson.Unmarshal(raw.Config, ty)
+	return err
+}
+
+// Values returns the config attributes, in the form NewProvider expects.
+func (pc ProviderConfig) Values() map[string]cty.Value {
+	if pc.TerraformConfig == cty.NilVal || pc.TerraformConfig.IsNull() {
+		return make(map[string]cty.Value)
+	}
+	values := pc.TerraformConfig.AsValueMap()
+	if values == nil {
+		values = make(map[string]cty.Value)
+	}
+	return values
+}
+
 // Configure calls the provider's grpc configuration interface,
 // also translating the ProviderConfig structure to the
 // Provider's encoded HCL representation.
@@ -39,7 +96,15 @@ func (p *Provider) Configure(cfg map[string]cty.Value) error {
 	}
 	// TODO: does not address nested blocks in the config
 	for key, attr := range schema.Attributes {
-		if _, ok := cfg[key]; !ok {
+		if v, ok := cfg[key]; ok {
+			// values read from a config file have JSON implied types, eg
+			// tuples rather than lists
+			converted, err := convert.Convert(v, attr.Type)
+			if err != nil {
+				return fmt.Errorf("provider config attribute %s: %s", key, err)
+			}
+			cfg[key] = converted
+		} else {
 			switch attr.Type.FriendlyName() {
 			case "string":
 				cfg[key] = cty.NullVal(cty.String)
@@ -56,7 +121,7 @@ func (p *Provider) Configure(cfg map[string]cty.Value) error {
 	}
 	ctyCfg := cty.ObjectVal(cfg)
 	cfgReq := providers.ConfigureRequest{
-		TerraformVersion: FakeTerraformVersion,
+		TerraformVersion: p.GetTerraformVersion(),
 		Config:           ctyCfg,
 	}
 	cfgResp := p.GRPCProvider.Configure(cfgReq)
@@ -87,20 +152,63 @@ func ReadProviderConfigFile(path string) (ProviderConfig, error) {
 // NewProvider constructs a Provider, which is a container type, holding a
 // terraform provider plugin grpc client, as well as metadata about this provider
 // instance, eg its configuration and type.
-func NewProvider(providerName string, pluginDir string, cfg map[string]cty.Value) (*Provider, error) {
-	grpc, err := NewGRPCProvider(providerName, pluginDir)
+func NewProvider(providerName string, ropts *RuntimeOptions, cfg map[string]cty.Value) (*Provider, error) {
+	// checked before spawning, for callers that did not validate their options
+	tfVersion := ropts.TerraformVersions[providerName]
+	if tfVersion != "" {
+		if err := validateTerraformVersion(providerName, tfVersion); err != nil {
+			return nil, err
+		}
+	}
+	grpc, err := NewGRPCProvider(providerName, ropts)
 	if err != nil {
 		return nil, err
 	}
 	provider := &Provider{
-		Name:         providerName,
-		GRPCProvider: grpc,
+		Name:             providerName,
+		GRPCProvider:     grpc,
+		ProtocolVersion:  ProtocolVersion(grpc),
+		TerraformVersion: tfVersion,
 	}
 	err = provider.Configure(cfg)
 
 	return provider, err
 }
 
+// TraceContext returns the context the provider's gRPC calls are traced
+// under.
+func (p *Provider) TraceContext() context.Context {
+	return p.scope.traceContext()
+}
+
+// SetTraceContext traces the provider's gRPC calls under ctx, until the
+// returned function restores the previous context. It has no effect on
+// Providers that were not spawned by a ProviderPool.
+func (p *Provider) SetTraceContext(ctx context.Context) func() {
+	if p.scope == nil {
+		return func() {}
+	}
+	prev := p.scope.traceContext()
+	p.scope.setTraceContext(ctx)
+	return func() { p.scope.setTraceContext(prev) }
+}
+
+// GetTerraformVersion returns the terraform version reported to the provider.
+func (p *Provider) GetTerraformVersion() string {
+	if p.TerraformVersion != "" {
+		return p.TerraformVersion
+	}
+	return DefaultTerraformVersion(p.ProtocolVersion)
+}
+
+// Close shuts down the provider plugin process.
+func (p *Provider) Close() error {
+	if p == nil || p.GRPCProvider == nil {
+		return nil
+	}
+	return p.GRPCProvider.Close()
+}
+
 func GetProviderSchema(p *Provider) (*configschema.Block, error) {
 	resp := p.GRPCProvider.GetSchema()
 	if resp.Diagnostics.HasErrors() {
@@ -114,14 +222,120 @@ type Initializer func(context.Context, resource.Managed, *RuntimeOptions, kubecl
 var DefaultProviderPoolSize = 5
 
 type RuntimeOptions struct {
-	PoolSize        int
+	// PoolSize is the maximum number of provider plugin processes
+	// the ProviderPool will run at once.
+	PoolSize int
+	// MinPoolSize is the number of providers the ProviderPool keeps
+	// alive even when they are idle.
+	MinPoolSize int
+	// IdleTimeout is how long a provider can sit unused in the
+	// ProviderPool before it is shut down. Zero disables idle eviction.
+	IdleTimeout time.Duration
+	// LeaseLeakThreshold is how long a provider can be borrowed from
+	// the ProviderPool before it is logged as a possible leak.
+	LeaseLeakThreshold time.Duration
+	// PluginDirectory is the first directory searched for provider plugins.
 	PluginDirectory string
+	// PluginDirectories are additional directories searched for provider
+	// plugins, after PluginDirectory. The OS/arch subdirectory of every
+	// directory (eg linux_amd64) is searched as well.
+	PluginDirectories []string
+	// VersionConstraints maps provider names to a version constraint,
+	// eg "~> 3.30", limiting which plugin versions will be used.
+	VersionConstraints map[string]string
+	// PluginLockFile is the path to a provider lock file, in the format of
+	// terraform's .terraform.lock.hcl. When set, plugin binaries are only
+	// started if they match a locked version and hash.
+	PluginLockFile string
+	// PluginArchives maps plugin binaries to the package archive they were
+	// unpacked from, so that "zh:" lock file hashes can be checked.
+	PluginArchives map[string]string
+	// PluginMirrorDirectory is a filesystem mirror, in the layout written
+	// by `terraform providers mirror`, that provider plugins are installed
+	// from into PluginDirectory at startup.
+	PluginMirrorDirectory string
+	// ReattachProviders maps provider names to providers that are already
+	// running, eg under a debugger, which the runtime connects to instead of
+	// spawning a plugin. TF_REATTACH_PROVIDERS is honored as well.
+	ReattachProviders map[string]ReattachConfig
+	// PluginLogLevels maps provider names to the TF_LOG style level, eg
+	// TRACE or OFF, their plugin logs are forwarded to Logger at.
+	// Providers without a level use TF_LOG, or DefaultPluginLogLevel.
+	PluginLogLevels map[string]string
+	// Logger receives the logs of provider plugins.
+	Logger logging.Logger
+	// TerraformVersions maps provider names to the terraform version
+	// reported to them, overriding TerraformVersionCompatibility.
+	TerraformVersions map[string]string
+	// RecordingMode is "record" to record all provider traffic to
+	// RecordingFile, or "replay" to serve it back from RecordingFile
+	// without running provider plugins. See pkg/client/recording.
+	RecordingMode string
+	// RecordingFile is the golden file used by RecordingMode.
+	RecordingFile string
+	// LenientReplay makes replay answer requests that were not recorded
+	// exactly with the next recorded interaction for the same call, instead
+	// of an error.
+	LenientReplay bool
+	// ProviderRateLimits maps Provider object names, or RateLimitAny, to
+	// the rate at which resources using them are reconciled.
+	ProviderRateLimits map[string]RateLimit
+	// ResourceRateLimits maps terraform resource types, or RateLimitAny, to
+	// the rate at which resources of the type are reconciled.
+	ResourceRateLimits map[string]RateLimit
+	// LeaderElection makes the ProviderPool wait until it is started, which
+	// a controller manager only does on the elected leader, before spawning
+	// plugins.
+	LeaderElection bool
+
+	// logScope is set by the ProviderPool on the RuntimeOptions it passes
+	// to the Initializer, tagging plugin logs with the pool slot and GVK.
+	logScope *pluginLogScope
 }
 
-var DefaultPluginDirectory string = "/Users/kasey/src/crossplane/provider-terraform-gcp/.terraform/plugins/darwin_amd64/"
+// DefaultPluginDirectory is where terraform init puts provider plugins,
+// relative to the working directory.
+var DefaultPluginDirectory string = ".terraform/plugins"
 
 func (ro *RuntimeOptions) GetPluginDirectory() string {
-	return DefaultPluginDirectory
+	if ro.PluginDirectory == "" {
+		return DefaultPluginDirectory
+	}
+	return ro.PluginDirectory
+}
+
+// GetPluginDirectories returns the plugin search path, PluginDirectory
+// followed by PluginDirectories.
+func (ro *RuntimeOptions) GetPluginDirectories() []string {
+	return append([]string{ro.GetPluginDirectory()}, ro.PluginDirectories...)
+}
+
+// GetVersionConstraint returns the version constraint for the named
+// provider, or an empty string if any version is allowed.
+func (ro *RuntimeOptions) GetVersionConstraint(providerName string) string {
+	return ro.VersionConstraints[providerName]
+}
+
+// GetPluginLogLevel returns the log level for the named provider's plugin.
+func (ro *RuntimeOptions) GetPluginLogLevel(providerName string) string {
+	if level := ro.PluginLogLevels[providerName]; level != "" {
+		return level
+	}
+	if level := os.Getenv(PluginLogLevelEnv); level != "" {
+		return level
+	}
+	return DefaultPluginLogLevel
+}
+
+// withLogScope returns a copy of the RuntimeOptions that tags plugin logs
+// with scope, logging to log if no Logger is set.
+func (ro *RuntimeOptions) withLogScope(scope *pluginLogScope, log logging.Logger) *RuntimeOptions {
+	scoped := *ro
+	if scoped.Logger == nil {
+		scoped.Logger = log
+	}
+	scoped.logScope = scope
+	return &scoped
 }
 
 func (ro *RuntimeOptions) WithPluginDirectory(dir string) *RuntimeOptions {
@@ -129,11 +343,118 @@ func (ro *RuntimeOptions) WithPluginDirectory(dir string) *RuntimeOptions {
 	return ro
 }
 
+func (ro *RuntimeOptions) WithPluginDirectories(dirs ...string) *RuntimeOptions {
+	ro.PluginDirectories = append(ro.PluginDirectories, dirs...)
+	return ro
+}
+
+func (ro *RuntimeOptions) WithVersionConstraint(providerName, constraint string) *RuntimeOptions {
+	if ro.VersionConstraints == nil {
+		ro.VersionConstraints = make(map[string]string)
+	}
+	ro.VersionConstraints[providerName] = constraint
+	return ro
+}
+
 func (ro *RuntimeOptions) WithPoolSize(size int) *RuntimeOptions {
 	ro.PoolSize = size
 	return ro
 }
 
+func (ro *RuntimeOptions) WithMinPoolSize(size int) *RuntimeOptions {
+	ro.MinPoolSize = size
+	return ro
+}
+
+func (ro *RuntimeOptions) WithIdleTimeout(d time.Duration) *RuntimeOptions {
+	ro.IdleTimeout = d
+	return ro
+}
+
+func (ro *RuntimeOptions) WithLeaseLeakThreshold(d time.Duration) *RuntimeOptions {
+	ro.LeaseLeakThreshold = d
+	return ro
+}
+
+func (ro *RuntimeOptions) WithPluginLockFile(path string) *RuntimeOptions {
+	ro.PluginLockFile = path
+	return ro
+}
+
+func (ro *RuntimeOptions) WithPluginArchive(pluginPath, archivePath string) *RuntimeOptions {
+	if ro.PluginArchives == nil {
+		ro.PluginArchives = make(map[string]string)
+	}
+	ro.PluginArchives[pluginPath] = archivePath
+	return ro
+}
+
+func (ro *RuntimeOptions) WithPluginMirrorDirectory(dir string) *RuntimeOptions {
+	ro.PluginMirrorDirectory = dir
+	return ro
+}
+
+func (ro *RuntimeOptions) WithReattachProvider(providerName string, rc ReattachConfig) *RuntimeOptions {
+	if ro.ReattachProviders == nil {
+		ro.ReattachProviders = make(map[string]ReattachConfig)
+	}
+	ro.ReattachProviders[providerName] = rc
+	return ro
+}
+
+func (ro *RuntimeOptions) WithPluginLogLevel(providerName, level string) *RuntimeOptions {
+	if ro.PluginLogLevels == nil {
+		ro.PluginLogLevels = make(map[string]string)
+	}
+	ro.PluginLogLevels[providerName] = level
+	return ro
+}
+
+func (ro *RuntimeOptions) WithTerraformVersion(providerName, version string) *RuntimeOptions {
+	if ro.TerraformVersions == nil {
+		ro.TerraformVersions = make(map[string]string)
+	}
+	ro.TerraformVersions[providerName] = version
+	return ro
+}
+
+func (ro *RuntimeOptions) WithRecording(mode, path string) *RuntimeOptions {
+	ro.RecordingMode = mode
+	ro.RecordingFile = path
+	return ro
+}
+
+func (ro *RuntimeOptions) WithLenientReplay(lenient bool) *RuntimeOptions {
+	ro.LenientReplay = lenient
+	return ro
+}
+
+func (ro *RuntimeOptions) WithProviderRateLimit(providerName string, limit RateLimit) *RuntimeOptions {
+	if ro.ProviderRateLimits == nil {
+		ro.ProviderRateLimits = make(map[string]RateLimit)
+	}
+	ro.ProviderRateLimits[providerName] = limit
+	return ro
+}
+
+func (ro *RuntimeOptions) WithResourceRateLimit(resourceType string, limit RateLimit) *RuntimeOptions {
+	if ro.ResourceRateLimits == nil {
+		ro.ResourceRateLimits = make(map[string]RateLimit)
+	}
+	ro.ResourceRateLimits[resourceType] = limit
+	return ro
+}
+
+func (ro *RuntimeOptions) WithLeaderElection() *RuntimeOptions {
+	ro.LeaderElection = true
+	return ro
+}
+
+func (ro *RuntimeOptions) WithLogger(log logging.Logger) *RuntimeOptions {
+	ro.Logger = log
+	return ro
+}
+
 func NewRuntimeOptions() *RuntimeOptions {
 	return &RuntimeOptions{}
 }
@@ -0,0 +1,133 @@
+package client
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/time/rate"
+)
+
+// RateLimitAny keys the rate limit of the Provider objects or terraform
+// resource types that have none of their own.
+const RateLimitAny = "*"
+
+// A RateLimit is a token bucket holding up to Burst tokens, refilled at QPS
+// tokens per second.
+type RateLimit struct {
+	QPS   float64
+	Burst int
+}
+
+// ParseRateLimit parses a RateLimit written as QPS or QPS:BURST, eg 5 or
+// 0.5:10. The burst defaults to the QPS rounded up.
+func ParseRateLimit(s string) (RateLimit, error) {
+	parts := strings.SplitN(s, ":", 2)
+	qps, err := strconv.ParseFloat(parts[0], 64)
+	if err != nil || qps <= 0 {
+		return RateLimit{}, fmt.Errorf("Invalid rate limit %q, expected a positive QPS, optionally followed by :BURST", s)
+	}
+	l := RateLimit{QPS: qps, Burst: int(math.Ceil(qps))}
+	if len(parts) == 2 {
+		if l.Burst, err = strconv.Atoi(parts[1]); err != nil || l.Burst < 1 {
+			return RateLimit{}, fmt.Errorf("Invalid rate limit %q, expected a burst of at least 1", s)
+		}
+	}
+	return l, nil
+}
+
+// ThrottledError is returned when a rate limit has no token left.
+type ThrottledError struct {
+	// Limit is the throttling bucket, eg provider/default or
+	// resource/google_compute_instance.
+	Limit string
+	// Delay is how long until the bucket has a token again.
+	Delay time.Duration
+}
+
+func (e *ThrottledError) Error() string {
+	return fmt.Sprintf("Throttled by rate limit %s, retry in %s", e.Limit, e.Delay)
+}
+
+// IsThrottled returns the ThrottledError err is caused by, if any.
+func IsThrottled(err error) (*ThrottledError, bool) {
+	te, ok := errors.Cause(err).(*ThrottledError)
+	return te, ok
+}
+
+// A RateLimiter keeps a token bucket per Provider object and per terraform
+// resource type. Objects and types without a RateLimit of their own each
+// get a bucket with the RateLimitAny limit, if there is one.
+type RateLimiter struct {
+	providers     map[string]RateLimit
+	resourceTypes map[string]RateLimit
+
+	mu      sync.Mutex
+	buckets map[string]*rate.Limiter
+}
+
+// NewRateLimiter returns a RateLimiter with the RateLimits of Provider
+// objects and resource types, keyed by name.
+func NewRateLimiter(providers, resourceTypes map[string]RateLimit) *RateLimiter {
+	return &RateLimiter{providers: providers, resourceTypes: resourceTypes, buckets: make(map[string]*rate.Limiter)}
+}
+
+// Take takes a token from the buckets of the Provider object and of the
+// resource type. If either is empty, it takes none and returns a
+// ThrottledError.
+func (l *RateLimiter) Take(providerName, resourceType string, now time.Time) error {
+	if l == nil {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	taken := make([]*rate.Reservation, 0, 2)
+	throttled := &ThrottledError{}
+	for _, b := range []struct {
+		name   string
+		limits map[string]RateLimit
+		key    string
+	}{
+		{name: "provider/" + providerName, limits: l.providers, key: providerName},
+		{name: "resource/" + resourceType, limits: l.resourceTypes, key: resourceType},
+	} {
+		bucket := l.bucket(b.name, b.limits, b.key)
+		if bucket == nil {
+			continue
+		}
+		r := bucket.ReserveN(now, 1)
+		taken = append(taken, r)
+		if delay := r.DelayFrom(now); delay > throttled.Delay {
+			throttled.Limit, throttled.Delay = b.name, delay
+		}
+	}
+	if throttled.Delay == 0 {
+		return nil
+	}
+	for _, r := range taken {
+		r.CancelAt(now)
+	}
+	return throttled
+}
+
+// bucket returns the named bucket, limited by the RateLimit of key in
+// limits, or nil if key is not limited. l.mu must be held.
+func (l *RateLimiter) bucket(name string, limits map[string]RateLimit, key string) *rate.Limiter {
+	if b, ok := l.buckets[name]; ok {
+		return b
+	}
+	limit, ok := limits[key]
+	if !ok {
+		limit, ok = limits[RateLimitAny]
+	}
+	var b *rate.Limiter
+	if ok {
+		b = rate.NewLimiter(rate.Limit(limit.QPS), limit.Burst)
+	}
+	l.buckets[name] = b
+	return b
+}
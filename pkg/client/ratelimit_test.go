@@ -0,0 +1,53 @@
+package client
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseRateLimit(t *testing.T) {
+	for in, want := range map[string]RateLimit{
+		"5":      {QPS: 5, Burst: 5},
+		"0.5":    {QPS: 0.5, Burst: 1},
+		"0.5:10": {QPS: 0.5, Burst: 10},
+	} {
+		if got, err := ParseRateLimit(in); err != nil || got != want {
+			t.Errorf("ParseRateLimit(%q) = %+v, %v, want %+v", in, got, err, want)
+		}
+	}
+	for _, in := range []string{"", "fast", "-1", "1:0", "1:x"} {
+		if _, err := ParseRateLimit(in); err == nil {
+			t.Errorf("Expected ParseRateLimit(%q) to fail", in)
+		}
+	}
+}
+
+func TestRateLimiterTake(t *testing.T) {
+	l := NewRateLimiter(
+		map[string]RateLimit{RateLimitAny: {QPS: 1, Burst: 2}},
+		map[string]RateLimit{"google_compute_instance": {QPS: 1, Burst: 1}},
+	)
+	now := time.Now()
+	if err := l.Take("team-a", "google_compute_instance", now); err != nil {
+		t.Fatalf("Unexpected error from the first Take: %s", err)
+	}
+	err := l.Take("team-a", "google_compute_instance", now)
+	te, ok := IsThrottled(err)
+	if !ok || te.Limit != "resource/google_compute_instance" || te.Delay != time.Second {
+		t.Fatalf("Expected the resource type limit to throttle for 1s, got %v", err)
+	}
+	// the throttled Take left the provider's second token in place
+	if err := l.Take("team-a", "google_storage_bucket", now); err != nil {
+		t.Errorf("Expected an unlimited resource type to use the provider's remaining token, got %s", err)
+	}
+	if _, ok := IsThrottled(l.Take("team-a", "google_storage_bucket", now)); !ok {
+		t.Errorf("Expected the provider limit to throttle once its burst is used")
+	}
+	// every Provider object gets its own bucket with the * limit
+	if err := l.Take("team-b", "google_storage_bucket", now); err != nil {
+		t.Errorf("Expected another Provider object to have its own bucket, got %s", err)
+	}
+	if err := l.Take("team-a", "google_compute_instance", now.Add(time.Second)); err != nil {
+		t.Errorf("Expected a token once the buckets refilled, got %s", err)
+	}
+}
@@ -0,0 +1,123 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"path"
+
+	plugin "github.com/hashicorp/go-plugin"
+	tfplugin "github.com/hashicorp/terraform/plugin"
+)
+
+// ReattachProvidersEnv is the environment variable terraform reads to
+// reattach to providers started in debug mode. Providers print the value to
+// use when they are started with -debug.
+const ReattachProvidersEnv = "TF_REATTACH_PROVIDERS"
+
+// DefaultReattachProtocolVersion is the plugin protocol version assumed when
+// a reattach config does not say which one the provider speaks.
+const DefaultReattachProtocolVersion = 5
+
+// ReattachConfig describes a provider process that is already running, in
+// the format used by TF_REATTACH_PROVIDERS. Rather than spawning a plugin,
+// the runtime connects to this process, so it can be run under a debugger.
+type ReattachConfig struct {
+	Protocol        string
+	ProtocolVersion int
+	Pid             int
+	Test            bool
+	Addr            ReattachAddr
+}
+
+// ReattachAddr is the address the provider's gRPC server listens on.
+type ReattachAddr struct {
+	Network string
+	String  string
+}
+
+// ParseReattachProviders parses a TF_REATTACH_PROVIDERS value. It is a JSON
+// object mapping provider addresses, eg registry.terraform.io/hashicorp/google,
+// to their ReattachConfig. The returned map is keyed by the short provider
+// name, eg google.
+func ParseReattachProviders(value string) (map[string]ReattachConfig, error) {
+	raw := make(map[string]ReattachConfig)
+	if err := json.Unmarshal([]byte(value), &raw); err != nil {
+		return nil, fmt.Errorf("Failed to parse %s: %s", ReattachProvidersEnv, err)
+	}
+	byName := make(map[string]ReattachConfig, len(raw))
+	for addr, rc := range raw {
+		byName[path.Base(addr)] = rc
+	}
+	return byName, nil
+}
+
+// pluginReattachConfig translates a ReattachConfig to the go-plugin type,
+// resolving the address.
+func (rc ReattachConfig) pluginReattachConfig() (*plugin.ReattachConfig, error) {
+	var addr net.Addr
+	var err error
+	switch rc.Addr.Network {
+	case "unix":
+		addr, err = net.ResolveUnixAddr("unix", rc.Addr.String)
+	case "tcp":
+		addr, err = net.ResolveTCPAddr("tcp", rc.Addr.String)
+	default:
+		return nil, fmt.Errorf("Unsupported reattach network %q", rc.Addr.Network)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Invalid reattach address %s: %s", rc.Addr.String, err)
+	}
+	return &plugin.ReattachConfig{
+		Protocol: plugin.Protocol(rc.Protocol),
+		Addr:     addr,
+		Pid:      rc.Pid,
+		// the runtime did not start the process, so it must never kill it
+		Test: true,
+	}, nil
+}
+
+// reattachConfigFor returns the reattach config for the named provider, from
+// the RuntimeOptions or, failing that, from TF_REATTACH_PROVIDERS.
+func reattachConfigFor(providerName string, ropts *RuntimeOptions) (*ReattachConfig, error) {
+	if rc, ok := ropts.ReattachProviders[providerName]; ok {
+		return &rc, nil
+	}
+	value := os.Getenv(ReattachProvidersEnv)
+	if value == "" {
+		return nil, nil
+	}
+	configs, err := ParseReattachProviders(value)
+	if err != nil {
+		return nil, err
+	}
+	if rc, ok := configs[providerName]; ok {
+		return &rc, nil
+	}
+	return nil, nil
+}
+
+// newReattachClientConfig builds a go-plugin client config that connects to
+// an already-running provider instead of spawning one.
+func newReattachClientConfig(rc *ReattachConfig) (*plugin.ClientConfig, error) {
+	reattach, err := rc.pluginReattachConfig()
+	if err != nil {
+		return nil, err
+	}
+	version := rc.ProtocolVersion
+	if version == 0 {
+		version = DefaultReattachProtocolVersion
+	}
+	plugins, ok := VersionedPlugins[version]
+	if !ok {
+		return nil, fmt.Errorf("Cannot reattach to provider speaking unsupported plugin protocol version %d", version)
+	}
+	return &plugin.ClientConfig{
+		Reattach:        reattach,
+		HandshakeConfig: tfplugin.Handshake,
+		// with no handshake to negotiate a version, the plugin set is picked up front
+		Plugins:          plugins,
+		AllowedProtocols: []plugin.Protocol{plugin.ProtocolGRPC},
+	}, nil
+}
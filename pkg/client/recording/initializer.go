@@ -0,0 +1,63 @@
+package recording
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+	kubeclient "sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/crossplane/terraform-provider-runtime/pkg/client"
+)
+
+const (
+	// ModeRecord records provider traffic to RuntimeOptions.RecordingFile.
+	ModeRecord = "record"
+	// ModeReplay replays provider traffic from RuntimeOptions.RecordingFile
+	// instead of running provider plugins.
+	ModeReplay = "replay"
+)
+
+// WrapInitializer returns a client.Initializer that records or replays
+// provider traffic according to RuntimeOptions.RecordingMode, and otherwise
+// calls initialize. In replay mode initialize is never called, so no
+// plugins, credentials or network are needed. All providers initialized by
+// the returned Initializer share a single session.
+func WrapInitializer(initialize client.Initializer) client.Initializer {
+	var once sync.Once
+	var session *Session
+	var loadErr error
+	return func(ctx context.Context, res resource.Managed, ropts *client.RuntimeOptions, kube kubeclient.Client) (*client.Provider, error) {
+		switch ropts.RecordingMode {
+		case "":
+			return initialize(ctx, res, ropts, kube)
+		case ModeRecord:
+			if ropts.RecordingFile == "" {
+				return nil, fmt.Errorf("Recording provider traffic requires a recording file")
+			}
+			p, err := initialize(ctx, res, ropts, kube)
+			if err != nil {
+				return p, err
+			}
+			once.Do(func() {
+				session = NewSession(p.Name, p.ProtocolVersion)
+			})
+			p.GRPCProvider = NewRecorder(p.GRPCProvider, session, ropts.RecordingFile)
+			return p, nil
+		case ModeReplay:
+			once.Do(func() {
+				session, loadErr = ReadSession(ropts.RecordingFile)
+			})
+			if loadErr != nil {
+				return nil, loadErr
+			}
+			return &client.Provider{
+				GRPCProvider:    NewReplayer(session, ropts.LenientReplay),
+				Name:            session.Provider,
+				ProtocolVersion: session.ProtocolVersion,
+			}, nil
+		}
+		return nil, fmt.Errorf("Unknown provider recording mode %q, expected %q or %q", ropts.RecordingMode, ModeRecord, ModeReplay)
+	}
+}
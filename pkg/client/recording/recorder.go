@@ -0,0 +1,179 @@
+package recording
+
+import (
+	"sync"
+
+	"github.com/hashicorp/terraform/providers"
+	"github.com/hashicorp/terraform/tfdiags"
+)
+
+// Recorder is a providers.Interface that passes every call through to a
+// provider and records it in a Session.
+type Recorder struct {
+	provider providers.Interface
+	session  *Session
+	path     string
+
+	mu  sync.Mutex
+	err error
+}
+
+// NewRecorder wraps provider, recording its traffic to session. If path is
+// set, every call is appended to the golden file there as it is recorded,
+// so that the recording survives a crash or a shutdown with the provider
+// still borrowed.
+func NewRecorder(provider providers.Interface, session *Session, path string) *Recorder {
+	return &Recorder{provider: provider, session: session, path: path}
+}
+
+// Err returns the first error encountered encoding a call. Calls that could
+// not be encoded are not recorded, but still reach the provider.
+func (r *Recorder) Err() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.err
+}
+
+func (r *Recorder) fail(err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.err == nil {
+		r.err = err
+	}
+}
+
+func (r *Recorder) record(method, typeName string, req Message, reqValues values, resp Message, respValues values, diags tfdiags.Diagnostics) {
+	var err error
+	if req.Values, err = reqValues.encode(); err == nil {
+		if resp.Values, err = respValues.encode(); err == nil {
+			resp.Diagnostics, err = encodeDiagnostics(diags)
+		}
+	}
+	if err != nil {
+		r.fail(err)
+		return
+	}
+	if err := r.session.record(Interaction{Method: method, TypeName: typeName, Request: req.normalized(), Response: resp}, r.path); err != nil {
+		r.fail(err)
+	}
+}
+
+func (r *Recorder) GetSchema() providers.GetSchemaResponse {
+	resp := r.provider.GetSchema()
+	if !r.session.claimSchema() {
+		return resp
+	}
+	schema := &Schema{Provider: resp.Provider, ResourceTypes: resp.ResourceTypes, DataSources: resp.DataSources}
+	r.record("GetSchema", "", Message{}, nil, Message{Schema: schema}, nil, resp.Diagnostics)
+	return resp
+}
+
+func (r *Recorder) PrepareProviderConfig(req providers.PrepareProviderConfigRequest) providers.PrepareProviderConfigResponse {
+	resp := r.provider.PrepareProviderConfig(req)
+	r.record("PrepareProviderConfig", "",
+		Message{}, values{"config": req.Config},
+		Message{}, values{"preparedConfig": resp.PreparedConfig}, resp.Diagnostics)
+	return resp
+}
+
+func (r *Recorder) ValidateResourceTypeConfig(req providers.ValidateResourceTypeConfigRequest) providers.ValidateResourceTypeConfigResponse {
+	resp := r.provider.ValidateResourceTypeConfig(req)
+	r.record("ValidateResourceTypeConfig", req.TypeName,
+		Message{}, values{"config": req.Config},
+		Message{}, nil, resp.Diagnostics)
+	return resp
+}
+
+func (r *Recorder) ValidateDataSourceConfig(req providers.ValidateDataSourceConfigRequest) providers.ValidateDataSourceConfigResponse {
+	resp := r.provider.ValidateDataSourceConfig(req)
+	r.record("ValidateDataSourceConfig", req.TypeName,
+		Message{}, values{"config": req.Config},
+		Message{}, nil, resp.Diagnostics)
+	return resp
+}
+
+func (r *Recorder) UpgradeResourceState(req providers.UpgradeResourceStateRequest) providers.UpgradeResourceStateResponse {
+	resp := r.provider.UpgradeResourceState(req)
+	r.record("UpgradeResourceState", req.TypeName,
+		Message{Version: req.Version, RawStateJSON: req.RawStateJSON, RawStateFlatmap: req.RawStateFlatmap}, nil,
+		Message{}, values{"upgradedState": resp.UpgradedState}, resp.Diagnostics)
+	return resp
+}
+
+func (r *Recorder) Configure(req providers.ConfigureRequest) providers.ConfigureResponse {
+	resp := r.provider.Configure(req)
+	r.record("Configure", "",
+		Message{TerraformVersion: req.TerraformVersion}, values{"config": req.Config},
+		Message{}, nil, resp.Diagnostics)
+	return resp
+}
+
+func (r *Recorder) Stop() error {
+	return r.provider.Stop()
+}
+
+func (r *Recorder) ReadResource(req providers.ReadResourceRequest) providers.ReadResourceResponse {
+	resp := r.provider.ReadResource(req)
+	r.record("ReadResource", req.TypeName,
+		Message{Private: req.Private}, values{"priorState": req.PriorState},
+		Message{Private: resp.Private}, values{"newState": resp.NewState}, resp.Diagnostics)
+	return resp
+}
+
+func (r *Recorder) PlanResourceChange(req providers.PlanResourceChangeRequest) providers.PlanResourceChangeResponse {
+	resp := r.provider.PlanResourceChange(req)
+	requiresReplace := make([]Path, 0, len(resp.RequiresReplace))
+	for _, p := range resp.RequiresReplace {
+		encoded, err := encodePath(p)
+		if err != nil {
+			r.fail(err)
+			return resp
+		}
+		requiresReplace = append(requiresReplace, encoded)
+	}
+	r.record("PlanResourceChange", req.TypeName,
+		Message{Private: req.PriorPrivate}, values{"priorState": req.PriorState, "proposedNewState": req.ProposedNewState, "config": req.Config},
+		Message{Private: resp.PlannedPrivate, RequiresReplace: requiresReplace, LegacyTypeSystem: resp.LegacyTypeSystem}, values{"plannedState": resp.PlannedState},
+		resp.Diagnostics)
+	return resp
+}
+
+func (r *Recorder) ApplyResourceChange(req providers.ApplyResourceChangeRequest) providers.ApplyResourceChangeResponse {
+	resp := r.provider.ApplyResourceChange(req)
+	r.record("ApplyResourceChange", req.TypeName,
+		Message{Private: req.PlannedPrivate}, values{"priorState": req.PriorState, "plannedState": req.PlannedState, "config": req.Config},
+		Message{Private: resp.Private, LegacyTypeSystem: resp.LegacyTypeSystem}, values{"newState": resp.NewState},
+		resp.Diagnostics)
+	return resp
+}
+
+func (r *Recorder) ImportResourceState(req providers.ImportResourceStateRequest) providers.ImportResourceStateResponse {
+	resp := r.provider.ImportResourceState(req)
+	imported := make([]ImportedResource, 0, len(resp.ImportedResources))
+	for _, ir := range resp.ImportedResources {
+		state, err := encodeValue(ir.State)
+		if err != nil {
+			r.fail(err)
+			return resp
+		}
+		imported = append(imported, ImportedResource{TypeName: ir.TypeName, State: state, Private: ir.Private})
+	}
+	r.record("ImportResourceState", req.TypeName,
+		Message{ID: req.ID}, nil,
+		Message{Imported: imported}, nil, resp.Diagnostics)
+	return resp
+}
+
+func (r *Recorder) ReadDataSource(req providers.ReadDataSourceRequest) providers.ReadDataSourceResponse {
+	resp := r.provider.ReadDataSource(req)
+	r.record("ReadDataSource", req.TypeName,
+		Message{}, values{"config": req.Config},
+		Message{}, values{"state": resp.State}, resp.Diagnostics)
+	return resp
+}
+
+// Close closes the provider. Every call has already been saved as it was
+// recorded.
+func (r *Recorder) Close() error {
+	return r.provider.Close()
+}
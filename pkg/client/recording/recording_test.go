@@ -0,0 +1,134 @@
+package recording
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+	"github.com/hashicorp/terraform/configs/configschema"
+	"github.com/hashicorp/terraform/providers"
+	"github.com/zclconf/go-cty/cty"
+	kubeclient "sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/crossplane/terraform-provider-runtime/pkg/client"
+	"github.com/crossplane/terraform-provider-runtime/pkg/client/fake"
+)
+
+const fakeResourceName = "fake_resource"
+
+func TestRecordThenReplay(t *testing.T) {
+	dir, err := ioutil.TempDir("", "provider-recording")
+	if err != nil {
+		t.Fatalf("Unexpected error creating temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+	golden := filepath.Join(dir, "session.json")
+
+	fp := fake.NewProvider(map[string]providers.Schema{
+		fakeResourceName: {Block: &configschema.Block{
+			Attributes: map[string]*configschema.Attribute{
+				"id":   {Type: cty.String, Computed: true},
+				"tags": {Type: cty.Map(cty.String), Optional: true},
+			},
+		}},
+	})
+	planned := cty.ObjectVal(map[string]cty.Value{
+		"id":   cty.UnknownVal(cty.String),
+		"tags": cty.MapVal(map[string]cty.Value{"env": cty.StringVal("ci")}),
+	})
+	create := providers.ApplyResourceChangeRequest{
+		TypeName:       fakeResourceName,
+		PriorState:     cty.NullVal(planned.Type()),
+		PlannedState:   planned,
+		Config:         planned,
+		PlannedPrivate: []byte("private"),
+	}
+
+	rec := NewRecorder(fp, NewSession("fake", 5), golden)
+	schema := rec.GetSchema()
+	created := rec.ApplyResourceChange(create)
+	read := providers.ReadResourceRequest{TypeName: fakeResourceName, PriorState: created.NewState}
+	rec.ReadResource(read)
+	fp.ScriptError("ReadResource", "throttled")
+	rec.ReadResource(read)
+	if err := rec.Close(); err != nil || rec.Err() != nil {
+		t.Fatalf("Unexpected error saving recording: close=%v record=%v", err, rec.Err())
+	}
+
+	ropts := client.NewRuntimeOptions().WithRecording(ModeReplay, golden)
+	initialize := WrapInitializer(func(context.Context, resource.Managed, *client.RuntimeOptions, kubeclient.Client) (*client.Provider, error) {
+		t.Fatalf("Expected replay mode to never call the wrapped Initializer")
+		return nil, nil
+	})
+	p, err := initialize(context.Background(), nil, ropts, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error initializing replaying provider: %s", err)
+	}
+	rp := p.GRPCProvider
+
+	replayedSchema := rp.GetSchema().ResourceTypes[fakeResourceName]
+	if !replayedSchema.Block.ImpliedType().Equals(schema.ResourceTypes[fakeResourceName].Block.ImpliedType()) {
+		t.Errorf("Expected the replayed schema to match the recorded one, got %s", replayedSchema.Block.ImpliedType().GoString())
+	}
+	replayedCreate := rp.ApplyResourceChange(create)
+	if !replayedCreate.NewState.RawEquals(created.NewState) || string(replayedCreate.Private) != "private" {
+		t.Errorf("Expected the recorded ApplyResourceChange response, got %s", replayedCreate.NewState.GoString())
+	}
+	if resp := rp.ReadResource(read); resp.Diagnostics.HasErrors() || !resp.NewState.RawEquals(created.NewState) {
+		t.Errorf("Expected the recorded ReadResource response, got %s %v", resp.NewState.GoString(), resp.Diagnostics.Err())
+	}
+	if resp := rp.ReadResource(read); !resp.Diagnostics.HasErrors() || resp.Diagnostics[0].Description().Summary != "throttled" {
+		t.Errorf("Expected the recorded error diagnostic to be replayed, got %v", resp.Diagnostics)
+	}
+	if resp := rp.ImportResourceState(providers.ImportResourceStateRequest{TypeName: fakeResourceName, ID: "x"}); !resp.Diagnostics.HasErrors() {
+		t.Errorf("Expected an error for a call that was never recorded")
+	}
+}
+
+func TestRecordingSurvivesUnclosedProviders(t *testing.T) {
+	dir, err := ioutil.TempDir("", "provider-recording")
+	if err != nil {
+		t.Fatalf("Unexpected error creating temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+	golden := filepath.Join(dir, "session.json")
+
+	fp := fake.NewProvider(map[string]providers.Schema{})
+	// like a provider still borrowed when the controller stops
+	rec := NewRecorder(fp, NewSession("fake", 5), golden)
+	rec.GetSchema()
+	rec.GetSchema()
+	rec.Configure(providers.ConfigureRequest{TerraformVersion: "0.12.26", Config: cty.EmptyObjectVal})
+	if rec.Err() != nil {
+		t.Fatalf("Unexpected error recording: %s", rec.Err())
+	}
+
+	session, err := ReadSession(golden)
+	if err != nil {
+		t.Fatalf("Expected the session to be saved before the Recorder is closed: %s", err)
+	}
+	if len(session.Interactions) != 2 || session.Interactions[0].Method != "GetSchema" || session.Interactions[1].Method != "Configure" {
+		t.Errorf("Expected one GetSchema and the Configure call to be saved, got %+v", session.Interactions)
+	}
+}
+
+func TestReplayIsStrictUnlessLenient(t *testing.T) {
+	fp := fake.NewProvider(map[string]providers.Schema{})
+	session := NewSession("fake", 5)
+	rec := NewRecorder(fp, session, "")
+	rec.Configure(providers.ConfigureRequest{TerraformVersion: "0.12.26", Config: cty.EmptyObjectVal})
+	if rec.Err() != nil {
+		t.Fatalf("Unexpected error recording: %s", rec.Err())
+	}
+
+	other := providers.ConfigureRequest{TerraformVersion: "0.13.0", Config: cty.EmptyObjectVal}
+	if resp := NewReplayer(session, false).Configure(other); !resp.Diagnostics.HasErrors() {
+		t.Errorf("Expected an error replaying a request that was not recorded")
+	}
+	if resp := NewReplayer(session, true).Configure(other); resp.Diagnostics.HasErrors() {
+		t.Errorf("Unexpected error from lenient replay: %s", resp.Diagnostics.Err())
+	}
+}
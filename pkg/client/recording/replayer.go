@@ -0,0 +1,181 @@
+package recording
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/terraform/providers"
+	"github.com/hashicorp/terraform/tfdiags"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// Replayer is a providers.Interface that answers calls from a recorded
+// Session instead of a provider plugin. Calls that have no recorded
+// interaction return an error diagnostic.
+type Replayer struct {
+	session *Session
+	lenient bool
+}
+
+// NewReplayer returns a Replayer serving responses from session. Replayers
+// sharing a session also share which interactions have been replayed. A
+// lenient Replayer answers requests that differ from the recorded ones with
+// the next recorded interaction for the same method and type, instead of an
+// error.
+func NewReplayer(session *Session, lenient bool) *Replayer {
+	return &Replayer{session: session, lenient: lenient}
+}
+
+// replay looks up the recorded response for a request.
+func (r *Replayer) replay(method, typeName string, req Message, reqValues values) *response {
+	var err error
+	if req.Values, err = reqValues.encode(); err != nil {
+		return &response{err: err}
+	}
+	in, err := r.session.take(method, typeName, req.normalized(), r.lenient)
+	if err != nil {
+		return &response{err: err}
+	}
+	return &response{Message: in.Response}
+}
+
+// response decodes a recorded response, collecting decoding errors into its
+// diagnostics.
+type response struct {
+	Message
+	err error
+}
+
+func (r *response) value(name string) cty.Value {
+	if r.err != nil {
+		return cty.NilVal
+	}
+	v, err := decodeValue(r.Values[name])
+	if err != nil {
+		r.err = fmt.Errorf("Failed to decode recorded %s: %s", name, err)
+	}
+	return v
+}
+
+// diagnostics returns the recorded diagnostics, along with any error
+// replaying or decoding the response. Call it after decoding all values.
+func (r *response) diagnostics() tfdiags.Diagnostics {
+	diags, err := decodeDiagnostics(r.Diagnostics)
+	if err != nil {
+		diags = diags.Append(err)
+	}
+	if r.err != nil {
+		diags = diags.Append(r.err)
+	}
+	return diags
+}
+
+func (r *Replayer) GetSchema() providers.GetSchemaResponse {
+	out := r.replay("GetSchema", "", Message{}, nil)
+	resp := providers.GetSchemaResponse{}
+	if out.Schema != nil {
+		resp.Provider = out.Schema.Provider
+		resp.ResourceTypes = out.Schema.ResourceTypes
+		resp.DataSources = out.Schema.DataSources
+	}
+	resp.Diagnostics = out.diagnostics()
+	return resp
+}
+
+func (r *Replayer) PrepareProviderConfig(req providers.PrepareProviderConfigRequest) providers.PrepareProviderConfigResponse {
+	out := r.replay("PrepareProviderConfig", "", Message{}, values{"config": req.Config})
+	resp := providers.PrepareProviderConfigResponse{PreparedConfig: out.value("preparedConfig")}
+	resp.Diagnostics = out.diagnostics()
+	return resp
+}
+
+func (r *Replayer) ValidateResourceTypeConfig(req providers.ValidateResourceTypeConfigRequest) providers.ValidateResourceTypeConfigResponse {
+	out := r.replay("ValidateResourceTypeConfig", req.TypeName, Message{}, values{"config": req.Config})
+	return providers.ValidateResourceTypeConfigResponse{Diagnostics: out.diagnostics()}
+}
+
+func (r *Replayer) ValidateDataSourceConfig(req providers.ValidateDataSourceConfigRequest) providers.ValidateDataSourceConfigResponse {
+	out := r.replay("ValidateDataSourceConfig", req.TypeName, Message{}, values{"config": req.Config})
+	return providers.ValidateDataSourceConfigResponse{Diagnostics: out.diagnostics()}
+}
+
+func (r *Replayer) UpgradeResourceState(req providers.UpgradeResourceStateRequest) providers.UpgradeResourceStateResponse {
+	out := r.replay("UpgradeResourceState", req.TypeName,
+		Message{Version: req.Version, RawStateJSON: req.RawStateJSON, RawStateFlatmap: req.RawStateFlatmap}, nil)
+	resp := providers.UpgradeResourceStateResponse{UpgradedState: out.value("upgradedState")}
+	resp.Diagnostics = out.diagnostics()
+	return resp
+}
+
+func (r *Replayer) Configure(req providers.ConfigureRequest) providers.ConfigureResponse {
+	out := r.replay("Configure", "", Message{TerraformVersion: req.TerraformVersion}, values{"config": req.Config})
+	return providers.ConfigureResponse{Diagnostics: out.diagnostics()}
+}
+
+func (r *Replayer) Stop() error {
+	return nil
+}
+
+func (r *Replayer) ReadResource(req providers.ReadResourceRequest) providers.ReadResourceResponse {
+	out := r.replay("ReadResource", req.TypeName, Message{Private: req.Private}, values{"priorState": req.PriorState})
+	resp := providers.ReadResourceResponse{NewState: out.value("newState"), Private: out.Private}
+	resp.Diagnostics = out.diagnostics()
+	return resp
+}
+
+func (r *Replayer) PlanResourceChange(req providers.PlanResourceChangeRequest) providers.PlanResourceChangeResponse {
+	out := r.replay("PlanResourceChange", req.TypeName,
+		Message{Private: req.PriorPrivate}, values{"priorState": req.PriorState, "proposedNewState": req.ProposedNewState, "config": req.Config})
+	resp := providers.PlanResourceChangeResponse{
+		PlannedState:     out.value("plannedState"),
+		PlannedPrivate:   out.Private,
+		LegacyTypeSystem: out.LegacyTypeSystem,
+	}
+	for _, p := range out.RequiresReplace {
+		decoded, err := decodePath(p)
+		if err != nil {
+			out.err = err
+			break
+		}
+		resp.RequiresReplace = append(resp.RequiresReplace, decoded)
+	}
+	resp.Diagnostics = out.diagnostics()
+	return resp
+}
+
+func (r *Replayer) ApplyResourceChange(req providers.ApplyResourceChangeRequest) providers.ApplyResourceChangeResponse {
+	out := r.replay("ApplyResourceChange", req.TypeName,
+		Message{Private: req.PlannedPrivate}, values{"priorState": req.PriorState, "plannedState": req.PlannedState, "config": req.Config})
+	resp := providers.ApplyResourceChangeResponse{
+		NewState:         out.value("newState"),
+		Private:          out.Private,
+		LegacyTypeSystem: out.LegacyTypeSystem,
+	}
+	resp.Diagnostics = out.diagnostics()
+	return resp
+}
+
+func (r *Replayer) ImportResourceState(req providers.ImportResourceStateRequest) providers.ImportResourceStateResponse {
+	out := r.replay("ImportResourceState", req.TypeName, Message{ID: req.ID}, nil)
+	resp := providers.ImportResourceStateResponse{}
+	for _, ir := range out.Imported {
+		state, err := decodeValue(ir.State)
+		if err != nil {
+			out.err = err
+			break
+		}
+		resp.ImportedResources = append(resp.ImportedResources, providers.ImportedResource{TypeName: ir.TypeName, State: state, Private: ir.Private})
+	}
+	resp.Diagnostics = out.diagnostics()
+	return resp
+}
+
+func (r *Replayer) ReadDataSource(req providers.ReadDataSourceRequest) providers.ReadDataSourceResponse {
+	out := r.replay("ReadDataSource", req.TypeName, Message{}, values{"config": req.Config})
+	resp := providers.ReadDataSourceResponse{State: out.value("state")}
+	resp.Diagnostics = out.diagnostics()
+	return resp
+}
+
+func (r *Replayer) Close() error {
+	return nil
+}
@@ -0,0 +1,388 @@
+// Package recording records the traffic between the runtime and a provider
+// plugin to a golden file, and replays it later without the plugin, so that
+// controllers can be exercised offline.
+package recording
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+
+	"github.com/hashicorp/terraform/providers"
+	"github.com/hashicorp/terraform/tfdiags"
+	"github.com/zclconf/go-cty/cty"
+	ctymsgpack "github.com/zclconf/go-cty/cty/msgpack"
+)
+
+// Session is the contents of a golden file: every request made to a
+// provider, and its response, in the order they were made.
+type Session struct {
+	Provider        string        `json:"provider"`
+	ProtocolVersion int           `json:"protocolVersion"`
+	Interactions    []Interaction `json:"-"`
+
+	mu   sync.Mutex
+	used []bool
+	// schemaRecorded is set once a GetSchema call has been recorded
+	schemaRecorded bool
+	// out is the golden file recorded interactions are appended to
+	out *os.File
+}
+
+// Interaction is a single provider call.
+type Interaction struct {
+	Method   string  `json:"method"`
+	TypeName string  `json:"typeName,omitempty"`
+	Request  Message `json:"request"`
+	Response Message `json:"response"`
+}
+
+// Message is a request or response. cty values are msgpack encoded with
+// their type, so the golden file can be decoded without the schema.
+type Message struct {
+	Values           map[string][]byte  `json:"values,omitempty"`
+	Private          []byte             `json:"private,omitempty"`
+	Diagnostics      []Diagnostic       `json:"diagnostics,omitempty"`
+	Schema           *Schema            `json:"schema,omitempty"`
+	TerraformVersion string             `json:"terraformVersion,omitempty"`
+	ID               string             `json:"id,omitempty"`
+	Version          int64              `json:"version,omitempty"`
+	RawStateJSON     []byte             `json:"rawStateJSON,omitempty"`
+	RawStateFlatmap  map[string]string  `json:"rawStateFlatmap,omitempty"`
+	RequiresReplace  []Path             `json:"requiresReplace,omitempty"`
+	LegacyTypeSystem bool               `json:"legacyTypeSystem,omitempty"`
+	Imported         []ImportedResource `json:"imported,omitempty"`
+}
+
+// Schema is a recorded GetSchema response.
+type Schema struct {
+	Provider      providers.Schema            `json:"provider"`
+	ResourceTypes map[string]providers.Schema `json:"resourceTypes,omitempty"`
+	DataSources   map[string]providers.Schema `json:"dataSources,omitempty"`
+}
+
+// ImportedResource is a recorded providers.ImportedResource.
+type ImportedResource struct {
+	TypeName string `json:"typeName"`
+	State    []byte `json:"state"`
+	Private  []byte `json:"private,omitempty"`
+}
+
+// Diagnostic is a recorded tfdiags.Diagnostic. Only the severity, the
+// description and the attribute path survive recording.
+type Diagnostic struct {
+	Severity  string `json:"severity"`
+	Summary   string `json:"summary"`
+	Detail    string `json:"detail,omitempty"`
+	Attribute Path   `json:"attribute,omitempty"`
+}
+
+// Path is a recorded cty.Path.
+type Path []PathStep
+
+// PathStep is either an attribute name or a msgpack encoded index key.
+type PathStep struct {
+	Attr string `json:"attr,omitempty"`
+	Key  []byte `json:"key,omitempty"`
+}
+
+// NewSession returns an empty Session for the named provider.
+func NewSession(providerName string, protocolVersion int) *Session {
+	return &Session{Provider: providerName, ProtocolVersion: protocolVersion, Interactions: make([]Interaction, 0)}
+}
+
+// ReadSession reads a golden file written by Session.Save or a Recorder.
+// The file holds the provider on its first line, then one interaction per
+// line. A last line cut short by a crash while recording is ignored.
+func ReadSession(path string) (*Session, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read recorded provider session %s: %s", path, err)
+	}
+	defer f.Close()
+	r := bufio.NewReader(f)
+	s := &Session{Interactions: make([]Interaction, 0)}
+	for n := 1; ; n++ {
+		line, err := r.ReadBytes('\n')
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("Failed to read recorded provider session %s: %s", path, err)
+		}
+		if n == 1 {
+			err = json.Unmarshal(line, s)
+		} else {
+			i := Interaction{}
+			err = json.Unmarshal(line, &i)
+			s.Interactions = append(s.Interactions, i)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("Failed to parse line %d of recorded provider session %s: %s", n, path, err)
+		}
+	}
+	if s.Provider == "" {
+		return nil, fmt.Errorf("Recorded provider session %s does not name a provider", path)
+	}
+	return s, nil
+}
+
+// Save writes the Session to a golden file. The file is replaced
+// atomically, so a crash while saving leaves the previous recording.
+func (s *Session) Save(path string) error {
+	tmp, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".")
+	if err != nil {
+		return fmt.Errorf("Failed to save recorded provider session: %s", err)
+	}
+	defer os.Remove(tmp.Name())
+	s.mu.Lock()
+	err = s.writeTo(tmp)
+	s.mu.Unlock()
+	if err != nil {
+		tmp.Close()
+		return fmt.Errorf("Failed to save recorded provider session: %s", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("Failed to save recorded provider session: %s", err)
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return fmt.Errorf("Failed to save recorded provider session: %s", err)
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// writeTo writes the provider line and every interaction recorded so far.
+// Callers hold s.mu.
+func (s *Session) writeTo(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	if err := enc.Encode(s); err != nil {
+		return err
+	}
+	for _, i := range s.Interactions {
+		if err := enc.Encode(i); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// record adds an interaction to the session. If path is set, the
+// interaction is also appended to the golden file there, which is created
+// by the first recorded call, so that the recording survives a crash or a
+// shutdown with providers still borrowed without rewriting it on every call.
+func (s *Session) record(i Interaction, path string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Interactions = append(s.Interactions, i)
+	if path == "" {
+		return nil
+	}
+	if s.out == nil {
+		f, err := os.Create(path)
+		if err != nil {
+			return fmt.Errorf("Failed to save recorded provider session: %s", err)
+		}
+		s.out = f
+		if err := s.writeTo(f); err != nil {
+			return fmt.Errorf("Failed to save recorded provider session: %s", err)
+		}
+		return nil
+	}
+	if err := json.NewEncoder(s.out).Encode(i); err != nil {
+		return fmt.Errorf("Failed to save recorded provider session: %s", err)
+	}
+	return nil
+}
+
+// claimSchema reports whether a GetSchema call is still to be recorded.
+// Schemas do not change during a session, so only the first is recorded.
+func (s *Session) claimSchema() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.schemaRecorded {
+		return false
+	}
+	s.schemaRecorded = true
+	return true
+}
+
+// take finds the recorded interaction to replay for a request. Unused
+// interactions with an identical request are preferred. Once those run out,
+// the last identical request is served again, eg for a resource that is
+// reconciled more often than during recording. A request that was never
+// recorded is an error, unless lenient, in which case the next unused
+// interaction for the same method and type is served before any reuse.
+func (s *Session) take(method, typeName string, req Message, lenient bool) (*Interaction, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.used) != len(s.Interactions) {
+		s.used = make([]bool, len(s.Interactions))
+	}
+	next, reuse := -1, -1
+	for i := range s.Interactions {
+		in := &s.Interactions[i]
+		if in.Method != method || in.TypeName != typeName {
+			continue
+		}
+		identical := reflect.DeepEqual(in.Request, req)
+		if !s.used[i] && identical {
+			s.used[i] = true
+			return in, nil
+		}
+		if !s.used[i] && next < 0 {
+			next = i
+		}
+		if identical {
+			reuse = i
+		}
+	}
+	if lenient && next >= 0 {
+		s.used[next] = true
+		return &s.Interactions[next], nil
+	}
+	if reuse >= 0 {
+		return &s.Interactions[reuse], nil
+	}
+	if next >= 0 {
+		return nil, fmt.Errorf("Recorded %s interactions for %s in provider session do not match the request", method, typeName)
+	}
+	return nil, fmt.Errorf("No recorded %s interaction for %s in provider session", method, typeName)
+}
+
+// values are the cty values of a Message, by name.
+type values map[string]cty.Value
+
+func (vs values) encode() (map[string][]byte, error) {
+	if len(vs) == 0 {
+		return nil, nil
+	}
+	encoded := make(map[string][]byte, len(vs))
+	for name, v := range vs {
+		b, err := encodeValue(v)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to encode %s: %s", name, err)
+		}
+		encoded[name] = b
+	}
+	return encoded, nil
+}
+
+// encodeValue msgpack encodes a value along with its type. The zero
+// cty.Value, which providers use for values that were not set, is encoded as nil.
+func encodeValue(v cty.Value) ([]byte, error) {
+	if v.Type() == cty.NilType {
+		return nil, nil
+	}
+	return ctymsgpack.Marshal(v, cty.DynamicPseudoType)
+}
+
+func decodeValue(b []byte) (cty.Value, error) {
+	if b == nil {
+		return cty.NilVal, nil
+	}
+	return ctymsgpack.Unmarshal(b, cty.DynamicPseudoType)
+}
+
+func encodePath(p cty.Path) (Path, error) {
+	if len(p) == 0 {
+		return nil, nil
+	}
+	encoded := make(Path, 0, len(p))
+	for _, step := range p {
+		switch s := step.(type) {
+		case cty.GetAttrStep:
+			encoded = append(encoded, PathStep{Attr: s.Name})
+		case cty.IndexStep:
+			key, err := encodeValue(s.Key)
+			if err != nil {
+				return nil, err
+			}
+			encoded = append(encoded, PathStep{Key: key})
+		}
+	}
+	return encoded, nil
+}
+
+func decodePath(p Path) (cty.Path, error) {
+	if len(p) == 0 {
+		return nil, nil
+	}
+	decoded := make(cty.Path, 0, len(p))
+	for _, step := range p {
+		if step.Key == nil {
+			decoded = append(decoded, cty.GetAttrStep{Name: step.Attr})
+			continue
+		}
+		key, err := decodeValue(step.Key)
+		if err != nil {
+			return nil, err
+		}
+		decoded = append(decoded, cty.IndexStep{Key: key})
+	}
+	return decoded, nil
+}
+
+func encodeDiagnostics(diags tfdiags.Diagnostics) ([]Diagnostic, error) {
+	if len(diags) == 0 {
+		return nil, nil
+	}
+	encoded := make([]Diagnostic, 0, len(diags))
+	for _, d := range diags {
+		attr, err := encodePath(tfdiags.GetAttribute(d))
+		if err != nil {
+			return nil, err
+		}
+		desc := d.Description()
+		encoded = append(encoded, Diagnostic{
+			Severity:  string(d.Severity()),
+			Summary:   desc.Summary,
+			Detail:    desc.Detail,
+			Attribute: attr,
+		})
+	}
+	return encoded, nil
+}
+
+func decodeDiagnostics(recorded []Diagnostic) (tfdiags.Diagnostics, error) {
+	var diags tfdiags.Diagnostics
+	for _, d := range recorded {
+		severity := tfdiags.Error
+		if d.Severity == string(tfdiags.Warning) {
+			severity = tfdiags.Warning
+		}
+		attr, err := decodePath(d.Attribute)
+		if err != nil {
+			return nil, err
+		}
+		if attr != nil {
+			diags = diags.Append(tfdiags.AttributeValue(severity, d.Summary, d.Detail, attr))
+			continue
+		}
+		diags = diags.Append(tfdiags.Sourceless(severity, d.Summary, d.Detail))
+	}
+	return diags, nil
+}
+
+// normalized clears empty fields the way a JSON round trip through the
+// golden file would, so live requests compare equal to recorded ones.
+func (m Message) normalized() Message {
+	if len(m.Values) == 0 {
+		m.Values = nil
+	}
+	if len(m.Private) == 0 {
+		m.Private = nil
+	}
+	if len(m.RawStateJSON) == 0 {
+		m.RawStateJSON = nil
+	}
+	if len(m.RawStateFlatmap) == 0 {
+		m.RawStateFlatmap = nil
+	}
+	return m
+}
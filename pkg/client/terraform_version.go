@@ -0,0 +1,45 @@
+package client
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/terraform/plugin/discovery"
+)
+
+// FakeTerraformVersion was the terraform version reported to every
+// provider. It keeps its original value; the version now reported to
+// protocol 5 providers is "0.12.26", without the v prefix.
+//
+// Deprecated: use DefaultTerraformVersion, or TerraformVersionCompatibility.
+const FakeTerraformVersion = "v0.12.26"
+
+const protocol5TerraformVersion = "0.12.26"
+
+// TerraformVersionCompatibility maps plugin protocol versions to the terraform
+// version reported to providers speaking that protocol, unless one is
+// configured with RuntimeOptions.WithTerraformVersion. Each is a terraform
+// release that only spoke protocols up to that version, so providers enable
+// the features they would under a real terraform of the same vintage.
+var TerraformVersionCompatibility = map[int]string{
+	5: protocol5TerraformVersion,
+	6: "1.0.0",
+}
+
+// DefaultTerraformVersion returns the terraform version reported to providers
+// speaking the given plugin protocol version. Unknown protocol versions get
+// the protocol 5 version.
+func DefaultTerraformVersion(protocolVersion int) string {
+	if v, ok := TerraformVersionCompatibility[protocolVersion]; ok {
+		return v
+	}
+	return TerraformVersionCompatibility[5]
+}
+
+// validateTerraformVersion checks that a configured terraform version parses
+// the way providers will parse it.
+func validateTerraformVersion(providerName, version string) error {
+	if _, err := discovery.VersionStr(version).Parse(); err != nil {
+		return fmt.Errorf("Invalid terraform version %q configured for provider %s: %s", version, providerName, err)
+	}
+	return nil
+}
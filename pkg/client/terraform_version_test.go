@@ -0,0 +1,59 @@
+package client
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform/configs/configschema"
+	"github.com/hashicorp/terraform/providers"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// configureRecorder records the terraform version it is configured with.
+type configureRecorder struct {
+	providers.Interface
+	terraformVersion string
+}
+
+func (c *configureRecorder) GetSchema() providers.GetSchemaResponse {
+	return providers.GetSchemaResponse{Provider: providers.Schema{Block: &configschema.Block{}}}
+}
+
+func (c *configureRecorder) Configure(req providers.ConfigureRequest) providers.ConfigureResponse {
+	c.terraformVersion = req.TerraformVersion
+	return providers.ConfigureResponse{}
+}
+
+func TestConfigureReportsTerraformVersion(t *testing.T) {
+	cases := map[string]struct {
+		provider *Provider
+		expected string
+	}{
+		"Protocol5Default": {provider: &Provider{ProtocolVersion: 5}, expected: "0.12.26"},
+		"Protocol6Default": {provider: &Provider{ProtocolVersion: 6}, expected: "1.0.0"},
+		"UnknownProtocol":  {provider: &Provider{}, expected: "0.12.26"},
+		"Configured":       {provider: &Provider{ProtocolVersion: 5, TerraformVersion: "0.13.5"}, expected: "0.13.5"},
+	}
+	for name, tc := range cases {
+		rec := &configureRecorder{}
+		tc.provider.GRPCProvider = rec
+		if err := tc.provider.Configure(map[string]cty.Value{}); err != nil {
+			t.Fatalf("%s: unexpected error from Configure: %s", name, err)
+		}
+		if rec.terraformVersion != tc.expected {
+			t.Errorf("%s: expected terraform version %s, got %s", name, tc.expected, rec.terraformVersion)
+		}
+	}
+	// the plugin directory is empty, so spawning would fail differently
+	dir, err := ioutil.TempDir("", "terraform-version")
+	if err != nil {
+		t.Fatalf("Unexpected error creating temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+	ropts := NewRuntimeOptions().WithPluginDirectory(dir).WithTerraformVersion("google", "not-a-version")
+	if _, err := NewProvider("google", ropts, map[string]cty.Value{}); err == nil || !strings.Contains(err.Error(), "Invalid terraform version") {
+		t.Errorf("Expected an unparseable terraform version to be rejected before spawning the plugin, err=%v", err)
+	}
+}
@@ -0,0 +1,7 @@
+// Package tfplugin6 holds the bindings of the terraform plugin protocol
+// version 6, which the terraform module this runtime builds against does not
+// ship. tfplugin6.proto and the generated files are copied unchanged from
+// github.com/hashicorp/terraform-plugin-go v0.3.1, tfprotov6/internal/tfplugin6.
+// To update them, copy them again from a newer release, or regenerate them
+// from the proto with protoc-gen-go and protoc-gen-go-grpc.
+package tfplugin6
@@ -3,53 +3,287 @@ package controller
 import (
 	"context"
 	"fmt"
+	"sync"
+	"time"
 
+	"github.com/crossplane/crossplane-runtime/pkg/event"
 	"github.com/crossplane/crossplane-runtime/pkg/logging"
 	"github.com/crossplane/crossplane-runtime/pkg/reconciler/managed"
 	"github.com/crossplane/crossplane-runtime/pkg/resource"
 	"github.com/crossplane/terraform-provider-runtime/pkg/client"
 	"github.com/crossplane/terraform-provider-runtime/pkg/plugin"
+	"github.com/crossplane/terraform-provider-runtime/pkg/tracing"
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	k8schema "k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
 	kubeclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 )
 
 const (
-	errProviderPoolBorrowFailed        = "Failed to Borrow a provider from the ProviderPool"
-	errFromContextDuringExternalClient = "Error from context while waiting for ExternalClient to complete operations"
+	errProviderPoolBorrowFailed = "Failed to Borrow a provider from the ProviderPool"
 )
 
+// throttleJitter spreads the requeues of resources throttled together, so
+// that they do not all come back when the rate limit refills.
+const throttleJitter = 0.1
+
+// connectionKey identifies the managed resource an External was connected
+// for. The controller-runtime workqueue never reconciles the same object
+// concurrently, so there is at most one connected External per key.
+type connectionKey struct {
+	gvk  k8schema.GroupVersionKind
+	name types.NamespacedName
+}
+
 // TODO: make New func and take Logger private (maybe?)
 type Connector struct {
 	KubeClient  kubeclient.Client
 	PluginIndex *plugin.Index
 	Logger      logging.Logger
 	Pool        *client.ProviderPool
+	// Recorder, when set, is passed an event for each resource the
+	// provider creates, updates or deletes and each diagnostic it reports.
+	Recorder event.Recorder
+
+	mu sync.Mutex
+	// connected holds the Lease of the External connected for each
+	// resource until it is released, and nothing else, so that a released
+	// External can be collected
+	connected map[connectionKey]*client.Lease
+	// reconciling tracks the reconciles in progress in a
+	// DisconnectingReconciler
+	reconciling map[connectionKey]*reconcileState
+	// scheme holds the types of the PluginIndex, to get the resources a
+	// DisconnectingReconciler throttles
+	scheme *runtime.Scheme
+}
+
+// reconcileState is what Connect passes on to the DisconnectingReconciler
+// running it.
+type reconcileState struct {
+	// ctx is the context of the Reconcile span, which the spans of Connect
+	// and the External are put under
+	ctx context.Context
+	// throttled is set if the resource was throttled before the Reconciler
+	// ran, so Connect must not take another token
+	throttled bool
 }
 
 func (c *Connector) Connect(ctx context.Context, mg resource.Managed) (managed.ExternalClient, error) {
 	gvk := mg.GetObjectKind().GroupVersionKind()
-	fmt.Printf("Connect: %s", gvk.String())
+	c.Logger.Debug(fmt.Sprintf("Connect: %s", gvk.String()))
+	key := connectionKey{gvk: gvk, name: types.NamespacedName{Namespace: mg.GetNamespace(), Name: mg.GetName()}}
+	var parent context.Context
+	st := c.reconcileState(key)
+	if st != nil {
+		parent = st.ctx
+	}
+	ctx, span := tracing.Start(tracing.WithParent(ctx, parent), "Connector.Connect", tracing.ResourceAttributes(mg)...)
+	ext, err := c.connect(ctx, mg, key, parent, st == nil || !st.throttled)
+	tracing.End(span, err)
+	return ext, err
+}
 
-	provider, err := c.Pool.Borrow(ctx, mg, c.KubeClient)
+func (c *Connector) connect(ctx context.Context, mg resource.Managed, key connectionKey, parent context.Context, throttle bool) (managed.ExternalClient, error) {
+	gvk := key.gvk
+
+	// look up the invoker before borrowing so a bad GVK never holds a provider
+	invoker, err := c.PluginIndex.InvokerForGVK(gvk)
 	if err != nil {
 		return &External{}, err
 	}
-	// The context passed in from the Reconciler is marked Done at the end of the Reconcile loop.
-	// We bank on that fact to schedule the provider lock for cleanup once its work is done.
+	// throttled resources fail to connect before they take up a slot,
+	// unless a DisconnectingReconciler has throttled them already
+	if throttle {
+		if err := c.Pool.Throttle(mg, invoker.TerraformResourceName()); err != nil {
+			return &External{}, err
+		}
+	}
+	lease, err := c.Pool.Lease(ctx, mg, c.KubeClient)
+	if err != nil {
+		return &External{}, errors.Wrap(err, errProviderPoolBorrowFailed)
+	}
+
+	ext := &External{KubeClient: c.KubeClient, Invoker: invoker, logger: c.Logger, recorder: c.Recorder, provider: lease.Provider, traceParent: parent}
+	lease.Provider.DiagnosticsObserver = ext.observeDiagnostics
+	// overrides delegate to a copy of the External that has none, and that
+	// leaves recording to ext
+	def := *ext
+	def.recorder = nil
+	ext.Callbacks = invoker.ExternalClientFns().Bind(plugin.ExternalCall{Provider: lease.Provider, Invoker: invoker, Default: &def})
+	c.track(ctx, key, lease)
+	return ext, nil
+}
+
+// startReconcile tracks a reconcile of the named resource under the
+// Reconcile span in ctx, until the returned function is called.
+func (c *Connector) startReconcile(key connectionKey, ctx context.Context, throttled bool) func() {
+	st := &reconcileState{ctx: ctx, throttled: throttled}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.reconciling == nil {
+		c.reconciling = make(map[connectionKey]*reconcileState)
+	}
+	c.reconciling[key] = st
+	return func() {
+		c.mu.Lock()
+		delete(c.reconciling, key)
+		c.mu.Unlock()
+	}
+}
+
+func (c *Connector) reconcileState(key connectionKey) *reconcileState {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.reconciling[key]
+}
+
+// throttle takes a token from the rate limits of the named resource,
+// returning how long until they have one again if there is none. It returns
+// an error if the resource cannot be read, leaving it to Connect.
+func (c *Connector) throttle(ctx context.Context, key connectionKey) (time.Duration, error) {
+	invoker, err := c.PluginIndex.InvokerForGVK(key.gvk)
+	if err != nil {
+		return 0, err
+	}
+	mg, err := c.newManaged(key.gvk)
+	if err != nil {
+		return 0, err
+	}
+	if err := c.KubeClient.Get(ctx, key.name, mg); err != nil {
+		return 0, err
+	}
+	err = c.Pool.Throttle(mg, invoker.TerraformResourceName())
+	if te, ok := client.IsThrottled(err); ok {
+		return te.Delay, nil
+	}
+	return 0, err
+}
+
+// newManaged returns an empty managed resource of the given kind, from a
+// scheme of the PluginIndex's types built on first use.
+func (c *Connector) newManaged(gvk k8schema.GroupVersionKind) (resource.Managed, error) {
+	c.mu.Lock()
+	if c.scheme == nil {
+		s := runtime.NewScheme()
+		for _, sb := range c.PluginIndex.SchemeBuilders() {
+			if err := sb.AddToScheme(s); err != nil {
+				c.mu.Unlock()
+				return nil, err
+			}
+		}
+		c.scheme = s
+	}
+	s := c.scheme
+	c.mu.Unlock()
+	obj, err := s.New(gvk)
+	if err != nil {
+		return nil, err
+	}
+	mg, ok := obj.(resource.Managed)
+	if !ok {
+		return nil, fmt.Errorf("%s is not a managed resource", gvk)
+	}
+	return mg, nil
+}
+
+// Disconnect releases the provider held by the External that was connected
+// for the named resource, if there is one.
+func (c *Connector) Disconnect(ctx context.Context, gvk k8schema.GroupVersionKind, name types.NamespacedName) error {
+	key := connectionKey{gvk: gvk, name: name}
+	c.mu.Lock()
+	lease, ok := c.connected[key]
+	delete(c.connected, key)
+	c.mu.Unlock()
+	if ok {
+		lease.Release()
+	}
+	return nil
+}
+
+// track holds the lease of the External connected for key until it is
+// released, which happens at the latest when ctx is done. The
+// managed.Reconciler cancels the context it connects with when its
+// Reconcile returns, so ReconcilerConfigurers that do not register a
+// DisconnectingReconciler do not leak providers.
+func (c *Connector) track(ctx context.Context, key connectionKey, lease *client.Lease) {
+	c.mu.Lock()
+	if c.connected == nil {
+		c.connected = make(map[connectionKey]*client.Lease)
+	}
+	prev, ok := c.connected[key]
+	c.connected[key] = lease
+	c.mu.Unlock()
+	// a previous reconcile of the same object finished without disconnecting
+	if ok {
+		c.Logger.Debug("Releasing provider held by a previous External that was never disconnected", "name", key.name.String())
+		prev.Release()
+	}
 	go func() {
 		select {
 		case <-ctx.Done():
-			if err := ctx.Err(); err != nil {
-				if err.Error() != "context canceled" {
-					c.Logger.WithValues("err", err).Debug(errFromContextDuringExternalClient)
-				}
-			}
+			lease.Release()
+		case <-lease.Released():
 		}
-		c.Pool.Return(provider)
+		c.untrack(key, lease)
 	}()
-	invoker, err := c.PluginIndex.InvokerForGVK(gvk)
+}
+
+// untrack forgets a released lease, unless key has been connected again.
+func (c *Connector) untrack(key connectionKey, lease *client.Lease) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.connected[key] == lease {
+		delete(c.connected, key)
+	}
+}
+
+// DisconnectingReconciler wraps the managed.Reconciler for a type, releasing
+// the provider borrowed by the Connector once each Reconcile returns.
+// ReconcilerConfigurers should register this instead of the bare
+// managed.Reconciler so that providers go back to the pool deterministically.
+type DisconnectingReconciler struct {
+	reconcile.Reconciler
+	GVK       k8schema.GroupVersionKind
+	Connector *Connector
+}
+
+func (r *DisconnectingReconciler) Reconcile(req reconcile.Request) (reconcile.Result, error) {
+	// the managed.Reconciler makes its own context, so the Connector puts
+	// its spans under this one by name
+	ctx, span := tracing.Start(context.Background(), "Reconcile",
+		tracing.AttributeGVK.String(r.GVK.String()), tracing.AttributeName.String(req.NamespacedName.String()))
+	key := connectionKey{gvk: r.GVK, name: req.NamespacedName}
+	// throttled resources are requeued once the rate limits have a token
+	// again, before the Reconciler fails to connect and reports an error
+	delay, err := r.Connector.throttle(ctx, key)
 	if err != nil {
-		return &External{}, err
+		r.Connector.Logger.Debug("Cannot throttle resource before reconciling it", "name", req.NamespacedName.String(), "err", err)
+	}
+	if delay > 0 {
+		tracing.End(span, nil)
+		return reconcile.Result{RequeueAfter: wait.Jitter(delay, throttleJitter)}, nil
 	}
+	done := r.Connector.startReconcile(key, ctx, err == nil)
+	defer func() {
+		if err := r.Connector.Disconnect(context.Background(), r.GVK, req.NamespacedName); err != nil {
+			r.Connector.Logger.Debug("Failed to disconnect External", "name", req.NamespacedName.String(), "err", err)
+		}
+		done()
+	}()
+	result, err := r.Reconciler.Reconcile(req)
+	tracing.End(span, err)
+	return result, err
+}
 
-	return &External{KubeClient: c.KubeClient, Invoker: invoker, logger: c.Logger, provider: provider}, nil
+// NewDisconnectingReconciler wraps r so that providers borrowed by c during
+// each Reconcile are released when it returns. ReconcilerConfigurers should
+// always register one: without it, providers are held until the context of
+// the managed.Reconciler is cancelled, and throttled resources fail to
+// connect with an error.
+func NewDisconnectingReconciler(r reconcile.Reconciler, gvk k8schema.GroupVersionKind, c *Connector) *DisconnectingReconciler {
+	return &DisconnectingReconciler{Reconciler: r, GVK: gvk, Connector: c}
 }
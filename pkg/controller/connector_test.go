@@ -0,0 +1,256 @@
+package controller
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/crossplane/crossplane-runtime/pkg/logging"
+	"github.com/crossplane/crossplane-runtime/pkg/reconciler/managed"
+	xpresource "github.com/crossplane/crossplane-runtime/pkg/resource"
+	xpfake "github.com/crossplane/crossplane-runtime/pkg/resource/fake"
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+	"github.com/zclconf/go-cty/cty"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	k8schema "k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	kubeclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/scheme"
+
+	"github.com/crossplane/terraform-provider-runtime/pkg/client"
+	"github.com/crossplane/terraform-provider-runtime/pkg/client/fake"
+	"github.com/crossplane/terraform-provider-runtime/pkg/plugin"
+	"github.com/crossplane/terraform-provider-runtime/pkg/plugin/plugintest"
+)
+
+// kindedManaged is a fake managed resource that knows its GVK, which
+// Connect looks the Implementation up by.
+type kindedManaged struct {
+	xpfake.Managed
+	metav1.TypeMeta
+}
+
+func (m *kindedManaged) GetObjectKind() k8schema.ObjectKind {
+	return &m.TypeMeta
+}
+
+func TestConnectWiresExternalClientFns(t *testing.T) {
+	var observedWith *client.Provider
+	connector, res := connectorFixture(t, client.NewRuntimeOptions(), &plugin.Implementation{ExternalClientFns: plugin.ExternalClientFns{
+		// delegates to the default, then adjusts its result
+		ObserveFn: func(ctx context.Context, mg xpresource.Managed, call plugin.ExternalCall) (managed.ExternalObservation, error) {
+			observedWith = call.Provider
+			obs, err := call.Default.Observe(ctx, mg)
+			obs.ResourceUpToDate = true
+			return obs, err
+		},
+		DeleteFn: func(ctx context.Context, mg xpresource.Managed, call plugin.ExternalCall) error {
+			return nil
+		},
+	}})
+	ext, err := connector.Connect(context.Background(), res)
+	if err != nil {
+		t.Fatalf("Unexpected error from Connect: %s", err)
+	}
+	defer connector.Disconnect(context.Background(), res.GroupVersionKind(), types.NamespacedName{Name: res.GetName()})
+	provider := ext.(*External).provider
+	fp := provider.GRPCProvider.(*fake.Provider)
+
+	if _, err := ext.Create(context.Background(), res); err != nil {
+		t.Fatalf("Unexpected error from the default Create: %s", err)
+	}
+	id := res.GetAnnotations()["id"]
+	fp.Put(fakeResourceName, id, cty.ObjectVal(map[string]cty.Value{"id": cty.StringVal(id), "size": cty.StringVal("large")}))
+	obs, err := ext.Observe(context.Background(), res)
+	if err != nil || !obs.ResourceExists || !obs.ResourceUpToDate {
+		t.Errorf("Expected the Observe override to adjust the default observation, obs=%+v err=%v", obs, err)
+	}
+	if observedWith != provider {
+		t.Errorf("Expected the override to be passed the borrowed provider")
+	}
+	if err := ext.Delete(context.Background(), res); err != nil {
+		t.Fatalf("Unexpected error from Delete: %s", err)
+	}
+	if _, ok := fp.Get(fakeResourceName, id); !ok {
+		t.Errorf("Expected the Delete override to replace the default deletion")
+	}
+}
+
+// connectorFixture returns a Connector for the fake resource type, with
+// layers Overlaid on top of the plugintest Implementation, backed by a pool
+// of fake providers, and a resource to reconcile with it, which the
+// Connector's kube client gets.
+func connectorFixture(t *testing.T, ropts *client.RuntimeOptions, layers ...*plugin.Implementation) (*Connector, *kindedManaged) {
+	gvk := plugintest.GVK
+	sb := &scheme.Builder{GroupVersion: gvk.GroupVersion()}
+	sb.SchemeBuilder.Register(func(s *runtime.Scheme) error {
+		s.AddKnownTypeWithName(gvk, &kindedManaged{})
+		return nil
+	})
+	idxr := plugin.NewIndexer()
+	idxr.Overlay(plugintest.Implementation())
+	idxr.Overlay(&plugin.Implementation{GVK: gvk, SchemeBuilder: sb})
+	for _, l := range layers {
+		l.GVK = gvk
+		if err := idxr.Overlay(l); err != nil {
+			t.Fatalf("Unexpected error from Overlay: %s", err)
+		}
+	}
+	idx, err := idxr.BuildIndex()
+	if err != nil {
+		t.Fatalf("Unexpected error from BuildIndex: %s", err)
+	}
+	fp := fakeProviderFixture()
+	pool := client.NewProviderPool(func(context.Context, xpresource.Managed, *client.RuntimeOptions, kubeclient.Client) (*client.Provider, error) {
+		return fake.NewClientProvider("fake", fp), nil
+	}, ropts, logging.NewNopLogger())
+	res := &kindedManaged{Managed: *resourceFixture(map[string]string{"size": "small"})}
+	res.SetGroupVersionKind(gvk)
+	kube := &test.MockClient{
+		MockGet: test.NewMockGetFn(nil, func(obj runtime.Object) error {
+			*obj.(*kindedManaged) = *res
+			return nil
+		}),
+		MockUpdate: test.NewMockUpdateFn(nil),
+	}
+	connector := &Connector{KubeClient: kube, PluginIndex: idx, Logger: logging.NewNopLogger(), Pool: pool}
+	return connector, res
+}
+
+func TestConnectorDisconnect(t *testing.T) {
+	connector, res := connectorFixture(t, client.NewRuntimeOptions())
+	if _, err := connector.Connect(context.Background(), res); err != nil {
+		t.Fatalf("Unexpected error from Connect: %s", err)
+	}
+	if n := connector.Pool.Borrowed(); n != 1 {
+		t.Fatalf("Expected Connect to borrow a provider, borrowed %d", n)
+	}
+	name := types.NamespacedName{Name: res.GetName()}
+	for i := 0; i < 2; i++ {
+		if err := connector.Disconnect(context.Background(), res.GroupVersionKind(), name); err != nil {
+			t.Errorf("Unexpected error from Disconnect: %s", err)
+		}
+	}
+	if n := connector.Pool.Borrowed(); n != 0 {
+		t.Errorf("Expected Disconnect to return the provider, borrowed %d", n)
+	}
+}
+
+func TestConnectReleasesWhenContextDone(t *testing.T) {
+	connector, res := connectorFixture(t, client.NewRuntimeOptions())
+	// like the managed.Reconciler, which cancels its context on return
+	ctx, cancel := context.WithCancel(context.Background())
+	if _, err := connector.Connect(ctx, res); err != nil {
+		t.Fatalf("Unexpected error from Connect: %s", err)
+	}
+	cancel()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for connector.Pool.Borrowed() > 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if n := connector.Pool.Borrowed(); n != 0 {
+		t.Errorf("Expected the provider to be returned once the context was done, borrowed %d", n)
+	}
+	for time.Now().Before(deadline) {
+		connector.mu.Lock()
+		n := len(connector.connected)
+		connector.mu.Unlock()
+		if n == 0 {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Errorf("Expected the released lease to be forgotten by the Connector")
+}
+
+func TestThrottledReconcileIsRequeued(t *testing.T) {
+	ropts := client.NewRuntimeOptions().WithResourceRateLimit(fakeResourceName, client.RateLimit{QPS: 0.1, Burst: 1})
+	connector, res := connectorFixture(t, ropts)
+	reconciles := 0
+	var connectErr error
+	r := NewDisconnectingReconciler(reconcile.Func(func(reconcile.Request) (reconcile.Result, error) {
+		reconciles++
+		_, connectErr = connector.Connect(context.Background(), res)
+		return reconcile.Result{}, nil
+	}), res.GroupVersionKind(), connector)
+	req := reconcile.Request{NamespacedName: types.NamespacedName{Name: res.GetName()}}
+
+	if _, err := r.Reconcile(req); err != nil || connectErr != nil {
+		t.Fatalf("Expected the first reconcile to connect without being throttled twice, got %v, %v", err, connectErr)
+	}
+	result, err := r.Reconcile(req)
+	if reconciles != 1 {
+		t.Errorf("Expected a throttled resource not to be passed to the Reconciler, reconciled %d times", reconciles)
+	}
+	// 10s until the bucket refills, plus up to 10% jitter
+	if err != nil || result.RequeueAfter <= time.Second || result.RequeueAfter > 11*time.Second {
+		t.Errorf("Expected a throttled reconcile to be requeued once the bucket refills, got %+v, %v", result, err)
+	}
+	if size := connector.Pool.Size(); size != 1 {
+		t.Errorf("Expected the throttled reconcile not to borrow a provider, pool size %d", size)
+	}
+}
+
+func TestConnectThrottlesWithoutDisconnectingReconciler(t *testing.T) {
+	ropts := client.NewRuntimeOptions().WithResourceRateLimit(fakeResourceName, client.RateLimit{QPS: 0.1, Burst: 1})
+	connector, res := connectorFixture(t, ropts)
+	if _, err := connector.Connect(context.Background(), res); err != nil {
+		t.Fatalf("Unexpected error from Connect: %s", err)
+	}
+	if _, err := connector.Connect(context.Background(), res); err == nil {
+		t.Errorf("Expected Connect to be throttled when no DisconnectingReconciler throttled the resource")
+	}
+}
+
+func TestReconcileSpans(t *testing.T) {
+	sr := tracetest.NewSpanRecorder()
+	prev := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr)))
+	defer otel.SetTracerProvider(prev)
+
+	connector, res := connectorFixture(t, client.NewRuntimeOptions())
+	gvk := res.GroupVersionKind()
+	// stands in for the managed.Reconciler, which makes its own context
+	r := NewDisconnectingReconciler(reconcile.Func(func(reconcile.Request) (reconcile.Result, error) {
+		ext, err := connector.Connect(context.Background(), res)
+		if err != nil {
+			return reconcile.Result{}, err
+		}
+		_, err = ext.Create(context.Background(), res)
+		return reconcile.Result{}, err
+	}), gvk, connector)
+	if _, err := r.Reconcile(reconcile.Request{NamespacedName: types.NamespacedName{Name: res.GetName()}}); err != nil {
+		t.Fatalf("Unexpected error from Reconcile: %s", err)
+	}
+
+	// names of the spans by id, and of their parents
+	names := map[string]string{}
+	parents := map[string][]string{}
+	for _, s := range sr.Ended() {
+		names[s.SpanContext().SpanID().String()] = s.Name()
+	}
+	for _, s := range sr.Ended() {
+		parents[s.Name()] = append(parents[s.Name()], names[s.Parent().SpanID().String()])
+	}
+	for child, parent := range map[string]string{
+		"Connector.Connect":   "Reconcile",
+		"ProviderPool.Borrow": "Connector.Connect",
+		"External.Create":     "Reconcile",
+		"api.Create":          "External.Create",
+		"api.GetSchema":       "api.Create",
+	} {
+		found := false
+		for _, p := range parents[child] {
+			found = found || p == parent
+		}
+		if !found {
+			t.Errorf("Expected a %s span under %s, got parents %v", child, parent, parents[child])
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package controller
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/crossplane/crossplane-runtime/apis/core/v1alpha1"
+	"github.com/crossplane/crossplane-runtime/pkg/event"
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/crossplane/terraform-provider-runtime/pkg/api"
+)
+
+// TypeDiagnostics is the condition reflecting the diagnostics the provider
+// reported for the last operation on a managed resource.
+const TypeDiagnostics v1alpha1.ConditionType = "TerraformDiagnostics"
+
+// Reasons of the TypeDiagnostics condition.
+const (
+	ReasonNoDiagnostics v1alpha1.ConditionReason = "NoDiagnostics"
+	ReasonWarnings      v1alpha1.ConditionReason = "ProviderWarnings"
+	ReasonErrors        v1alpha1.ConditionReason = "ProviderErrors"
+)
+
+// Reasons of the events an External records.
+const (
+	ReasonCreated    event.Reason = "CreatedTerraformResource"
+	ReasonUpdated    event.Reason = "UpdatedTerraformResource"
+	ReasonDeleted    event.Reason = "DeletedTerraformResource"
+	ReasonDiagnostic event.Reason = "TerraformDiagnostic"
+)
+
+// DiagnosticsCondition returns the TypeDiagnostics condition for diags.
+func DiagnosticsCondition(diags []api.Diagnostic) v1alpha1.Condition {
+	c := v1alpha1.Condition{
+		Type:               TypeDiagnostics,
+		Status:             corev1.ConditionFalse,
+		LastTransitionTime: metav1.Now(),
+		Reason:             ReasonNoDiagnostics,
+	}
+	if len(diags) == 0 {
+		return c
+	}
+	c.Status = corev1.ConditionTrue
+	c.Reason = ReasonWarnings
+	msgs := make([]string, 0, len(diags))
+	for _, d := range diags {
+		if d.Error {
+			c.Reason = ReasonErrors
+		}
+		msgs = append(msgs, formatDiagnostic(d))
+	}
+	c.Message = strings.Join(msgs, "; ")
+	return c
+}
+
+// recordDiagnostics sets the TypeDiagnostics condition of res, and records
+// a Warning event for each diagnostic if they differ from the ones the
+// condition reported, so that persistent warnings are not recorded on
+// every poll.
+func recordDiagnostics(r event.Recorder, res resource.Managed, diags []api.Diagnostic) {
+	c := DiagnosticsCondition(diags)
+	if res.GetCondition(TypeDiagnostics).Message != c.Message {
+		for _, d := range diags {
+			r.Event(res, event.Warning(ReasonDiagnostic, errors.New(formatDiagnostic(d))))
+		}
+	}
+	res.SetConditions(c)
+}
+
+func formatDiagnostic(d api.Diagnostic) string {
+	msg := d.Summary
+	if d.Path != "" {
+		msg = fmt.Sprintf("%s: %s", d.Path, msg)
+	}
+	if d.Detail != "" {
+		msg = fmt.Sprintf("%s: %s", msg, d.Detail)
+	}
+	if d.Error {
+		return "error: " + msg
+	}
+	return "warning: " + msg
+}
@@ -22,12 +22,15 @@ import (
 
 	kubeclient "sigs.k8s.io/controller-runtime/pkg/client"
 
+	"github.com/crossplane/crossplane-runtime/pkg/event"
 	"github.com/crossplane/crossplane-runtime/pkg/logging"
 	"github.com/crossplane/crossplane-runtime/pkg/reconciler/managed"
 	"github.com/crossplane/crossplane-runtime/pkg/resource"
 	"github.com/crossplane/terraform-provider-runtime/pkg/api"
 	"github.com/crossplane/terraform-provider-runtime/pkg/client"
 	"github.com/crossplane/terraform-provider-runtime/pkg/plugin"
+	"github.com/crossplane/terraform-provider-runtime/pkg/tracing"
+	"github.com/hashicorp/terraform/tfdiags"
 )
 
 const (
@@ -44,11 +47,64 @@ type External struct {
 	Invoker    *plugin.Invoker
 	Callbacks  managed.ExternalClientFns
 	logger     logging.Logger
+	recorder   event.Recorder
 	provider   *client.Provider
+	// diags are the diagnostics of the operation in progress
+	diags tfdiags.Diagnostics
+	// traceParent is the context of the Reconcile span, if any
+	traceParent context.Context
 }
 
 func (c *External) Observe(ctx context.Context, res resource.Managed) (managed.ExternalObservation, error) {
 	c.entryLog(res, "Observe")
+	ctx, end := c.startSpan(ctx, "Observe", res)
+	c.diags = nil
+	obs, err := c.observe(ctx, res)
+	end(err)
+	c.recordDiagnostics(res)
+	return obs, err
+}
+
+func (c *External) Create(ctx context.Context, res resource.Managed) (managed.ExternalCreation, error) {
+	c.entryLog(res, "Create")
+	ctx, end := c.startSpan(ctx, "Create", res)
+	c.diags = nil
+	cre, err := c.create(ctx, res)
+	end(err)
+	c.recordDiagnostics(res)
+	if err == nil {
+		c.record(res, event.Normal(ReasonCreated, fmt.Sprintf("Created %s %s", c.Invoker.TerraformResourceName(), res.GetName())))
+	}
+	return cre, err
+}
+
+func (c *External) Update(ctx context.Context, res resource.Managed) (managed.ExternalUpdate, error) {
+	c.entryLog(res, "Update")
+	ctx, end := c.startSpan(ctx, "Update", res)
+	c.diags = nil
+	upd, err := c.update(ctx, res)
+	end(err)
+	c.recordDiagnostics(res)
+	if err == nil {
+		c.record(res, event.Normal(ReasonUpdated, fmt.Sprintf("Updated %s %s", c.Invoker.TerraformResourceName(), res.GetName())))
+	}
+	return upd, err
+}
+
+func (c *External) Delete(ctx context.Context, res resource.Managed) error {
+	c.entryLog(res, "Delete")
+	ctx, end := c.startSpan(ctx, "Delete", res)
+	c.diags = nil
+	err := c.delete(ctx, res)
+	end(err)
+	c.recordDiagnostics(res)
+	if err == nil {
+		c.record(res, event.Normal(ReasonDeleted, fmt.Sprintf("Deleted %s %s", c.Invoker.TerraformResourceName(), res.GetName())))
+	}
+	return err
+}
+
+func (c *External) observe(ctx context.Context, res resource.Managed) (managed.ExternalObservation, error) {
 	gvk := res.GetObjectKind().GroupVersionKind()
 	c.logger.Debug(fmt.Sprintf("terraform.External.Observe: %s", gvk.String()))
 	if c.Callbacks.ObserveFn != nil {
@@ -63,7 +119,7 @@ func (c *External) Observe(ctx context.Context, res resource.Managed) (managed.E
 		return managed.ExternalObservation{}, err
 	}
 
-	description, err := c.Invoker.MergeResources(res, ares)
+	description, err := c.merge(res, ares)
 	if err != nil {
 		return managed.ExternalObservation{}, err
 	}
@@ -80,8 +136,7 @@ func (c *External) Observe(ctx context.Context, res resource.Managed) (managed.E
 	}, nil
 }
 
-func (c *External) Create(ctx context.Context, res resource.Managed) (managed.ExternalCreation, error) {
-	c.entryLog(res, "Create")
+func (c *External) create(ctx context.Context, res resource.Managed) (managed.ExternalCreation, error) {
 	if c.Callbacks.CreateFn != nil {
 		return c.Callbacks.Create(ctx, res)
 	}
@@ -91,7 +146,7 @@ func (c *External) Create(ctx context.Context, res resource.Managed) (managed.Ex
 		return managed.ExternalCreation{}, err
 	}
 
-	description, err := c.Invoker.MergeResources(res, created)
+	description, err := c.merge(res, created)
 	if err != nil {
 		return managed.ExternalCreation{}, err
 	}
@@ -103,8 +158,7 @@ func (c *External) Create(ctx context.Context, res resource.Managed) (managed.Ex
 	return managed.ExternalCreation{}, nil
 }
 
-func (c *External) Update(ctx context.Context, res resource.Managed) (managed.ExternalUpdate, error) {
-	c.entryLog(res, "Update")
+func (c *External) update(ctx context.Context, res resource.Managed) (managed.ExternalUpdate, error) {
 	if c.Callbacks.UpdateFn != nil {
 		return c.Callbacks.Update(ctx, res)
 	}
@@ -113,7 +167,7 @@ func (c *External) Update(ctx context.Context, res resource.Managed) (managed.Ex
 	if err != nil {
 		return managed.ExternalUpdate{}, err
 	}
-	description, err := c.Invoker.MergeResources(res, updated)
+	description, err := c.merge(res, updated)
 	if err != nil {
 		return managed.ExternalUpdate{}, err
 	}
@@ -126,8 +180,7 @@ func (c *External) Update(ctx context.Context, res resource.Managed) (managed.Ex
 	return managed.ExternalUpdate{}, nil
 }
 
-func (c *External) Delete(ctx context.Context, res resource.Managed) error {
-	c.entryLog(res, "Delete")
+func (c *External) delete(ctx context.Context, res resource.Managed) error {
 	if c.Callbacks.DeleteFn != nil {
 		return c.Callbacks.Delete(ctx, res)
 	}
@@ -135,6 +188,54 @@ func (c *External) Delete(ctx context.Context, res resource.Managed) error {
 	return api.Delete(c.provider, c.Invoker, res)
 }
 
+// merge merges observed into res with the schema of its resource type, so
+// that schema-driven ResourceMergers can late-initialize it.
+func (c *External) merge(res, observed resource.Managed) (plugin.MergeDescription, error) {
+	s, err := api.SchemaForInvoker(c.provider, c.Invoker)
+	if err != nil {
+		return plugin.MergeDescription{}, err
+	}
+	description, err := c.Invoker.MergeResources(res, observed, s)
+	if err != nil {
+		return description, err
+	}
+	if len(description.LateInitializedPaths) > 0 {
+		c.logger.Debug("Late-initialized resource", "name", res.GetName(), "paths", description.LateInitializedPaths)
+	}
+	return description, nil
+}
+
+// startSpan starts the span of an operation under the Reconcile span, and
+// traces the provider's calls under it until the returned function ends it.
+func (c *External) startSpan(ctx context.Context, op string, res resource.Managed) (context.Context, func(error)) {
+	attrs := append(tracing.ResourceAttributes(res), tracing.AttributeTerraformType.String(c.Invoker.TerraformResourceName()))
+	ctx, span := tracing.Start(tracing.WithParent(ctx, c.traceParent), "External."+op, attrs...)
+	restore := c.provider.SetTraceContext(ctx)
+	return ctx, func(err error) {
+		restore()
+		tracing.End(span, err)
+	}
+}
+
+// observeDiagnostics collects the diagnostics of the provider calls made
+// by the operation in progress.
+func (c *External) observeDiagnostics(diags tfdiags.Diagnostics) {
+	c.diags = c.diags.Append(diags)
+}
+
+func (c *External) recordDiagnostics(res resource.Managed) {
+	if c.recorder == nil {
+		return
+	}
+	recordDiagnostics(c.recorder, res, api.Diagnostics(c.diags))
+}
+
+func (c *External) record(res resource.Managed, e event.Event) {
+	if c.recorder != nil {
+		c.recorder.Event(res, e)
+	}
+}
+
 func (c *External) entryLog(res resource.Managed, method string) {
 	gvk := res.GetObjectKind().GroupVersionKind()
 	c.logger.Debug(fmt.Sprintf("terraform.External.%s: %s", method, gvk.String()))
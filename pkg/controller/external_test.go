@@ -0,0 +1,195 @@
+package controller
+
+import (
+	"context"
+	"testing"
+
+	"github.com/crossplane/crossplane-runtime/pkg/event"
+	"github.com/crossplane/crossplane-runtime/pkg/logging"
+	xpfake "github.com/crossplane/crossplane-runtime/pkg/resource/fake"
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+	"github.com/hashicorp/terraform/configs/configschema"
+	"github.com/hashicorp/terraform/providers"
+	"github.com/hashicorp/terraform/tfdiags"
+	"github.com/zclconf/go-cty/cty"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	kubeclient "sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/crossplane/terraform-provider-runtime/pkg/client/fake"
+	"github.com/crossplane/terraform-provider-runtime/pkg/plugin"
+	"github.com/crossplane/terraform-provider-runtime/pkg/plugin/plugintest"
+)
+
+const fakeResourceName = plugintest.ResourceName
+
+func externalFixture(t *testing.T, fp *fake.Provider, kube kubeclient.Client) *External {
+	gvk := plugintest.GVK
+	idxr := plugin.NewIndexer()
+	if err := idxr.Overlay(plugintest.Implementation()); err != nil {
+		t.Fatalf("Unexpected error from Overlay: %s", err)
+	}
+	idx, err := idxr.BuildIndex()
+	if err != nil {
+		t.Fatalf("Unexpected error from BuildIndex: %s", err)
+	}
+	inv, err := idx.InvokerForGVK(gvk)
+	if err != nil {
+		t.Fatalf("Unexpected error from InvokerForGVK: %s", err)
+	}
+	return &External{
+		KubeClient: kube,
+		Invoker:    inv,
+		logger:     logging.NewNopLogger(),
+		provider:   fake.NewClientProvider("fake", fp),
+	}
+}
+
+func fakeProviderFixture() *fake.Provider {
+	return fake.NewProvider(map[string]providers.Schema{
+		fakeResourceName: {Block: &configschema.Block{
+			Attributes: map[string]*configschema.Attribute{
+				"id":   {Type: cty.String, Computed: true},
+				"size": {Type: cty.String, Optional: true, Computed: true},
+			},
+		}},
+	})
+}
+
+func resourceFixture(annotations map[string]string) *xpfake.Managed {
+	res := &xpfake.Managed{}
+	res.SetName("test")
+	res.SetAnnotations(annotations)
+	return res
+}
+
+func TestExternalObserve(t *testing.T) {
+	fp := fakeProviderFixture()
+	fp.Put(fakeResourceName, "fake-1", cty.ObjectVal(map[string]cty.Value{
+		"id":   cty.StringVal("fake-1"),
+		"size": cty.StringVal("large"),
+	}))
+	kubeUpdates := 0
+	kube := &test.MockClient{MockUpdate: func(context.Context, runtime.Object, ...kubeclient.UpdateOption) error {
+		kubeUpdates++
+		return nil
+	}}
+	ext := externalFixture(t, fp, kube)
+	ctx := context.Background()
+
+	obs, err := ext.Observe(ctx, resourceFixture(map[string]string{"id": "missing"}))
+	if err != nil || obs.ResourceExists {
+		t.Errorf("Expected a resource the provider does not know to not exist, obs=%+v err=%v", obs, err)
+	}
+
+	res := resourceFixture(map[string]string{"id": "fake-1"})
+	obs, err = ext.Observe(ctx, res)
+	if err != nil {
+		t.Fatalf("Unexpected error from Observe: %s", err)
+	}
+	if !obs.ResourceExists || !obs.ResourceUpToDate {
+		t.Errorf("Expected a late-initialized resource to exist and be up to date, obs=%+v", obs)
+	}
+	if res.GetAnnotations()["size"] != "large" || kubeUpdates != 1 {
+		t.Errorf("Expected Observe to late-initialize size and update the object, size=%q updates=%d", res.GetAnnotations()["size"], kubeUpdates)
+	}
+
+	obs, err = ext.Observe(ctx, resourceFixture(map[string]string{"id": "fake-1", "size": "small"}))
+	if err != nil || obs.ResourceUpToDate {
+		t.Errorf("Expected a resource that differs from the provider to need an update, obs=%+v err=%v", obs, err)
+	}
+	if kubeUpdates != 1 {
+		t.Errorf("Expected no object update when nothing was merged, updates=%d", kubeUpdates)
+	}
+}
+
+func TestExternalCreateMergesID(t *testing.T) {
+	fp := fakeProviderFixture()
+	kube := &test.MockClient{MockUpdate: test.NewMockUpdateFn(nil)}
+	ext := externalFixture(t, fp, kube)
+
+	res := resourceFixture(map[string]string{"size": "small"})
+	if _, err := ext.Create(context.Background(), res); err != nil {
+		t.Fatalf("Unexpected error from Create: %s", err)
+	}
+	id := res.GetAnnotations()["id"]
+	if _, ok := fp.Get(fakeResourceName, id); !ok {
+		t.Errorf("Expected Create to merge the provider assigned id %q into the object", id)
+	}
+
+	fp.ScriptError("ApplyResourceChange", "quota exceeded")
+	if _, err := ext.Create(context.Background(), resourceFixture(nil)); err == nil {
+		t.Errorf("Expected Create to return the provider's error diagnostic")
+	}
+}
+
+type eventRecorder struct {
+	events []event.Event
+}
+
+func (r *eventRecorder) Event(_ runtime.Object, e event.Event) {
+	r.events = append(r.events, e)
+}
+
+func (r *eventRecorder) WithAnnotations(...string) event.Recorder { return r }
+
+func TestExternalRecordsDiagnostics(t *testing.T) {
+	fp := fakeProviderFixture()
+	ext := externalFixture(t, fp, &test.MockClient{MockUpdate: test.NewMockUpdateFn(nil)})
+	rec := &eventRecorder{}
+	ext.recorder = rec
+	ext.provider.DiagnosticsObserver = ext.observeDiagnostics
+
+	var diags tfdiags.Diagnostics
+	fp.Script("ApplyResourceChange", diags.Append(tfdiags.AttributeValue(tfdiags.Warning, "Deprecated", "use disk_size", cty.GetAttrPath("size"))))
+	res := resourceFixture(map[string]string{"size": "small"})
+	if _, err := ext.Create(context.Background(), res); err != nil {
+		t.Fatalf("Unexpected error from Create: %s", err)
+	}
+	if len(rec.events) != 2 || rec.events[0].Type != event.TypeWarning || rec.events[1].Reason != ReasonCreated {
+		t.Fatalf("Expected a warning and a created event, got %+v", rec.events)
+	}
+	if want := "warning: size: Deprecated: use disk_size"; rec.events[0].Message != want {
+		t.Errorf("warning event message = %q, want %q", rec.events[0].Message, want)
+	}
+	if c := res.GetCondition(TypeDiagnostics); c.Status != corev1.ConditionTrue || c.Reason != ReasonWarnings {
+		t.Errorf("Expected a warnings condition, got %+v", c)
+	}
+
+	rec.events = nil
+	if _, err := ext.Observe(context.Background(), res); err != nil {
+		t.Fatalf("Unexpected error from Observe: %s", err)
+	}
+	if c := res.GetCondition(TypeDiagnostics); c.Status != corev1.ConditionFalse || len(rec.events) != 0 {
+		t.Errorf("Expected a clean Observe to clear the condition, got %+v and events %+v", c, rec.events)
+	}
+}
+
+func TestPersistentDiagnosticsAreRecordedOnce(t *testing.T) {
+	fp := fakeProviderFixture()
+	ext := externalFixture(t, fp, &test.MockClient{MockUpdate: test.NewMockUpdateFn(nil)})
+	rec := &eventRecorder{}
+	ext.recorder = rec
+	ext.provider.DiagnosticsObserver = ext.observeDiagnostics
+
+	res := resourceFixture(map[string]string{"size": "small"})
+	if _, err := ext.Create(context.Background(), res); err != nil {
+		t.Fatalf("Unexpected error from Create: %s", err)
+	}
+	deprecated := tfdiags.Diagnostics{}.Append(tfdiags.AttributeValue(tfdiags.Warning, "Deprecated", "use disk_size", cty.GetAttrPath("size")))
+	for i := 0; i < 3; i++ {
+		fp.Script("ReadResource", deprecated)
+	}
+	rec.events = nil
+	for i := 0; i < 3; i++ {
+		if _, err := ext.Observe(context.Background(), res); err != nil {
+			t.Fatalf("Unexpected error from Observe: %s", err)
+		}
+	}
+	if len(rec.events) != 1 {
+		t.Errorf("Expected a persistent warning to be recorded once, got %+v", rec.events)
+	}
+	if c := res.GetCondition(TypeDiagnostics); c.Reason != ReasonWarnings {
+		t.Errorf("Expected the warnings condition to be kept, got %+v", c)
+	}
+}
@@ -23,11 +23,16 @@ import (
 
 	crossplaneapis "github.com/crossplane/crossplane/apis"
 	"github.com/crossplane/terraform-provider-runtime/pkg/client"
+	"github.com/crossplane/terraform-provider-runtime/pkg/client/recording"
 	"github.com/crossplane/terraform-provider-runtime/pkg/plugin"
 )
 
+// IndexDebugPath is where the metrics server serves the Descriptions of
+// the Implementations in the Index.
+const IndexDebugPath = "/debug/index"
+
 //func StartTerraformManager(r *registry.Registry, opts ctrl.Options, ropts *client.RuntimeOptions, log logging.Logger) error {
-func StartTerraformManager(idx *plugin.Index, p *plugin.ProviderInit, opts ctrl.Options, ropts *client.RuntimeOptions, log logging.Logger) error {
+func StartTerraformManager(idx *plugin.Index, p *plugin.ProviderInit, opts ctrl.Options, ropts *client.RuntimeOptions, settings plugin.ReconcilerSettings, log logging.Logger) error {
 	cfg, err := ctrl.GetConfig()
 	if err != nil {
 		return errors.Wrap(err, "Cannot get API server rest config")
@@ -45,10 +50,35 @@ func StartTerraformManager(idx *plugin.Index, p *plugin.ProviderInit, opts ctrl.
 			return err
 		}
 	}
-	p.SchemeBuilder.AddToScheme(mgr.GetScheme())
-	pool := client.NewProviderPool(p.Initializer, ropts)
-	for _, rc := range idx.ReconcilerConfigurers() {
-		if err := rc.ConfigureReconciler(mgr, log, idx, pool); err != nil {
+	if p.SchemeBuilder != nil {
+		if err := p.SchemeBuilder.AddToScheme(mgr.GetScheme()); err != nil {
+			return err
+		}
+	}
+	// plugins have to be in place before the pool can spawn anything
+	if err := client.InstallMirroredPlugins(ropts, p.ProviderName); err != nil {
+		return errors.Wrap(err, "Cannot install provider plugins from mirror")
+	}
+	// standby replicas must not spawn plugins, only the elected leader
+	if opts.LeaderElection {
+		ropts.WithLeaderElection()
+	}
+	// in replay mode providers are served from a recording instead of plugins
+	pool := client.NewProviderPool(recording.WrapInitializer(p.Initializer), ropts, log)
+	// the manager drives the pool's idle reaper and shuts the pool down on exit
+	if err := mgr.Add(pool); err != nil {
+		return errors.Wrap(err, "Cannot add provider pool to controller manager")
+	}
+	// served next to the metrics, to see which layers make up each resource
+	if err := mgr.AddMetricsExtraHandler(IndexDebugPath, plugin.DebugHandler(idx)); err != nil {
+		return errors.Wrap(err, "Cannot add index debug endpoint")
+	}
+	for _, gvk := range idx.GVKs() {
+		inv, err := idx.InvokerForGVK(gvk)
+		if err != nil {
+			return err
+		}
+		if err := inv.ConfigureReconciler(mgr, log, idx, pool, settings.For(inv.TerraformResourceName())); err != nil {
 			return err
 		}
 	}
@@ -0,0 +1,59 @@
+package controller
+
+import (
+	"context"
+
+	"github.com/crossplane/crossplane-runtime/pkg/logging"
+	"github.com/pkg/errors"
+	"gopkg.in/alecthomas/kingpin.v2"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+
+	"github.com/crossplane/terraform-provider-runtime/pkg/options"
+	"github.com/crossplane/terraform-provider-runtime/pkg/plugin"
+	"github.com/crossplane/terraform-provider-runtime/pkg/tracing"
+)
+
+// Run parses the runtime flags from args, logs the effective options and
+// starts the terraform manager, so that provider binaries only supply their
+// Index and ProviderInit.
+func Run(name string, args []string, idx *plugin.Index, p *plugin.ProviderInit) error {
+	if err := p.Validate(); err != nil {
+		return err
+	}
+	if idx == nil || len(idx.GVKs()) == 0 {
+		return errors.New("The Index has no Implementations, there is nothing to reconcile")
+	}
+	app := kingpin.New(name, "Crossplane provider backed by the "+p.ProviderName+" terraform provider.")
+	flags := options.RegisterFlags(app)
+	if _, err := app.Parse(args); err != nil {
+		return err
+	}
+	opts, err := flags.Options()
+	if err != nil {
+		return err
+	}
+
+	zl := zap.New(zap.UseDevMode(opts.Logging.Debug))
+	ctrl.SetLogger(zl)
+	log := logging.NewLogrLogger(zl.WithName(name))
+	dump, err := opts.Dump()
+	if err != nil {
+		return errors.Wrap(err, "Cannot dump effective options")
+	}
+	log.Info("Starting with effective options", "options", dump)
+
+	shutdown, err := tracing.Setup(context.Background(), opts.Tracing.Exporter, opts.Tracing.Endpoint, name)
+	if err != nil {
+		return errors.Wrap(err, "Cannot set up tracing")
+	}
+	// flush the spans still batched when the manager stops
+	defer func() {
+		if err := shutdown(context.Background()); err != nil {
+			log.Info("Cannot flush spans", "err", err)
+		}
+	}()
+
+	ropts := opts.RuntimeOptions().WithLogger(log)
+	return StartTerraformManager(idx, p, opts.ManagerOptions(), ropts, opts.ReconcilerSettings(), log)
+}
@@ -0,0 +1,37 @@
+package controller
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	xpresource "github.com/crossplane/crossplane-runtime/pkg/resource"
+	kubeclient "sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/crossplane/terraform-provider-runtime/pkg/client"
+	"github.com/crossplane/terraform-provider-runtime/pkg/plugin"
+)
+
+func TestRunRejectsIncompleteProviders(t *testing.T) {
+	connector, _ := connectorFixture(t, client.NewRuntimeOptions())
+	idx := connector.PluginIndex
+	initializer := func(context.Context, xpresource.Managed, *client.RuntimeOptions, kubeclient.Client) (*client.Provider, error) {
+		return nil, nil
+	}
+
+	err := Run("test", nil, idx, &plugin.ProviderInit{})
+	for _, field := range []string{"ProviderName", "Initializer"} {
+		if err == nil || !strings.Contains(err.Error(), field) {
+			t.Errorf("Expected Run to report the missing %s, got %v", field, err)
+		}
+	}
+
+	empty, err := plugin.NewIndexer().BuildIndex()
+	if err != nil {
+		t.Fatalf("Unexpected error from BuildIndex: %s", err)
+	}
+	err = Run("test", nil, empty, &plugin.ProviderInit{ProviderName: "fake", Initializer: initializer})
+	if err == nil || !strings.Contains(err.Error(), "no Implementations") {
+		t.Errorf("Expected Run to refuse an empty Index, got %v", err)
+	}
+}
@@ -0,0 +1,363 @@
+package generator
+
+import (
+	"fmt"
+)
+
+const (
+	pkgMetav1          = "k8s.io/apimachinery/pkg/apis/meta/v1"
+	pkgRuntime         = "k8s.io/apimachinery/pkg/runtime"
+	pkgSchema          = "k8s.io/apimachinery/pkg/runtime/schema"
+	pkgScheme          = "sigs.k8s.io/controller-runtime/pkg/scheme"
+	pkgCorev1          = "k8s.io/api/core/v1"
+	pkgRuntimev1alpha1 = "github.com/crossplane/crossplane-runtime/apis/core/v1alpha1"
+	pkgResource        = "github.com/crossplane/crossplane-runtime/pkg/resource"
+	pkgMeta            = "github.com/crossplane/crossplane-runtime/pkg/meta"
+	pkgManaged         = "github.com/crossplane/crossplane-runtime/pkg/reconciler/managed"
+	pkgEvent           = "github.com/crossplane/crossplane-runtime/pkg/event"
+	pkgLogging         = "github.com/crossplane/crossplane-runtime/pkg/logging"
+	pkgCty             = "github.com/zclconf/go-cty/cty"
+	pkgProviders       = "github.com/hashicorp/terraform/providers"
+	pkgYAML            = "sigs.k8s.io/yaml"
+	pkgCtrl            = "sigs.k8s.io/controller-runtime"
+	pkgPlugin          = "github.com/crossplane/terraform-provider-runtime/pkg/plugin"
+	pkgCtyconv         = "github.com/crossplane/terraform-provider-runtime/pkg/plugin/ctyconv"
+	pkgClient          = "github.com/crossplane/terraform-provider-runtime/pkg/client"
+	pkgController      = "github.com/crossplane/terraform-provider-runtime/pkg/controller"
+)
+
+// emitDoc writes the package doc, with the markers and options
+// controller-gen needs to generate CRDs from the types.
+func emitDoc(pkg *goPackage, m *resourceModel, cfg Config) *goFile {
+	f := pkg.newFile()
+	f.doc = fmt.Sprintf("// Package %s contains the %s managed resource, for the terraform\n// %s resource. Terraform numbers are float64 fields, so controller-gen\n// needs crd:allowDangerousTypes=true.\n// +kubebuilder:object:generate=true\n// +groupName=%s\n", m.Package, m.Kind, m.TFName, cfg.Group)
+	return f
+}
+
+// emitTypes writes the API types of a resource, and registers them with
+// the package's SchemeBuilder.
+func emitTypes(pkg *goPackage, m *resourceModel, cfg Config) *goFile {
+	f := pkg.newFile()
+	f.use(pkgMetav1, "metav1")
+	f.use(pkgSchema, "")
+	f.use(pkgScheme, "")
+	f.use(pkgRuntimev1alpha1, "runtimev1alpha1")
+	k := m.Kind
+
+	f.p("// Package type metadata.")
+	f.p("const (")
+	f.p("Group = %q", cfg.Group)
+	f.p("Version = %q", cfg.Version)
+	f.p("Kind = %q", k)
+	f.p("// TerraformResourceName is the terraform resource type of %s.", k)
+	f.p("TerraformResourceName = %q", m.TFName)
+	f.p(")")
+	f.p("")
+	f.p("var (")
+	f.p("// GroupVersion is the API group and version of %s.", k)
+	f.p("GroupVersion = schema.GroupVersion{Group: Group, Version: Version}")
+	f.p("// GroupVersionKind identifies %s.", k)
+	f.p("GroupVersionKind = GroupVersion.WithKind(Kind)")
+	f.p("// GroupKind is the GroupKind of %s as a string.", k)
+	f.p("GroupKind = schema.GroupKind{Group: Group, Kind: Kind}.String()")
+	f.p("// SchemeBuilder registers %s and %sList with a runtime.Scheme.", k, k)
+	f.p("SchemeBuilder = &scheme.Builder{GroupVersion: GroupVersion}")
+	f.p(")")
+	f.p("")
+	f.p("func init() {")
+	f.p("SchemeBuilder.Register(&%s{}, &%sList{})", k, k)
+	f.p("}")
+	f.p("")
+
+	f.p("// +kubebuilder:object:root=true")
+	f.p("")
+	f.p("// %s is a managed resource representing the terraform %s resource.", k, m.TFName)
+	f.p("// +kubebuilder:subresource:status")
+	f.p("// +kubebuilder:resource:scope=Cluster,categories={crossplane,managed,%s}", cfg.ProviderName)
+	for _, c := range printerColumns {
+		f.p("// +kubebuilder:printcolumn:name=%q,type=%q,JSONPath=%q", c.Name, c.Type, c.JSONPath)
+	}
+	f.p("type %s struct {", k)
+	f.p("metav1.TypeMeta `json:\",inline\"`")
+	f.p("metav1.ObjectMeta `json:\"metadata,omitempty\"`")
+	f.p("")
+	f.p("Spec %sSpec `json:\"spec\"`", k)
+	f.p("Status %sStatus `json:\"status,omitempty\"`", k)
+	f.p("}")
+	f.p("")
+	f.p("// %sSpec defines the desired state of %s.", k, article(k))
+	f.p("type %sSpec struct {", k)
+	f.p("runtimev1alpha1.ResourceSpec `json:\",inline\"`")
+	f.p("ForProvider %s `json:\"forProvider\"`", m.Parameters.Name)
+	f.p("}")
+	f.p("")
+	f.p("// %sStatus represents the observed state of %s.", k, article(k))
+	f.p("type %sStatus struct {", k)
+	f.p("runtimev1alpha1.ResourceStatus `json:\",inline\"`")
+	f.p("AtProvider %s `json:\"atProvider,omitempty\"`", m.Observation.Name)
+	f.p("}")
+	f.p("")
+	f.p("// +kubebuilder:object:root=true")
+	f.p("")
+	f.p("// %sList contains a list of %s.", k, k)
+	f.p("type %sList struct {", k)
+	f.p("metav1.TypeMeta `json:\",inline\"`")
+	f.p("metav1.ListMeta `json:\"metadata,omitempty\"`")
+	f.p("Items []%s `json:\"items\"`", k)
+	f.p("}")
+
+	for _, st := range m.Structs {
+		f.p("")
+		f.comment(st.Comment)
+		f.p("type %s struct {", st.Name)
+		for i, fd := range st.Fields {
+			if i > 0 {
+				f.p("")
+			}
+			emitField(f, fd)
+		}
+		f.p("}")
+	}
+	return f
+}
+
+func emitField(f *goFile, fd *field) {
+	if fd.Description != "" {
+		f.comment(fd.Description)
+	}
+	if fd.Required {
+		f.p("// +kubebuilder:validation:Required")
+	} else {
+		f.p("// +optional")
+	}
+	if fd.Block && fd.Type.kind != kindStruct {
+		if fd.MinItems > 0 {
+			f.p("// +kubebuilder:validation:MinItems=%d", fd.MinItems)
+		}
+		if fd.MaxItems > 0 {
+			f.p("// +kubebuilder:validation:MaxItems=%d", fd.MaxItems)
+		}
+	}
+	tag := fd.JSON
+	if !fd.Required {
+		tag += ",omitempty"
+	}
+	f.p("%s %s `json:%q`", fd.Name, fd.GoType(), tag)
+}
+
+// emitDeepCopy writes the deepcopy functions controller-gen would write, so
+// that the generated packages compile without running it.
+func emitDeepCopy(pkg *goPackage, m *resourceModel) *goFile {
+	f := pkg.newFile()
+	f.use(pkgRuntime, "")
+	k := m.Kind
+
+	f.p("// DeepCopyInto copies the receiver into out. in must be non-nil.")
+	f.p("func (in *%s) DeepCopyInto(out *%s) {", k, k)
+	f.p("*out = *in")
+	f.p("out.TypeMeta = in.TypeMeta")
+	f.p("in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)")
+	f.p("in.Spec.DeepCopyInto(&out.Spec)")
+	f.p("in.Status.DeepCopyInto(&out.Status)")
+	f.p("}")
+	emitDeepCopyFuncs(f, k)
+	f.p("")
+	f.p("// DeepCopyObject is a deepcopy function, copying the receiver into a runtime.Object.")
+	f.p("func (in *%s) DeepCopyObject() runtime.Object {", k)
+	f.p("if c := in.DeepCopy(); c != nil {")
+	f.p("return c")
+	f.p("}")
+	f.p("return nil")
+	f.p("}")
+	f.p("")
+	f.p("// DeepCopyInto copies the receiver into out. in must be non-nil.")
+	f.p("func (in *%sList) DeepCopyInto(out *%sList) {", k, k)
+	f.p("*out = *in")
+	f.p("out.TypeMeta = in.TypeMeta")
+	f.p("in.ListMeta.DeepCopyInto(&out.ListMeta)")
+	f.p("if in.Items != nil {")
+	f.p("in, out := &in.Items, &out.Items")
+	f.p("*out = make([]%s, len(*in))", k)
+	f.p("for i := range *in {")
+	f.p("(*in)[i].DeepCopyInto(&(*out)[i])")
+	f.p("}")
+	f.p("}")
+	f.p("}")
+	emitDeepCopyFuncs(f, k+"List")
+	f.p("")
+	f.p("// DeepCopyObject is a deepcopy function, copying the receiver into a runtime.Object.")
+	f.p("func (in *%sList) DeepCopyObject() runtime.Object {", k)
+	f.p("if c := in.DeepCopy(); c != nil {")
+	f.p("return c")
+	f.p("}")
+	f.p("return nil")
+	f.p("}")
+	f.p("")
+	f.p("// DeepCopyInto copies the receiver into out. in must be non-nil.")
+	f.p("func (in *%sSpec) DeepCopyInto(out *%sSpec) {", k, k)
+	f.p("*out = *in")
+	f.p("in.ResourceSpec.DeepCopyInto(&out.ResourceSpec)")
+	f.p("in.ForProvider.DeepCopyInto(&out.ForProvider)")
+	f.p("}")
+	emitDeepCopyFuncs(f, k+"Spec")
+	f.p("")
+	f.p("// DeepCopyInto copies the receiver into out. in must be non-nil.")
+	f.p("func (in *%sStatus) DeepCopyInto(out *%sStatus) {", k, k)
+	f.p("*out = *in")
+	f.p("in.ResourceStatus.DeepCopyInto(&out.ResourceStatus)")
+	f.p("in.AtProvider.DeepCopyInto(&out.AtProvider)")
+	f.p("}")
+	emitDeepCopyFuncs(f, k+"Status")
+
+	for _, st := range m.Structs {
+		f.p("")
+		f.p("// DeepCopyInto copies the receiver into out. in must be non-nil.")
+		f.p("func (in *%s) DeepCopyInto(out *%s) {", st.Name, st.Name)
+		f.p("*out = *in")
+		for _, fd := range st.Fields {
+			switch {
+			case fd.Pointer && fd.Type.kind == kindStruct:
+				f.p("if in.%s != nil {", fd.Name)
+				f.p("in, out := &in.%s, &out.%s", fd.Name, fd.Name)
+				f.p("*out = new(%s)", fd.Type.Expr())
+				f.p("(*in).DeepCopyInto(*out)")
+				f.p("}")
+			case fd.Pointer:
+				f.p("if in.%s != nil {", fd.Name)
+				f.p("in, out := &in.%s, &out.%s", fd.Name, fd.Name)
+				f.p("*out = new(%s)", fd.Type.Expr())
+				f.p("**out = **in")
+				f.p("}")
+			case fd.Type.kind == kindStruct:
+				f.p("in.%s.DeepCopyInto(&out.%s)", fd.Name, fd.Name)
+			case fd.Type.kind != kindPrimitive:
+				f.p("out.%s = %s(in.%s)", fd.Name, copyFunc(f, fd.Type), fd.Name)
+			}
+		}
+		f.p("}")
+		emitDeepCopyFuncs(f, st.Name)
+	}
+	return f
+}
+
+func emitDeepCopyFuncs(f *goFile, name string) {
+	f.p("")
+	f.p("// DeepCopy copies the receiver, creating a new %s.", name)
+	f.p("func (in *%s) DeepCopy() *%s {", name, name)
+	f.p("if in == nil {")
+	f.p("return nil")
+	f.p("}")
+	f.p("out := new(%s)", name)
+	f.p("in.DeepCopyInto(out)")
+	f.p("return out")
+	f.p("}")
+}
+
+// copyFunc returns the name of the helper deep copying a collection,
+// queueing it to be written.
+func copyFunc(f *goFile, t *goType) string {
+	name := "copy" + t.Key()
+	if !f.once(name) {
+		return name
+	}
+	h := f.helper()
+	h.p("")
+	h.p("func %s(in %s) %s {", name, t.Expr(), t.Expr())
+	h.p("if in == nil {")
+	h.p("return nil")
+	h.p("}")
+	elem := t.elem
+	if t.kind == kindMap {
+		h.p("out := make(%s, len(in))", t.Expr())
+		h.p("for k, v := range in {")
+		switch elem.kind {
+		case kindPrimitive:
+			h.p("out[k] = v")
+		case kindStruct:
+			h.p("var c %s", elem.Expr())
+			h.p("v.DeepCopyInto(&c)")
+			h.p("out[k] = c")
+		default:
+			h.p("out[k] = %s(v)", copyFunc(h, elem))
+		}
+		h.p("}")
+	} else {
+		h.p("out := make(%s, len(in))", t.Expr())
+		switch elem.kind {
+		case kindPrimitive:
+			h.p("copy(out, in)")
+		case kindStruct:
+			h.p("for i := range in {")
+			h.p("in[i].DeepCopyInto(&out[i])")
+			h.p("}")
+		default:
+			h.p("for i := range in {")
+			h.p("out[i] = %s(in[i])", copyFunc(h, elem))
+			h.p("}")
+		}
+	}
+	h.p("return out")
+	h.p("}")
+	f.queue(h)
+	return name
+}
+
+// emitManaged writes the methods that make the Kind a resource.Managed,
+// like crossplane-tools' angryjet would.
+func emitManaged(pkg *goPackage, m *resourceModel) *goFile {
+	f := pkg.newFile()
+	f.use(pkgCorev1, "corev1")
+	f.use(pkgRuntimev1alpha1, "runtimev1alpha1")
+	f.use(pkgResource, "")
+	k := m.Kind
+	accessors := []struct{ name, typ, field string }{
+		{"BindingPhase", "runtimev1alpha1.BindingPhase", ""},
+		{"ClaimReference", "*corev1.ObjectReference", "Spec.ClaimReference"},
+		{"ClassReference", "*corev1.ObjectReference", "Spec.ClassReference"},
+		{"ProviderReference", "*corev1.ObjectReference", "Spec.ProviderReference"},
+		{"ReclaimPolicy", "runtimev1alpha1.ReclaimPolicy", "Spec.ReclaimPolicy"},
+		{"WriteConnectionSecretToReference", "*runtimev1alpha1.SecretReference", "Spec.WriteConnectionSecretToReference"},
+	}
+	for i, a := range accessors {
+		if i > 0 {
+			f.p("")
+		}
+		f.p("// Get%s of this %s.", a.name, k)
+		f.p("func (mg *%s) Get%s() %s {", k, a.name, a.typ)
+		if a.field == "" {
+			f.p("return mg.Status.Get%s()", a.name)
+		} else {
+			f.p("return mg.%s", a.field)
+		}
+		f.p("}")
+		f.p("")
+		f.p("// Set%s of this %s.", a.name, k)
+		f.p("func (mg *%s) Set%s(r %s) {", k, a.name, a.typ)
+		if a.field == "" {
+			f.p("mg.Status.Set%s(r)", a.name)
+		} else {
+			f.p("mg.%s = r", a.field)
+		}
+		f.p("}")
+	}
+	f.p("")
+	f.p("// GetCondition of this %s.", k)
+	f.p("func (mg *%s) GetCondition(ct runtimev1alpha1.ConditionType) runtimev1alpha1.Condition {", k)
+	f.p("return mg.Status.GetCondition(ct)")
+	f.p("}")
+	f.p("")
+	f.p("// SetConditions of this %s.", k)
+	f.p("func (mg *%s) SetConditions(c ...runtimev1alpha1.Condition) {", k)
+	f.p("mg.Status.SetConditions(c...)")
+	f.p("}")
+	f.p("")
+	f.p("// GetItems of this %sList.", k)
+	f.p("func (l *%sList) GetItems() []resource.Managed {", k)
+	f.p("items := make([]resource.Managed, len(l.Items))")
+	f.p("for i := range l.Items {")
+	f.p("items[i] = &l.Items[i]")
+	f.p("}")
+	f.p("return items")
+	f.p("}")
+	return f
+}
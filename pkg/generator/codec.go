@@ -0,0 +1,295 @@
+package generator
+
+import (
+	"fmt"
+)
+
+// emitCodec writes the CtyEncoder, CtyDecoder and YAML (un)marshallers of
+// a resource. The encoders look attribute types up in the schema they are
+// given rather than compiling them in, and skip attributes the schema does
+// not have, so that they keep working with other provider versions.
+func emitCodec(pkg *goPackage, m *resourceModel) *goFile {
+	f := pkg.newFile()
+	f.use("fmt", "")
+	f.use(pkgResource, "")
+	f.use(pkgProviders, "")
+	f.use(pkgCty, "")
+	f.use(pkgYAML, "")
+	f.use(pkgCtyconv, "")
+	k := m.Kind
+
+	f.p("// codec encodes %s to and from cty values and YAML.", k)
+	f.p("type codec struct{}")
+	f.p("")
+	f.p("// EncodeCty encodes the parameters and observation of %s, and its", article(k))
+	f.p("// external name as the id.")
+	f.p("func (codec) EncodeCty(mr resource.Managed, s *providers.Schema) (cty.Value, error) {")
+	f.p("r, ok := mr.(*%s)", k)
+	f.p("if !ok {")
+	f.p("return cty.NilVal, fmt.Errorf(\"cannot encode %%T as a %%s\", mr, Kind)")
+	f.p("}")
+	f.p("ty := s.Block.ImpliedType()")
+	f.p("attrs := make(map[string]cty.Value)")
+	f.p("%s(r.Spec.ForProvider, ty, attrs)", encodeAttrsFunc(f, m.Parameters))
+	f.p("%s(r.Status.AtProvider, ty, attrs)", encodeAttrsFunc(f, m.Observation))
+	if m.HasID {
+		f.use(pkgMeta, "")
+		f.p("if id := meta.GetExternalName(r); id != \"\" {")
+		f.p("attrs[%q] = cty.StringVal(id)", idAttribute)
+		f.p("}")
+	}
+	f.p("return ctyconv.Object(ty, attrs), nil")
+	f.p("}")
+	f.p("")
+	f.p("// DecodeCty decodes v into a copy of the %s, replacing its parameters", k)
+	f.p("// and observation. The id becomes the external name.")
+	f.p("func (codec) DecodeCty(mr resource.Managed, v cty.Value, s *providers.Schema) (resource.Managed, error) {")
+	f.p("base, ok := mr.(*%s)", k)
+	f.p("if !ok {")
+	f.p("return nil, fmt.Errorf(\"cannot decode into %%T, expected a %%s\", mr, Kind)")
+	f.p("}")
+	f.p("r := base.DeepCopy()")
+	f.p("r.Spec.ForProvider = %s(v)", decodeFunc(f, &goType{kind: kindStruct, strct: m.Parameters}))
+	f.p("r.Status.AtProvider = %s(v)", decodeFunc(f, &goType{kind: kindStruct, strct: m.Observation}))
+	if m.HasID {
+		f.p("if id := ctyconv.String(ctyconv.GetAttr(v, %q)); id != \"\" {", idAttribute)
+		f.p("meta.SetExternalName(r, id)")
+		f.p("}")
+	}
+	f.p("return r, nil")
+	f.p("}")
+	f.p("")
+	f.p("// MarshalResourceYAML returns the %s as a YAML manifest.", k)
+	f.p("func (codec) MarshalResourceYAML(mr resource.Managed) ([]byte, error) {")
+	f.p("return yaml.Marshal(mr)")
+	f.p("}")
+	f.p("")
+	f.p("// UnmarshalResourceYAML reads %s from a YAML manifest.", article(k))
+	f.p("func (codec) UnmarshalResourceYAML(b []byte) (resource.Managed, error) {")
+	f.p("r := &%s{}", k)
+	f.p("if err := yaml.UnmarshalStrict(b, r); err != nil {")
+	f.p("return nil, err")
+	f.p("}")
+	f.p("return r, nil")
+	f.p("}")
+	return f
+}
+
+// encodeAttrsFunc returns the name of the helper adding the attributes of
+// a struct to an attribute map, queueing it to be written.
+func encodeAttrsFunc(f *goFile, st *structType) string {
+	name := "encode" + st.Name + "Attributes"
+	if !f.once(name) {
+		return name
+	}
+	h := f.helper()
+	h.p("")
+	h.p("func %s(v %s, ty cty.Type, attrs map[string]cty.Value) {", name, st.Name)
+	for _, fd := range st.Fields {
+		value := "v." + fd.Name
+		if !fd.Pointer && fd.Type.kind == kindPrimitive {
+			h.p("if _, ok := ctyconv.Attribute(ty, %q); ok {", fd.TF)
+		} else {
+			h.p("if aty, ok := ctyconv.Attribute(ty, %q); ok {", fd.TF)
+		}
+		switch {
+		case fd.Pointer:
+			h.p("if %s == nil {", value)
+			h.p("attrs[%q] = cty.NullVal(aty)", fd.TF)
+			h.p("} else {")
+			h.p("attrs[%q] = %s", fd.TF, encodeExpr(h, fd.Type, "*"+value, "aty"))
+			h.p("}")
+		case fd.Block:
+			h.p("attrs[%q] = ctyconv.EmptyIfNull(%s)", fd.TF, encodeExpr(h, fd.Type, value, "aty"))
+		default:
+			h.p("attrs[%q] = %s", fd.TF, encodeExpr(h, fd.Type, value, "aty"))
+		}
+		h.p("}")
+	}
+	h.p("}")
+	f.queue(h)
+	return name
+}
+
+// encodeExpr returns an expression encoding value, of type t, as a cty
+// value of type ty.
+func encodeExpr(f *goFile, t *goType, value, ty string) string {
+	switch {
+	case t.kind != kindPrimitive:
+		return fmt.Sprintf("%s(%s, %s)", encodeFunc(f, t), value, ty)
+	case t.primitive == "float64":
+		return fmt.Sprintf("cty.NumberFloatVal(%s)", value)
+	case t.primitive == "bool":
+		return fmt.Sprintf("cty.BoolVal(%s)", value)
+	}
+	return fmt.Sprintf("cty.StringVal(%s)", value)
+}
+
+// encodeFunc returns the name of the helper encoding a struct or
+// collection, queueing it to be written. Nil collections encode as nulls.
+func encodeFunc(f *goFile, t *goType) string {
+	name := "encode" + t.Key()
+	if !f.once(name) {
+		return name
+	}
+	h := f.helper()
+	h.p("")
+	h.p("func %s(v %s, ty cty.Type) cty.Value {", name, t.Expr())
+	switch t.kind {
+	case kindStruct:
+		h.p("attrs := make(map[string]cty.Value)")
+		h.p("%s(v, ty, attrs)", encodeAttrsFunc(h, t.strct))
+		h.p("return ctyconv.Object(ty, attrs)")
+	case kindList, kindSet:
+		ctor, empty := "cty.ListVal", "cty.ListValEmpty"
+		if t.kind == kindSet {
+			ctor, empty = "cty.SetVal", "cty.SetValEmpty"
+		}
+		h.p("if v == nil {")
+		h.p("return cty.NullVal(ty)")
+		h.p("}")
+		h.p("ety := ctyconv.ElementType(ty)")
+		h.p("if len(v) == 0 {")
+		h.p("return %s(ety)", empty)
+		h.p("}")
+		h.p("elems := make([]cty.Value, len(v))")
+		h.p("for i := range v {")
+		h.p("elems[i] = %s", encodeExpr(h, t.elem, "v[i]", "ety"))
+		h.p("}")
+		h.p("return %s(elems)", ctor)
+	case kindMap:
+		h.p("if v == nil {")
+		h.p("return cty.NullVal(ty)")
+		h.p("}")
+		h.p("ety := ctyconv.ElementType(ty)")
+		h.p("if len(v) == 0 {")
+		h.p("return cty.MapValEmpty(ety)")
+		h.p("}")
+		h.p("elems := make(map[string]cty.Value, len(v))")
+		h.p("for k := range v {")
+		h.p("elems[k] = %s", encodeExpr(h, t.elem, "v[k]", "ety"))
+		h.p("}")
+		h.p("return cty.MapVal(elems)")
+	}
+	h.p("}")
+	f.queue(h)
+	return name
+}
+
+// decodeExpr returns an expression decoding the cty value v into type t.
+func decodeExpr(f *goFile, t *goType, v string) string {
+	switch {
+	case t.kind != kindPrimitive:
+		return fmt.Sprintf("%s(%s)", decodeFunc(f, t), v)
+	case t.dynamic:
+		return fmt.Sprintf("ctyconv.Dynamic(%s)", v)
+	case t.primitive == "float64":
+		return fmt.Sprintf("ctyconv.Float64(%s)", v)
+	case t.primitive == "bool":
+		return fmt.Sprintf("ctyconv.Bool(%s)", v)
+	}
+	return fmt.Sprintf("ctyconv.String(%s)", v)
+}
+
+// decodeFunc returns the name of the helper decoding a struct or
+// collection, queueing it to be written. Nulls decode as nil collections,
+// except for nested blocks, where empty collections decode as nil too.
+func decodeFunc(f *goFile, t *goType) string {
+	name := "decode" + t.Key()
+	if !f.once(name) {
+		return name
+	}
+	h := f.helper()
+	h.p("")
+	h.p("func %s(v cty.Value) %s {", name, t.Expr())
+	switch t.kind {
+	case kindStruct:
+		h.p("out := %s{}", t.Expr())
+		h.p("if ctyconv.IsNull(v) {")
+		h.p("return out")
+		h.p("}")
+		for _, fd := range t.strct.Fields {
+			attr := fmt.Sprintf("ctyconv.GetAttr(v, %q)", fd.TF)
+			switch {
+			case fd.Pointer:
+				h.p("if a := %s; !ctyconv.IsNull(a) {", attr)
+				h.p("d := %s", decodeExpr(h, fd.Type, "a"))
+				h.p("out.%s = &d", fd.Name)
+				h.p("}")
+			case fd.Block && fd.Type.kind != kindStruct:
+				h.p("if d := %s; len(d) > 0 {", decodeExpr(h, fd.Type, attr))
+				h.p("out.%s = d", fd.Name)
+				h.p("}")
+			default:
+				h.p("out.%s = %s", fd.Name, decodeExpr(h, fd.Type, attr))
+			}
+		}
+		h.p("return out")
+	case kindList, kindSet:
+		h.p("if ctyconv.IsNull(v) {")
+		h.p("return nil")
+		h.p("}")
+		h.p("out := make(%s, 0, v.LengthInt())", t.Expr())
+		h.p("for it := v.ElementIterator(); it.Next(); {")
+		h.p("_, e := it.Element()")
+		h.p("out = append(out, %s)", decodeExpr(h, t.elem, "e"))
+		h.p("}")
+		h.p("return out")
+	case kindMap:
+		h.p("if ctyconv.IsNull(v) {")
+		h.p("return nil")
+		h.p("}")
+		h.p("out := make(%s, v.LengthInt())", t.Expr())
+		h.p("for it := v.ElementIterator(); it.Next(); {")
+		h.p("k, e := it.Element()")
+		h.p("out[k.AsString()] = %s", decodeExpr(h, t.elem, "e"))
+		h.p("}")
+		h.p("return out")
+	}
+	h.p("}")
+	f.queue(h)
+	return name
+}
+
+// emitMerge writes the ResourceMerger of a resource. Merging copies the
+// external name and observation from the provider's view of the resource,
+// and then compares the parameters. The generated Implementation runs it
+// under a plugin.LateInitializer, which late-initializes the parameters
+// first.
+func emitMerge(pkg *goPackage, m *resourceModel) *goFile {
+	f := pkg.newFile()
+	f.use("reflect", "")
+	f.use(pkgResource, "")
+	f.use(pkgPlugin, "")
+	k := m.Kind
+
+	f.p("// merger merges the provider's view of %s into the local one.", article(k))
+	f.p("type merger struct{}")
+	f.p("")
+	f.p("// MergeResources merges observed into local.")
+	f.p("func (merger) MergeResources(local, observed resource.Managed) plugin.MergeDescription {")
+	f.p("md := plugin.MergeDescription{}")
+	f.p("l, ok := local.(*%s)", k)
+	f.p("if !ok {")
+	f.p("return md")
+	f.p("}")
+	f.p("o, ok := observed.(*%s)", k)
+	f.p("if !ok {")
+	f.p("return md")
+	f.p("}")
+	if m.HasID {
+		f.use(pkgMeta, "")
+		f.p("if id := meta.GetExternalName(o); id != \"\" && id != meta.GetExternalName(l) {")
+		f.p("meta.SetExternalName(l, id)")
+		f.p("md.AnnotationsUpdated = true")
+		f.p("}")
+	}
+	f.p("if !reflect.DeepEqual(l.Status.AtProvider, o.Status.AtProvider) {")
+	f.p("o.Status.AtProvider.DeepCopyInto(&l.Status.AtProvider)")
+	f.p("md.StatusUpdated = true")
+	f.p("}")
+	f.p("md.NeedsProviderUpdate = !reflect.DeepEqual(l.Spec.ForProvider, o.Spec.ForProvider)")
+	f.p("return md")
+	f.p("}")
+	return f
+}
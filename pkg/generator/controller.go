@@ -0,0 +1,103 @@
+package generator
+
+// emitConfigure writes the ReconcilerConfigurer of a resource, which binds
+// a managed.Reconciler using the runtime's Connector to the Kind.
+func emitConfigure(pkg *goPackage, m *resourceModel) *goFile {
+	f := pkg.newFile()
+	f.use(pkgCtrl, "ctrl")
+	f.use(pkgEvent, "")
+	f.use(pkgLogging, "")
+	f.use(pkgManaged, "")
+	f.use(pkgResource, "")
+	f.use(pkgClient, "")
+	f.use(pkgController, "")
+	f.use(pkgPlugin, "")
+	k := m.Kind
+
+	f.p("// reconcilerConfigurer sets up the controller for %s.", k)
+	f.p("type reconcilerConfigurer struct{}")
+	f.p("")
+	f.p("// ConfigureReconciler adds a managed resource controller for %s to mgr.", k)
+	f.p("// The external name is left unset until the provider assigns an id.")
+	f.p("func (reconcilerConfigurer) ConfigureReconciler(mgr ctrl.Manager, l logging.Logger, idx *plugin.Index, pool *client.ProviderPool, o plugin.ReconcilerOptions) error {")
+	f.p("name := managed.ControllerName(GroupKind)")
+	f.p("recorder := event.NewAPIRecorder(mgr.GetEventRecorderFor(name))")
+	f.p("connector := &controller.Connector{KubeClient: mgr.GetClient(), PluginIndex: idx, Logger: l, Pool: pool, Recorder: recorder}")
+	f.p("opts := []managed.ReconcilerOption{")
+	f.p("managed.WithExternalConnecter(connector),")
+	f.p("managed.WithInitializers(),")
+	f.p("managed.WithLogger(l.WithValues(\"controller\", name)),")
+	f.p("managed.WithRecorder(recorder),")
+	f.p("}")
+	f.p("if o.PollInterval > 0 {")
+	f.p("opts = append(opts, managed.WithLongWait(o.PollInterval))")
+	f.p("}")
+	f.p("r := managed.NewReconciler(mgr, resource.ManagedKind(GroupVersionKind), opts...)")
+	f.p("return ctrl.NewControllerManagedBy(mgr).")
+	f.p("Named(name).")
+	f.p("For(&%s{}).", k)
+	f.p("WithOptions(o.ControllerOptions()).")
+	f.p("Complete(controller.NewDisconnectingReconciler(r, GroupVersionKind, connector))")
+	f.p("}")
+	return f
+}
+
+// emitImplementation writes the function returning the generated
+// Implementation of a resource.
+func emitImplementation(pkg *goPackage, m *resourceModel) *goFile {
+	f := pkg.newFile()
+	f.use(pkgPlugin, "")
+
+	f.p("// Implementation returns the generated Implementation for %s. Overlay", m.Kind)
+	f.p("// hand written Implementations on top of it to override its callbacks.")
+	f.p("func Implementation() *plugin.Implementation {")
+	f.p("return &plugin.Implementation{")
+	f.p("GVK: GroupVersionKind,")
+	f.p("TerraformResourceName: TerraformResourceName,")
+	f.p("SchemeBuilder: SchemeBuilder,")
+	f.p("ReconcilerConfigurer: reconcilerConfigurer{},")
+	f.p("ResourceMerger: plugin.NewLateInitializer(merger{}),")
+	f.p("CtyEncoder: codec{},")
+	f.p("CtyDecoder: codec{},")
+	f.p("ResourceYAMLMarshaller: codec{},")
+	f.p("ResourceYAMLUnmarshaller: codec{},")
+	f.p("}")
+	f.p("}")
+	return f
+}
+
+// emitIndex writes the root package, which overlays the Implementations of
+// every generated resource onto a plugin.Indexer.
+func emitIndex(pkg *goPackage, cfg Config, models []*resourceModel) *goFile {
+	f := pkg.newFile()
+	f.use(pkgPlugin, "")
+	f.doc = "// Package " + pkg.name + " registers the generated managed resources of the\n// " + cfg.ProviderName + " terraform provider.\n"
+	for _, m := range models {
+		f.use(cfg.Package+"/"+m.Package, "")
+	}
+
+	f.p("// ProviderName is the name of the terraform provider.")
+	f.p("const ProviderName = %q", cfg.ProviderName)
+	f.p("")
+	f.p("// Implementations returns the generated Implementation of every resource.")
+	f.p("func Implementations() []*plugin.Implementation {")
+	f.p("return []*plugin.Implementation{")
+	for _, m := range models {
+		f.p("%s.Implementation(),", m.Package)
+	}
+	f.p("}")
+	f.p("}")
+	f.p("")
+	f.p("// Indexer returns a plugin.Indexer with the generated Implementations")
+	f.p("// overlaid. Overlay hand written Implementations before building the Index.")
+	f.p("func Indexer() (*plugin.Indexer, error) {")
+	f.p("idxr := plugin.NewIndexer()")
+	f.p("for _, impl := range Implementations() {")
+	f.p("if err := idxr.Overlay(impl); err != nil {")
+	f.p("return nil, err")
+	f.p("}")
+	f.p("}")
+	f.p("return idxr, nil")
+	f.p("}")
+	return f
+}
@@ -0,0 +1,156 @@
+package generator
+
+import (
+	"strings"
+
+	apiextv1beta1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+)
+
+type printerColumn struct {
+	Name, Type, JSONPath string
+}
+
+// printerColumns are shown by kubectl get for every generated Kind.
+var printerColumns = []printerColumn{
+	{"READY", "string", ".status.conditions[?(@.type=='Ready')].status"},
+	{"SYNCED", "string", ".status.conditions[?(@.type=='Synced')].status"},
+	{"EXTERNAL-NAME", "string", ".metadata.annotations.crossplane\\.io/external-name"},
+	{"AGE", "date", ".metadata.creationTimestamp"},
+}
+
+// crd returns the CustomResourceDefinition for a resource, matching what
+// controller-gen produces from the kubebuilder markers on its types.
+func crd(m *resourceModel, cfg Config) ([]byte, error) {
+	spec := object(map[string]apiextv1beta1.JSONSchemaProps{
+		"forProvider":                structSchema(m.Parameters),
+		"providerRef":                objectReference(),
+		"claimRef":                   objectReference(),
+		"classRef":                   objectReference(),
+		"writeConnectionSecretToRef": object(map[string]apiextv1beta1.JSONSchemaProps{"name": str(), "namespace": str()}, "name", "namespace"),
+		"reclaimPolicy":              enum("Retain", "Delete"),
+	}, "forProvider", "providerRef")
+	status := object(map[string]apiextv1beta1.JSONSchemaProps{
+		"atProvider":   structSchema(m.Observation),
+		"bindingPhase": enum("Unbindable", "Unbound", "Bound", "Released"),
+		"conditions": {
+			Type: "array",
+			Items: &apiextv1beta1.JSONSchemaPropsOrArray{Schema: func() *apiextv1beta1.JSONSchemaProps {
+				c := object(map[string]apiextv1beta1.JSONSchemaProps{
+					"type":               str(),
+					"status":             str(),
+					"lastTransitionTime": {Type: "string", Format: "date-time"},
+					"reason":             str(),
+					"message":            str(),
+				}, "lastTransitionTime", "reason", "status", "type")
+				return &c
+			}()},
+		},
+	})
+	root := object(map[string]apiextv1beta1.JSONSchemaProps{
+		"apiVersion": str(),
+		"kind":       str(),
+		"metadata":   {Type: "object"},
+		"spec":       spec,
+		"status":     status,
+	}, "spec")
+	root.Description = m.Kind + " is a managed resource representing the terraform " + m.TFName + " resource."
+
+	columns := make([]apiextv1beta1.CustomResourceColumnDefinition, len(printerColumns))
+	for i, c := range printerColumns {
+		columns[i] = apiextv1beta1.CustomResourceColumnDefinition{Name: c.Name, Type: c.Type, JSONPath: c.JSONPath}
+	}
+	def := apiextv1beta1.CustomResourceDefinition{
+		TypeMeta:   metav1.TypeMeta{APIVersion: apiextv1beta1.SchemeGroupVersion.String(), Kind: "CustomResourceDefinition"},
+		ObjectMeta: metav1.ObjectMeta{Name: m.Plural + "." + cfg.Group},
+		Spec: apiextv1beta1.CustomResourceDefinitionSpec{
+			Group: cfg.Group,
+			Names: apiextv1beta1.CustomResourceDefinitionNames{
+				Kind:       m.Kind,
+				ListKind:   m.Kind + "List",
+				Plural:     m.Plural,
+				Singular:   strings.ToLower(m.Kind),
+				Categories: []string{"crossplane", "managed", cfg.ProviderName},
+			},
+			Scope:                    apiextv1beta1.ClusterScoped,
+			Subresources:             &apiextv1beta1.CustomResourceSubresources{Status: &apiextv1beta1.CustomResourceSubresourceStatus{}},
+			AdditionalPrinterColumns: columns,
+			Validation:               &apiextv1beta1.CustomResourceValidation{OpenAPIV3Schema: &root},
+			Versions:                 []apiextv1beta1.CustomResourceDefinitionVersion{{Name: cfg.Version, Served: true, Storage: true}},
+		},
+	}
+	return yaml.Marshal(def)
+}
+
+func structSchema(st *structType) apiextv1beta1.JSONSchemaProps {
+	props := make(map[string]apiextv1beta1.JSONSchemaProps, len(st.Fields))
+	var required []string
+	for _, fd := range st.Fields {
+		s := typeSchema(fd.Type)
+		s.Description = fd.Description
+		if fd.Block && fd.Type.kind != kindStruct {
+			if fd.MinItems > 0 {
+				min := int64(fd.MinItems)
+				s.MinItems = &min
+			}
+			if fd.MaxItems > 0 {
+				max := int64(fd.MaxItems)
+				s.MaxItems = &max
+			}
+		}
+		props[fd.JSON] = s
+		if fd.Required {
+			required = append(required, fd.JSON)
+		}
+	}
+	return object(props, required...)
+}
+
+func typeSchema(t *goType) apiextv1beta1.JSONSchemaProps {
+	switch t.kind {
+	case kindList, kindSet:
+		elem := typeSchema(t.elem)
+		return apiextv1beta1.JSONSchemaProps{Type: "array", Items: &apiextv1beta1.JSONSchemaPropsOrArray{Schema: &elem}}
+	case kindMap:
+		elem := typeSchema(t.elem)
+		return apiextv1beta1.JSONSchemaProps{Type: "object", AdditionalProperties: &apiextv1beta1.JSONSchemaPropsOrBool{Allows: true, Schema: &elem}}
+	case kindStruct:
+		return structSchema(t.strct)
+	}
+	switch t.primitive {
+	case "float64":
+		return apiextv1beta1.JSONSchemaProps{Type: "number"}
+	case "bool":
+		return apiextv1beta1.JSONSchemaProps{Type: "boolean"}
+	}
+	return str()
+}
+
+func object(props map[string]apiextv1beta1.JSONSchemaProps, required ...string) apiextv1beta1.JSONSchemaProps {
+	return apiextv1beta1.JSONSchemaProps{Type: "object", Properties: props, Required: required}
+}
+
+func objectReference() apiextv1beta1.JSONSchemaProps {
+	return object(map[string]apiextv1beta1.JSONSchemaProps{
+		"apiVersion":      str(),
+		"fieldPath":       str(),
+		"kind":            str(),
+		"name":            str(),
+		"namespace":       str(),
+		"resourceVersion": str(),
+		"uid":             str(),
+	})
+}
+
+func str() apiextv1beta1.JSONSchemaProps {
+	return apiextv1beta1.JSONSchemaProps{Type: "string"}
+}
+
+func enum(values ...string) apiextv1beta1.JSONSchemaProps {
+	s := str()
+	for _, v := range values {
+		s.Enum = append(s.Enum, apiextv1beta1.JSON{Raw: []byte(`"` + v + `"`)})
+	}
+	return s
+}
@@ -0,0 +1,148 @@
+// Package generator generates managed resources for the resource types of
+// a terraform provider from its schema. For every resource type it writes
+// a Go package with the API types, with kubebuilder markers and deepcopy
+// functions, and the CtyEncoder, CtyDecoder, ResourceMerger,
+// ReconcilerConfigurer and Implementation the runtime needs, plus a CRD.
+// A root package overlays every Implementation onto a plugin.Indexer.
+package generator
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/hashicorp/terraform/providers"
+
+	"github.com/crossplane/terraform-provider-runtime/pkg/client"
+)
+
+// DefaultVersion is the API version of generated resources.
+const DefaultVersion = "v1alpha1"
+
+// Config configures the generator.
+type Config struct {
+	// ProviderName is the terraform provider name, eg google.
+	ProviderName string
+	// Group is the API group of the generated resources, defaulting to
+	// <provider>.terraform-plugin.crossplane.io.
+	Group string
+	// Version defaults to DefaultVersion.
+	Version string
+	// OutputDir is where the root package is written, with a sub-package
+	// per resource type.
+	OutputDir string
+	// Package is the import path of OutputDir.
+	Package string
+	// CRDDir defaults to the crds directory under OutputDir.
+	CRDDir string
+	// Resources limits generation to these terraform resource types.
+	// Every resource type is generated when it is empty.
+	Resources []string
+}
+
+func (cfg *Config) setDefaults() {
+	if cfg.Group == "" {
+		cfg.Group = cfg.ProviderName + ".terraform-plugin.crossplane.io"
+	}
+	if cfg.Version == "" {
+		cfg.Version = DefaultVersion
+	}
+	if cfg.CRDDir == "" {
+		cfg.CRDDir = filepath.Join(cfg.OutputDir, "crds")
+	}
+}
+
+// ReadSchema starts the provider plugin and returns its schema.
+func ReadSchema(providerName string, ropts *client.RuntimeOptions) (providers.GetSchemaResponse, error) {
+	p, err := client.NewGRPCProvider(providerName, ropts)
+	if err != nil {
+		return providers.GetSchemaResponse{}, err
+	}
+	defer p.Close()
+	resp := p.GetSchema()
+	if resp.Diagnostics.HasErrors() {
+		return resp, resp.Diagnostics.Err()
+	}
+	return resp, nil
+}
+
+// Generate writes the packages and CRDs for the resource types in schema.
+func Generate(cfg Config, schema providers.GetSchemaResponse) error {
+	if cfg.ProviderName == "" || cfg.OutputDir == "" || cfg.Package == "" {
+		return fmt.Errorf("the generator needs a provider name, an output directory and its package")
+	}
+	cfg.setDefaults()
+
+	resourceTypes := cfg.Resources
+	if len(resourceTypes) == 0 {
+		for name := range schema.ResourceTypes {
+			resourceTypes = append(resourceTypes, name)
+		}
+	}
+	sort.Strings(resourceTypes)
+
+	models := make([]*resourceModel, 0, len(resourceTypes))
+	packages := make(map[string]string)
+	for _, name := range resourceTypes {
+		s, ok := schema.ResourceTypes[name]
+		if !ok || s.Block == nil {
+			return fmt.Errorf("provider %s has no resource type %s", cfg.ProviderName, name)
+		}
+		m := newResourceModel(cfg.ProviderName, name, s.Block)
+		if other, ok := packages[m.Package]; ok {
+			return fmt.Errorf("resource types %s and %s would both be generated as package %s", other, name, m.Package)
+		}
+		packages[m.Package] = name
+		models = append(models, m)
+	}
+
+	for _, m := range models {
+		if err := generateResource(m, cfg); err != nil {
+			return err
+		}
+	}
+	root := newGoPackage(packageName(goName(cfg.ProviderName)))
+	return writeGoFile(filepath.Join(cfg.OutputDir, "index.go"), emitIndex(root, cfg, models))
+}
+
+func generateResource(m *resourceModel, cfg Config) error {
+	dir := filepath.Join(cfg.OutputDir, m.Package)
+	pkg := newGoPackage(m.Package)
+	files := map[string]*goFile{
+		"doc.go":                         emitDoc(pkg, m, cfg),
+		"types.go":                       emitTypes(pkg, m, cfg),
+		"zz_generated.deepcopy.go":       emitDeepCopy(pkg, m),
+		"zz_generated.managed.go":        emitManaged(pkg, m),
+		"zz_generated.codec.go":          emitCodec(pkg, m),
+		"zz_generated.merge.go":          emitMerge(pkg, m),
+		"zz_generated.configure.go":      emitConfigure(pkg, m),
+		"zz_generated.implementation.go": emitImplementation(pkg, m),
+	}
+	for name, f := range files {
+		if err := writeGoFile(filepath.Join(dir, name), f); err != nil {
+			return err
+		}
+	}
+	def, err := crd(m, cfg)
+	if err != nil {
+		return err
+	}
+	return writeFile(filepath.Join(cfg.CRDDir, cfg.Group+"_"+m.Plural+".yaml"), def)
+}
+
+func writeGoFile(path string, f *goFile) error {
+	src, err := f.source()
+	if err != nil {
+		return err
+	}
+	return writeFile(path, src)
+}
+
+func writeFile(path string, content []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, content, 0644)
+}
@@ -0,0 +1,176 @@
+package generator
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/hashicorp/terraform/configs/configschema"
+	"github.com/hashicorp/terraform/providers"
+	"github.com/zclconf/go-cty/cty"
+	apiextv1beta1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
+	"sigs.k8s.io/yaml"
+)
+
+const modulePath = "github.com/crossplane/terraform-provider-runtime"
+
+func schemaFixture() providers.GetSchemaResponse {
+	nested := configschema.Block{
+		Attributes: map[string]*configschema.Attribute{
+			"name":  {Type: cty.String, Required: true},
+			"size":  {Type: cty.Number, Optional: true},
+			"state": {Type: cty.String, Computed: true},
+		},
+	}
+	return providers.GetSchemaResponse{
+		Provider: providers.Schema{Block: &configschema.Block{}},
+		ResourceTypes: map[string]providers.Schema{
+			"fake_instance": {Block: &configschema.Block{
+				Attributes: map[string]*configschema.Attribute{
+					"id":          {Type: cty.String, Computed: true},
+					"name":        {Type: cty.String, Required: true},
+					"zone":        {Type: cty.String, Optional: true, Computed: true},
+					"count":       {Type: cty.Number, Optional: true},
+					"enabled":     {Type: cty.Bool, Optional: true},
+					"cpu_ratio":   {Type: cty.Number, Optional: true},
+					"tags":        {Type: cty.List(cty.String), Optional: true},
+					"ports":       {Type: cty.Set(cty.Number), Optional: true},
+					"labels":      {Type: cty.Map(cty.String), Optional: true},
+					"metadata":    {Type: cty.DynamicPseudoType, Optional: true},
+					"self_link":   {Type: cty.String, Computed: true},
+					"created_at":  {Type: cty.String, Computed: true},
+					"network_ids": {Type: cty.List(cty.String), Computed: true},
+					"scheduling": {Type: cty.Object(map[string]cty.Type{
+						"preemptible": cty.Bool,
+						"priority":    cty.Number,
+						"nodes":       cty.List(cty.String),
+					}), Optional: true},
+				},
+				BlockTypes: map[string]*configschema.NestedBlock{
+					"boot_disk":  {Nesting: configschema.NestingSingle, Block: nested},
+					"settings":   {Nesting: configschema.NestingGroup, Block: nested},
+					"disk":       {Nesting: configschema.NestingList, Block: nested, MinItems: 1},
+					"interface":  {Nesting: configschema.NestingSet, Block: nested},
+					"attachment": {Nesting: configschema.NestingMap, Block: nested},
+					"timeouts": {Nesting: configschema.NestingSingle, Block: configschema.Block{
+						Attributes: map[string]*configschema.Attribute{
+							"create": {Type: cty.String, Optional: true},
+						},
+					}},
+				},
+			}},
+			"fake_bucket": {Block: &configschema.Block{
+				Attributes: map[string]*configschema.Attribute{
+					"name": {Type: cty.String, Required: true},
+				},
+			}},
+		},
+	}
+}
+
+// conformanceTest runs the conformance kit over every generated
+// Implementation, against the schema written next to it.
+const conformanceTest = `package fake
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"testing"
+
+	"github.com/hashicorp/terraform/providers"
+
+	"github.com/crossplane/terraform-provider-runtime/pkg/plugin/conformance"
+)
+
+func TestConformance(t *testing.T) {
+	b, err := ioutil.ReadFile("schema.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	schemas := map[string]providers.Schema{}
+	if err := json.Unmarshal(b, &schemas); err != nil {
+		t.Fatal(err)
+	}
+	idxr, err := Indexer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := idxr.BuildIndex(); err != nil {
+		t.Fatal(err)
+	}
+	for _, impl := range Implementations() {
+		s := schemas[impl.TerraformResourceName]
+		conformance.Run(t, conformance.Case{Implementation: impl, Schema: &s})
+	}
+}
+`
+
+func TestGenerate(t *testing.T) {
+	// generate inside the module, so that the output can be built with it
+	if _, err := os.Stat("testdata"); os.IsNotExist(err) {
+		if err := os.Mkdir("testdata", 0755); err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll("testdata")
+	}
+	dir, err := ioutil.TempDir("testdata", "generated")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	schema := schemaFixture()
+	cfg := Config{ProviderName: "fake", OutputDir: dir, Package: modulePath + "/pkg/generator/" + filepath.ToSlash(dir)}
+	if err := Generate(cfg, schema); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, path := range []string{
+		"index.go",
+		"instance/types.go",
+		"instance/zz_generated.deepcopy.go",
+		"bucket/zz_generated.implementation.go",
+	} {
+		if _, err := os.Stat(filepath.Join(dir, path)); err != nil {
+			t.Error(err)
+		}
+	}
+	b, err := ioutil.ReadFile(filepath.Join(dir, "crds", "fake.terraform-plugin.crossplane.io_instances.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	def := apiextv1beta1.CustomResourceDefinition{}
+	if err := yaml.UnmarshalStrict(b, &def); err != nil {
+		t.Fatal(err)
+	}
+	if def.Spec.Names.Kind != "Instance" || def.Spec.Group != "fake.terraform-plugin.crossplane.io" {
+		t.Errorf("unexpected CRD names %+v in group %s", def.Spec.Names, def.Spec.Group)
+	}
+	forProvider := def.Spec.Validation.OpenAPIV3Schema.Properties["spec"].Properties["forProvider"]
+	if _, ok := forProvider.Properties["bootDisk"]; !ok {
+		t.Errorf("forProvider is missing the bootDisk block: %v", forProvider.Properties)
+	}
+
+	if testing.Short() {
+		t.Skip("skipping building the generated packages in short mode")
+	}
+	b, err = json.Marshal(schema.ResourceTypes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "schema.json"), b, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "conformance_test.go"), []byte(conformanceTest), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for _, args := range [][]string{{"vet", "./..."}, {"test", "."}} {
+		cmd := exec.Command("go", args...)
+		cmd.Dir = dir
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("go %v: %s\n%s", args, err, out)
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package generator
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"sort"
+	"strings"
+)
+
+// header marks generated files, see https://golang.org/s/generatedcode.
+const header = "// Code generated by the terraform-provider-runtime generator. DO NOT EDIT.\n\n"
+
+// goFile accumulates the source of a generated file and the imports it
+// uses.
+type goFile struct {
+	pkg     string
+	doc     string
+	imports map[string]string
+	body    bytes.Buffer
+	// helpers are written after the body.
+	helperBody bytes.Buffer
+	// emitted tracks the helper functions already written.
+	emitted map[string]bool
+}
+
+// goPackage is a generated package. Its files share helper functions.
+type goPackage struct {
+	name    string
+	emitted map[string]bool
+}
+
+func newGoPackage(name string) *goPackage {
+	return &goPackage{name: name, emitted: make(map[string]bool)}
+}
+
+func (p *goPackage) newFile() *goFile {
+	return &goFile{pkg: p.name, imports: make(map[string]string), emitted: p.emitted}
+}
+
+// helper returns a file to write a helper function to, before it is queued
+// onto f.
+func (f *goFile) helper() *goFile {
+	return &goFile{pkg: f.pkg, imports: make(map[string]string), emitted: f.emitted}
+}
+
+// use imports path, under alias if it is not empty.
+func (f *goFile) use(path, alias string) {
+	f.imports[path] = alias
+}
+
+// p writes a line of source.
+func (f *goFile) p(format string, args ...interface{}) {
+	fmt.Fprintf(&f.body, format, args...)
+	f.body.WriteString("\n")
+}
+
+// once reports whether the helper called name still has to be written,
+// marking it as written.
+func (f *goFile) once(name string) bool {
+	if f.emitted[name] {
+		return false
+	}
+	f.emitted[name] = true
+	return true
+}
+
+// queue appends the source and imports of h to the file's helpers.
+func (f *goFile) queue(h *goFile) {
+	f.helperBody.Write(h.body.Bytes())
+	f.helperBody.Write(h.helperBody.Bytes())
+	for path, alias := range h.imports {
+		f.imports[path] = alias
+	}
+}
+
+// comment writes text as a line comment, one line per line of text.
+func (f *goFile) comment(text string) {
+	for _, line := range strings.Split(strings.TrimSpace(text), "\n") {
+		f.p("// %s", strings.TrimSpace(line))
+	}
+}
+
+// source returns the formatted source of the file.
+func (f *goFile) source() ([]byte, error) {
+	var b bytes.Buffer
+	b.WriteString(header)
+	b.WriteString(f.doc)
+	fmt.Fprintf(&b, "package %s\n\n", f.pkg)
+	if len(f.imports) > 0 {
+		// the standard library is imported first, in its own group
+		var std, other []string
+		for path := range f.imports {
+			if strings.Contains(strings.SplitN(path, "/", 2)[0], ".") {
+				other = append(other, path)
+			} else {
+				std = append(std, path)
+			}
+		}
+		sort.Strings(std)
+		sort.Strings(other)
+		b.WriteString("import (\n")
+		for i, group := range [][]string{std, other} {
+			if i > 0 && len(std) > 0 && len(other) > 0 {
+				b.WriteString("\n")
+			}
+			for _, path := range group {
+				fmt.Fprintf(&b, "\t%s %q\n", f.imports[path], path)
+			}
+		}
+		b.WriteString(")\n\n")
+	}
+	b.Write(f.body.Bytes())
+	b.Write(f.helperBody.Bytes())
+	src, err := format.Source(b.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("generated invalid Go source for package %s: %s", f.pkg, err)
+	}
+	return src, nil
+}
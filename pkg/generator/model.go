@@ -0,0 +1,295 @@
+package generator
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/terraform/configs/configschema"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// idAttribute is stored in the crossplane external name annotation rather
+// than in the spec or status.
+const idAttribute = "id"
+
+type typeKind int
+
+const (
+	kindPrimitive typeKind = iota
+	kindList
+	kindSet
+	kindMap
+	kindStruct
+)
+
+// goType is the Go representation of a cty type.
+type goType struct {
+	kind typeKind
+	// primitive is string, float64 or bool.
+	primitive string
+	// dynamic primitives hold dynamically typed values as strings.
+	dynamic bool
+	elem    *goType
+	strct   *structType
+}
+
+// Expr returns the type as written in Go source.
+func (t *goType) Expr() string {
+	switch t.kind {
+	case kindList, kindSet:
+		return "[]" + t.elem.Expr()
+	case kindMap:
+		return "map[string]" + t.elem.Expr()
+	case kindStruct:
+		return t.strct.Name
+	}
+	return t.primitive
+}
+
+// Key names the type in the names of generated helpers, eg StringList.
+func (t *goType) Key() string {
+	switch t.kind {
+	case kindList:
+		return t.elem.Key() + "List"
+	case kindSet:
+		return t.elem.Key() + "Set"
+	case kindMap:
+		return t.elem.Key() + "Map"
+	case kindStruct:
+		return t.strct.Name
+	}
+	if t.dynamic {
+		return "Dynamic"
+	}
+	return goName(t.primitive)
+}
+
+// structType is a generated struct, for a block or an object type.
+type structType struct {
+	Name    string
+	Comment string
+	Fields  []*field
+}
+
+// field is a field of a generated struct, for an attribute or a nested
+// block.
+type field struct {
+	Name string
+	JSON string
+	// TF is the name of the attribute or block in the terraform schema.
+	TF   string
+	Type *goType
+	// Pointer fields are nil when the attribute is null.
+	Pointer  bool
+	Required bool
+	// Block fields hold nested blocks, whose collections are never null.
+	Block       bool
+	MinItems    int
+	MaxItems    int
+	Description string
+}
+
+// GoType returns the field's type as written in Go source.
+func (f *field) GoType() string {
+	if f.Pointer {
+		return "*" + f.Type.Expr()
+	}
+	return f.Type.Expr()
+}
+
+// resourceModel is everything generated for one terraform resource type.
+type resourceModel struct {
+	TFName  string
+	Kind    string
+	Package string
+	Plural  string
+	// HasID is true if the resource has an id attribute, which is stored
+	// as the external name.
+	HasID       bool
+	Parameters  *structType
+	Observation *structType
+	// Structs lists every generated struct, including Parameters and
+	// Observation, in the order they are emitted.
+	Structs []*structType
+}
+
+type modelBuilder struct {
+	structs []*structType
+	names   map[string]bool
+}
+
+// newResourceModel splits the resource's top level attributes into the
+// spec's parameters, set by users, and the status' observation, set by the
+// provider. Nested blocks always belong to the parameters.
+func newResourceModel(providerName, resourceType string, block *configschema.Block) *resourceModel {
+	kind := kindName(providerName, resourceType)
+	m := &resourceModel{
+		TFName:  resourceType,
+		Kind:    kind,
+		Package: packageName(kind),
+		Plural:  plural(kind),
+	}
+	// names taken by the generated Kind, its list and spec and status
+	b := &modelBuilder{names: map[string]bool{kind: true, kind + "List": true, kind + "Spec": true, kind + "Status": true}}
+	m.Parameters = b.newStruct(kind+"Parameters", fmt.Sprintf("%sParameters are the configurable fields of %s.", kind, article(kind)))
+	m.Observation = b.newStruct(kind+"Observation", fmt.Sprintf("%sObservation are the observable fields of %s.", kind, article(kind)))
+
+	for _, name := range sortedKeys(block.Attributes) {
+		attr := block.Attributes[name]
+		if name == idAttribute {
+			m.HasID = true
+			continue
+		}
+		if attr.Computed && !attr.Optional && !attr.Required {
+			addField(m.Observation, b.attributeField(name, attr, kind))
+			continue
+		}
+		addField(m.Parameters, b.attributeField(name, attr, kind))
+	}
+	for _, name := range sortedKeys(block.BlockTypes) {
+		addField(m.Parameters, b.blockField(name, block.BlockTypes[name], kind))
+	}
+	m.Structs = b.structs
+	return m
+}
+
+// newStruct registers a struct, suffixing its name if it is taken.
+func (b *modelBuilder) newStruct(name, comment string) *structType {
+	unique := name
+	for i := 2; b.names[unique]; i++ {
+		unique = fmt.Sprintf("%s%d", name, i)
+	}
+	b.names[unique] = true
+	st := &structType{Name: unique, Comment: strings.Replace(comment, name, unique, 1)}
+	b.structs = append(b.structs, st)
+	return st
+}
+
+func (b *modelBuilder) attributeField(name string, attr *configschema.Attribute, parent string) *field {
+	f := &field{
+		Name:        goName(name),
+		JSON:        jsonName(name),
+		TF:          name,
+		Type:        b.typeOf(attr.Type, parent+goName(name)),
+		Required:    attr.Required,
+		Description: attr.Description,
+	}
+	f.Pointer = !attr.Required && (f.Type.kind == kindPrimitive || f.Type.kind == kindStruct)
+	return f
+}
+
+func (b *modelBuilder) blockField(name string, nb *configschema.NestedBlock, parent string) *field {
+	st := b.newStruct(parent+goName(name), "")
+	st.Comment = fmt.Sprintf("%s is the %s block of %s.", st.Name, name, article(parent))
+	b.fillBlock(st, &nb.Block, st.Name)
+	f := &field{
+		Name:     goName(name),
+		JSON:     jsonName(name),
+		TF:       name,
+		Block:    true,
+		Required: nb.MinItems > 0,
+		MinItems: nb.MinItems,
+		MaxItems: nb.MaxItems,
+	}
+	elem := &goType{kind: kindStruct, strct: st}
+	switch nb.Nesting {
+	case configschema.NestingSingle:
+		f.Type, f.Pointer = elem, true
+	case configschema.NestingGroup:
+		f.Type = elem
+	case configschema.NestingList:
+		f.Type = &goType{kind: kindList, elem: elem}
+	case configschema.NestingSet:
+		f.Type = &goType{kind: kindSet, elem: elem}
+	case configschema.NestingMap:
+		f.Type = &goType{kind: kindMap, elem: elem}
+	}
+	return f
+}
+
+// fillBlock adds a field to st for every attribute and nested block.
+// Computed attributes of nested blocks stay with the parameters.
+func (b *modelBuilder) fillBlock(st *structType, block *configschema.Block, parent string) {
+	for _, name := range sortedKeys(block.Attributes) {
+		addField(st, b.attributeField(name, block.Attributes[name], parent))
+	}
+	for _, name := range sortedKeys(block.BlockTypes) {
+		addField(st, b.blockField(name, block.BlockTypes[name], parent))
+	}
+}
+
+// typeOf maps a cty type to Go. Numbers become float64, since terraform
+// does not tell integers apart, and dynamically typed values and tuples
+// become strings.
+func (b *modelBuilder) typeOf(ty cty.Type, name string) *goType {
+	switch {
+	case ty == cty.String:
+		return &goType{kind: kindPrimitive, primitive: "string"}
+	case ty == cty.Number:
+		return &goType{kind: kindPrimitive, primitive: "float64"}
+	case ty == cty.Bool:
+		return &goType{kind: kindPrimitive, primitive: "bool"}
+	case ty.IsListType():
+		return &goType{kind: kindList, elem: b.typeOf(ty.ElementType(), name)}
+	case ty.IsSetType():
+		return &goType{kind: kindSet, elem: b.typeOf(ty.ElementType(), name)}
+	case ty.IsMapType():
+		return &goType{kind: kindMap, elem: b.typeOf(ty.ElementType(), name)}
+	case ty.IsObjectType():
+		st := b.newStruct(name, "")
+		st.Comment = fmt.Sprintf("%s is an object attribute.", st.Name)
+		attrTypes := ty.AttributeTypes()
+		for _, attr := range sortedKeys(attrTypes) {
+			f := &field{
+				Name: goName(attr),
+				JSON: jsonName(attr),
+				TF:   attr,
+				Type: b.typeOf(attrTypes[attr], st.Name+goName(attr)),
+			}
+			f.Pointer = f.Type.kind == kindPrimitive || f.Type.kind == kindStruct
+			addField(st, f)
+		}
+		return &goType{kind: kindStruct, strct: st}
+	}
+	return &goType{kind: kindPrimitive, primitive: "string", dynamic: true}
+}
+
+// addField appends f to st, suffixing its Go and JSON names if distinct
+// terraform names map to the same ones.
+func addField(st *structType, f *field) {
+	name, json := f.Name, f.JSON
+	for i := 2; hasField(st, f.Name, f.JSON); i++ {
+		f.Name, f.JSON = fmt.Sprintf("%s%d", name, i), fmt.Sprintf("%s%d", json, i)
+	}
+	st.Fields = append(st.Fields, f)
+}
+
+func hasField(st *structType, name, json string) bool {
+	for _, f := range st.Fields {
+		if f.Name == name || f.JSON == json {
+			return true
+		}
+	}
+	return false
+}
+
+func sortedKeys(m interface{}) []string {
+	var keys []string
+	switch m := m.(type) {
+	case map[string]*configschema.Attribute:
+		for k := range m {
+			keys = append(keys, k)
+		}
+	case map[string]*configschema.NestedBlock:
+		for k := range m {
+			keys = append(keys, k)
+		}
+	case map[string]cty.Type:
+		for k := range m {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,93 @@
+package generator
+
+import (
+	"go/token"
+	"strings"
+	"unicode"
+)
+
+// initialisms are spelled in upper case in Go names, following golint.
+var initialisms = map[string]string{
+	"acl": "ACL", "api": "API", "arn": "ARN", "cidr": "CIDR", "cpu": "CPU",
+	"dns": "DNS", "gpu": "GPU", "http": "HTTP", "https": "HTTPS", "id": "ID",
+	"ip": "IP", "ipv4": "IPv4", "ipv6": "IPv6", "json": "JSON", "kms": "KMS",
+	"os": "OS", "sql": "SQL", "ssh": "SSH", "ssl": "SSL", "tcp": "TCP",
+	"tls": "TLS", "ttl": "TTL", "udp": "UDP", "uri": "URI", "url": "URL",
+	"uuid": "UUID", "vm": "VM", "vpc": "VPC", "vpn": "VPN",
+}
+
+// words splits a terraform name, eg network_interface, into its words.
+func words(name string) []string {
+	return strings.FieldsFunc(strings.ToLower(name), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// goName returns the exported Go name for a terraform name, eg
+// NetworkInterfaceID for network_interface_id.
+func goName(name string) string {
+	var b strings.Builder
+	for _, w := range words(name) {
+		if i, ok := initialisms[w]; ok {
+			b.WriteString(i)
+			continue
+		}
+		b.WriteString(strings.ToUpper(w[:1]) + w[1:])
+	}
+	s := b.String()
+	if s == "" || unicode.IsDigit(rune(s[0])) {
+		s = "X" + s
+	}
+	return s
+}
+
+// jsonName returns the lowerCamelCase JSON name for a terraform name, eg
+// networkInterfaceId for network_interface_id.
+func jsonName(name string) string {
+	ws := words(name)
+	if len(ws) == 0 {
+		return "x"
+	}
+	var b strings.Builder
+	b.WriteString(ws[0])
+	for _, w := range ws[1:] {
+		b.WriteString(strings.ToUpper(w[:1]) + w[1:])
+	}
+	return b.String()
+}
+
+// kindName returns the Kind for a terraform resource type, without the
+// provider prefix, eg ComputeInstance for google_compute_instance.
+func kindName(providerName, resourceType string) string {
+	return goName(strings.TrimPrefix(resourceType, providerName+"_"))
+}
+
+// packageName returns the Go package name for a Kind, eg computeinstance.
+func packageName(kind string) string {
+	name := strings.ToLower(kind)
+	if token.IsKeyword(name) {
+		name += "resource"
+	}
+	return name
+}
+
+// plural returns the lower case plural of a Kind, as used in CRD names.
+func plural(kind string) string {
+	name := strings.ToLower(kind)
+	switch {
+	case strings.HasSuffix(name, "s"), strings.HasSuffix(name, "x"), strings.HasSuffix(name, "ch"), strings.HasSuffix(name, "sh"):
+		return name + "es"
+	case strings.HasSuffix(name, "y") && len(name) > 1 && !strings.ContainsAny(name[len(name)-2:len(name)-1], "aeiou"):
+		return name[:len(name)-1] + "ies"
+	}
+	return name + "s"
+}
+
+// article prefixes a name with the indefinite article used in doc
+// comments, eg an Instance.
+func article(name string) string {
+	if name != "" && strings.ContainsAny(name[:1], "AEIOUaeiou") {
+		return "an " + name
+	}
+	return "a " + name
+}
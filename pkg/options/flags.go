@@ -0,0 +1,258 @@
+package options
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+// EnvPrefix prefixes the environment variable of every flag, eg
+// --pool-max-size can be set with TF_RUNTIME_POOL_MAX_SIZE.
+const EnvPrefix = "TF_RUNTIME_"
+
+// Flags are the command line flags, and their environment variables, that
+// override the config file. Only flags that are set override anything.
+type Flags struct {
+	configFile string
+	overrides  []func(*Options)
+}
+
+// RegisterFlags adds the runtime flags to a kingpin application. After the
+// application is parsed, Flags.Options returns the effective Options.
+func RegisterFlags(app *kingpin.Application) *Flags {
+	f := &Flags{}
+	app.Flag("config", "Path to a YAML config file with runtime options.").
+		Envar(envar("config")).StringVar(&f.configFile)
+
+	f.repeated(app, "plugin-dir", "Directory searched for provider plugins, in order. Repeatable.",
+		func(o *Options, dirs []string) { o.Plugins.Directories = dirs })
+	f.string(app, "plugin-mirror-dir", "Filesystem mirror provider plugins are installed from.",
+		func(o *Options) *string { return &o.Plugins.MirrorDirectory })
+	f.string(app, "plugin-lock-file", "Lock file pinning provider plugin versions and hashes.",
+		func(o *Options) *string { return &o.Plugins.LockFile })
+	f.keyValue(app, "plugin-version", "Version constraint for a provider plugin, as NAME=CONSTRAINT. Repeatable.",
+		func(o *Options) *map[string]string { return &o.Plugins.Versions })
+	f.keyValue(app, "plugin-log-level", "Log level for a provider plugin, as NAME=LEVEL, eg google=TRACE. Repeatable.",
+		func(o *Options) *map[string]string { return &o.Plugins.LogLevels })
+	f.keyValue(app, "terraform-version", "Terraform version reported to a provider, as NAME=VERSION. Repeatable.",
+		func(o *Options) *map[string]string { return &o.Plugins.TerraformVersions })
+
+	f.int(app, "pool-min-size", "Number of provider processes kept alive when idle.",
+		func(o *Options) *int { return &o.Pool.MinSize })
+	f.int(app, "pool-max-size", "Maximum number of provider processes.",
+		func(o *Options) *int { return &o.Pool.MaxSize })
+	f.duration(app, "pool-idle-timeout", "How long an idle provider process is kept alive. 0 keeps them forever.",
+		func(o *Options) *time.Duration { return &o.Pool.IdleTimeout.Duration })
+	f.duration(app, "pool-lease-leak-threshold", "How long a provider can be borrowed before it is reported as leaked.",
+		func(o *Options) *time.Duration { return &o.Pool.LeaseLeakThreshold.Duration })
+
+	f.bool(app, "debug", "Enable debug logging.",
+		func(o *Options) *bool { return &o.Logging.Debug }).Short('d')
+
+	f.duration(app, "sync-period", "Controller manager sync period.",
+		func(o *Options) *time.Duration { return &o.Manager.SyncPeriod.Duration })
+	f.string(app, "metrics-addr", "Address the metrics endpoint binds to.",
+		func(o *Options) *string { return &o.Manager.MetricsAddress })
+	f.bool(app, "leader-election", "Use leader election for the controller manager.",
+		func(o *Options) *bool { return &o.Manager.LeaderElection })
+	f.string(app, "leader-election-id", "Name of the leader election ConfigMap.",
+		func(o *Options) *string { return &o.Manager.LeaderElectionID })
+	f.string(app, "leader-election-namespace", "Namespace of the leader election ConfigMap.",
+		func(o *Options) *string { return &o.Manager.LeaderElectionNamespace })
+
+	f.string(app, "recording-mode", "Record provider traffic to, or replay it from, the recording file: record or replay.",
+		func(o *Options) *string { return &o.Recording.Mode })
+	f.string(app, "recording-file", "Golden file provider traffic is recorded to or replayed from.",
+		func(o *Options) *string { return &o.Recording.File })
+	f.bool(app, "recording-lenient", "Replay the next recorded interaction for a call whose request was not recorded exactly, instead of failing it.",
+		func(o *Options) *bool { return &o.Recording.Lenient })
+
+	f.keyValue(app, "rate-limit-provider", "Rate limit of the resources using a Provider object, as NAME=QPS[:BURST], or *=QPS[:BURST] for every other one. Repeatable.",
+		func(o *Options) *map[string]string { return &o.RateLimit.Providers })
+	f.keyValue(app, "rate-limit-resource", "Rate limit of a terraform resource type, as TYPE=QPS[:BURST], or *=QPS[:BURST] for every other one. Repeatable.",
+		func(o *Options) *map[string]string { return &o.RateLimit.ResourceTypes })
+
+	f.int(app, "max-concurrent-reconciles", "How many resources of a kind are reconciled at once.",
+		func(o *Options) *int { return &o.Reconcilers.MaxConcurrentReconciles })
+	f.duration(app, "poll-interval", "How often up to date resources are observed.",
+		func(o *Options) *time.Duration { return &o.Reconcilers.PollInterval.Duration })
+	f.resourceType(app, "resource-max-concurrent-reconciles", "Concurrent reconciles of a terraform resource type, as TYPE=N. Repeatable.",
+		func(r *ResourceReconcilerOptions, v string) error {
+			i, err := strconv.Atoi(v)
+			if err != nil {
+				return fmt.Errorf("expected an integer, got %q", v)
+			}
+			r.MaxConcurrentReconciles = i
+			return nil
+		})
+	f.resourceType(app, "resource-poll-interval", "Poll interval of a terraform resource type, as TYPE=DURATION. Repeatable.",
+		func(r *ResourceReconcilerOptions, v string) error {
+			d, err := time.ParseDuration(v)
+			if err != nil {
+				return fmt.Errorf("expected a duration, eg 30s, got %q", v)
+			}
+			r.PollInterval.Duration = d
+			return nil
+		})
+
+	f.string(app, "tracing-exporter", "Export OpenTelemetry spans to stdout or otlp. Tracing is off when unset.",
+		func(o *Options) *string { return &o.Tracing.Exporter })
+	f.string(app, "tracing-endpoint", "OTLP gRPC endpoint spans are exported to, eg localhost:4317.",
+		func(o *Options) *string { return &o.Tracing.Endpoint })
+	return f
+}
+
+// Options returns the default Options, overlaid with the config file if
+// one was given, then with the flags and environment variables that were
+// set. The result is validated.
+func (f *Flags) Options() (*Options, error) {
+	o := Default()
+	if f.configFile != "" {
+		if err := o.Load(f.configFile); err != nil {
+			return nil, err
+		}
+	}
+	for _, override := range f.overrides {
+		override(o)
+	}
+	if err := o.Validate(); err != nil {
+		return nil, err
+	}
+	return o, nil
+}
+
+func envar(flag string) string {
+	return EnvPrefix + strings.ToUpper(strings.Replace(flag, "-", "_", -1))
+}
+
+// setting is a kingpin.Value that records an override each time the flag
+// or its environment variable is set.
+type setting struct {
+	flags      *Flags
+	value      string
+	cumulative bool
+	boolean    bool
+	parse      func(string) (func(*Options), error)
+}
+
+func (s *setting) Set(v string) error {
+	apply, err := s.parse(v)
+	if err != nil {
+		return err
+	}
+	s.value = v
+	s.flags.overrides = append(s.flags.overrides, apply)
+	return nil
+}
+
+func (s *setting) String() string {
+	return s.value
+}
+
+// IsCumulative lets kingpin accept repeated flags.
+func (s *setting) IsCumulative() bool {
+	return s.cumulative
+}
+
+// IsBoolFlag lets kingpin accept --flag and --no-flag without a value.
+func (s *setting) IsBoolFlag() bool {
+	return s.boolean
+}
+
+func (f *Flags) flag(app *kingpin.Application, name, help string, s *setting) *kingpin.FlagClause {
+	s.flags = f
+	clause := app.Flag(name, help).Envar(envar(name))
+	clause.SetValue(s)
+	return clause
+}
+
+func (f *Flags) string(app *kingpin.Application, name, help string, field func(*Options) *string) {
+	f.flag(app, name, help, &setting{parse: func(v string) (func(*Options), error) {
+		return func(o *Options) { *field(o) = v }, nil
+	}})
+}
+
+func (f *Flags) int(app *kingpin.Application, name, help string, field func(*Options) *int) {
+	f.flag(app, name, help, &setting{parse: func(v string) (func(*Options), error) {
+		i, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("expected an integer, got %q", v)
+		}
+		return func(o *Options) { *field(o) = i }, nil
+	}})
+}
+
+func (f *Flags) bool(app *kingpin.Application, name, help string, field func(*Options) *bool) *kingpin.FlagClause {
+	return f.flag(app, name, help, &setting{boolean: true, parse: func(v string) (func(*Options), error) {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, fmt.Errorf("expected true or false, got %q", v)
+		}
+		return func(o *Options) { *field(o) = b }, nil
+	}})
+}
+
+func (f *Flags) duration(app *kingpin.Application, name, help string, field func(*Options) *time.Duration) {
+	f.flag(app, name, help, &setting{parse: func(v string) (func(*Options), error) {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return nil, fmt.Errorf("expected a duration, eg 30s, got %q", v)
+		}
+		return func(o *Options) { *field(o) = d }, nil
+	}})
+}
+
+// repeated collects every value of a repeatable flag. The first value
+// replaces the list from the config file rather than appending to it.
+func (f *Flags) repeated(app *kingpin.Application, name, help string, set func(*Options, []string)) {
+	values := make([]string, 0)
+	f.flag(app, name, help, &setting{cumulative: true, parse: func(v string) (func(*Options), error) {
+		values = append(values, v)
+		current := append([]string{}, values...)
+		return func(o *Options) { set(o, current) }, nil
+	}})
+}
+
+// keyValue sets one entry of a map per NAME=VALUE flag, on top of the
+// entries from the config file.
+func (f *Flags) keyValue(app *kingpin.Application, name, help string, field func(*Options) *map[string]string) {
+	f.flag(app, name, help, &setting{cumulative: true, parse: func(v string) (func(*Options), error) {
+		kv := strings.SplitN(v, "=", 2)
+		if len(kv) != 2 || kv[0] == "" {
+			return nil, fmt.Errorf("expected NAME=VALUE, got %q", v)
+		}
+		return func(o *Options) {
+			m := field(o)
+			if *m == nil {
+				*m = make(map[string]string)
+			}
+			(*m)[kv[0]] = kv[1]
+		}, nil
+	}})
+}
+
+// resourceType sets one field of the reconciler overrides of a terraform
+// resource type per TYPE=VALUE flag, keeping the other fields from the
+// config file.
+func (f *Flags) resourceType(app *kingpin.Application, name, help string, set func(*ResourceReconcilerOptions, string) error) {
+	f.flag(app, name, help, &setting{cumulative: true, parse: func(v string) (func(*Options), error) {
+		kv := strings.SplitN(v, "=", 2)
+		if len(kv) != 2 || kv[0] == "" {
+			return nil, fmt.Errorf("expected TYPE=VALUE, got %q", v)
+		}
+		if err := set(&ResourceReconcilerOptions{}, kv[1]); err != nil {
+			return nil, err
+		}
+		return func(o *Options) {
+			if o.Reconcilers.ResourceTypes == nil {
+				o.Reconcilers.ResourceTypes = make(map[string]ResourceReconcilerOptions)
+			}
+			r := o.Reconcilers.ResourceTypes[kv[0]]
+			_ = set(&r, kv[1])
+			o.Reconcilers.ResourceTypes[kv[0]] = r
+		}, nil
+	}})
+}
@@ -0,0 +1,331 @@
+// Package options assembles the settings of a terraform provider runtime
+// from defaults, a YAML config file, environment variables and flags, in
+// increasing order of precedence.
+package options
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/terraform/plugin/discovery"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/yaml"
+
+	"github.com/crossplane/terraform-provider-runtime/pkg/client"
+	"github.com/crossplane/terraform-provider-runtime/pkg/client/recording"
+	"github.com/crossplane/terraform-provider-runtime/pkg/plugin"
+	"github.com/crossplane/terraform-provider-runtime/pkg/tracing"
+)
+
+// Options are all the settings of a provider runtime. The YAML config file
+// has the same structure.
+type Options struct {
+	Plugins     PluginOptions     `json:"plugins"`
+	Pool        PoolOptions       `json:"pool"`
+	Logging     LoggingOptions    `json:"logging"`
+	Manager     ManagerOptions    `json:"manager"`
+	Recording   RecordingOptions  `json:"recording,omitempty"`
+	Tracing     TracingOptions    `json:"tracing,omitempty"`
+	RateLimit   RateLimitOptions  `json:"rateLimit,omitempty"`
+	Reconcilers ReconcilerOptions `json:"reconcilers"`
+}
+
+// PluginOptions configure how provider plugins are found and run.
+type PluginOptions struct {
+	// Directories are searched for provider plugins, in order.
+	Directories []string `json:"directories"`
+	// MirrorDirectory is a filesystem mirror plugins are installed from.
+	MirrorDirectory string `json:"mirrorDirectory,omitempty"`
+	// LockFile pins plugin versions and hashes.
+	LockFile string `json:"lockFile,omitempty"`
+	// Versions maps provider names to version constraints.
+	Versions map[string]string `json:"versions,omitempty"`
+	// LogLevels maps provider names to TF_LOG style plugin log levels.
+	LogLevels map[string]string `json:"logLevels,omitempty"`
+	// TerraformVersions maps provider names to the terraform version
+	// reported to them.
+	TerraformVersions map[string]string `json:"terraformVersions,omitempty"`
+}
+
+// PoolOptions size the ProviderPool.
+type PoolOptions struct {
+	MinSize            int             `json:"minSize"`
+	MaxSize            int             `json:"maxSize"`
+	IdleTimeout        metav1.Duration `json:"idleTimeout"`
+	LeaseLeakThreshold metav1.Duration `json:"leaseLeakThreshold"`
+}
+
+// LoggingOptions configure the controller's own logs. Plugin log levels are
+// set per provider in PluginOptions.
+type LoggingOptions struct {
+	Debug bool `json:"debug"`
+}
+
+// ManagerOptions configure the controller manager.
+type ManagerOptions struct {
+	SyncPeriod              metav1.Duration `json:"syncPeriod"`
+	MetricsAddress          string          `json:"metricsAddress"`
+	LeaderElection          bool            `json:"leaderElection"`
+	LeaderElectionID        string          `json:"leaderElectionID,omitempty"`
+	LeaderElectionNamespace string          `json:"leaderElectionNamespace,omitempty"`
+}
+
+// RecordingOptions switch providers to recording or replaying their
+// traffic; see pkg/client/recording.
+type RecordingOptions struct {
+	Mode string `json:"mode,omitempty"`
+	File string `json:"file,omitempty"`
+	// Lenient replays the next recorded interaction for a call when no
+	// recorded request matches exactly.
+	Lenient bool `json:"lenient,omitempty"`
+}
+
+// TracingOptions configure where OpenTelemetry spans are exported to.
+type TracingOptions struct {
+	// Exporter is stdout or otlp. Spans are not recorded when it is empty.
+	Exporter string `json:"exporter,omitempty"`
+	// Endpoint is the OTLP gRPC endpoint, defaulting to a local collector.
+	Endpoint string `json:"endpoint,omitempty"`
+}
+
+// RateLimitOptions are token-bucket limits on how often resources are
+// reconciled, written as QPS or QPS:BURST. The * key sets the limit of
+// every Provider object or resource type without one of its own.
+type RateLimitOptions struct {
+	// Providers maps Provider object names to rate limits.
+	Providers map[string]string `json:"providers,omitempty"`
+	// ResourceTypes maps terraform resource types to rate limits.
+	ResourceTypes map[string]string `json:"resourceTypes,omitempty"`
+}
+
+// ReconcilerOptions configure the controller of every resource type.
+type ReconcilerOptions struct {
+	// MaxConcurrentReconciles is how many resources of a kind are
+	// reconciled at once.
+	MaxConcurrentReconciles int `json:"maxConcurrentReconciles"`
+	// PollInterval is how often up to date resources are observed.
+	PollInterval metav1.Duration `json:"pollInterval"`
+	// ResourceTypes maps terraform resource types to the settings that
+	// differ from the ones above.
+	ResourceTypes map[string]ResourceReconcilerOptions `json:"resourceTypes,omitempty"`
+}
+
+// ResourceReconcilerOptions override ReconcilerOptions for one resource
+// type. Unset fields are inherited.
+type ResourceReconcilerOptions struct {
+	MaxConcurrentReconciles int             `json:"maxConcurrentReconciles,omitempty"`
+	PollInterval            metav1.Duration `json:"pollInterval,omitempty"`
+}
+
+// Default returns the Options used when nothing else is configured.
+func Default() *Options {
+	return &Options{
+		Plugins: PluginOptions{
+			Directories: []string{client.DefaultPluginDirectory},
+		},
+		Pool: PoolOptions{
+			MaxSize:            client.DefaultProviderPoolSize,
+			LeaseLeakThreshold: metav1.Duration{Duration: client.DefaultLeaseLeakThreshold},
+		},
+		Manager: ManagerOptions{
+			SyncPeriod:     metav1.Duration{Duration: time.Hour},
+			MetricsAddress: ":8080",
+		},
+		Reconcilers: ReconcilerOptions{
+			MaxConcurrentReconciles: 1,
+			PollInterval:            metav1.Duration{Duration: plugin.DefaultPollInterval},
+		},
+	}
+}
+
+// Load overlays the YAML config file at path onto the Options. Unknown
+// fields are rejected, so typos do not silently fall back to defaults.
+func (o *Options) Load(path string) error {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("Failed to read config file %s: %s", path, err)
+	}
+	if err := yaml.UnmarshalStrict(content, o); err != nil {
+		return fmt.Errorf("Failed to parse config file %s: %s", path, err)
+	}
+	return nil
+}
+
+// Validate checks the Options, reporting every problem at once.
+func (o *Options) Validate() error {
+	problems := make([]string, 0)
+	report := func(format string, args ...interface{}) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+
+	if len(o.Plugins.Directories) == 0 {
+		report("plugins.directories must list at least one directory")
+	}
+	for _, dir := range o.Plugins.Directories {
+		if strings.TrimSpace(dir) == "" {
+			report("plugins.directories must not contain empty paths")
+		}
+	}
+	for name, c := range o.Plugins.Versions {
+		if _, err := discovery.ConstraintStr(c).Parse(); err != nil {
+			report("plugins.versions.%s: invalid version constraint %q: %s", name, c, err)
+		}
+	}
+	for name, level := range o.Plugins.LogLevels {
+		if _, err := client.ParsePluginLogLevel(level); err != nil {
+			report("plugins.logLevels.%s: %s", name, err)
+		}
+	}
+	for name, v := range o.Plugins.TerraformVersions {
+		if _, err := discovery.VersionStr(v).Parse(); err != nil {
+			report("plugins.terraformVersions.%s: invalid version %q: %s", name, v, err)
+		}
+	}
+
+	if o.Pool.MaxSize < 1 {
+		report("pool.maxSize must be at least 1, got %d", o.Pool.MaxSize)
+	}
+	if o.Pool.MinSize < 0 || o.Pool.MinSize > o.Pool.MaxSize {
+		report("pool.minSize must be between 0 and pool.maxSize (%d), got %d", o.Pool.MaxSize, o.Pool.MinSize)
+	}
+	if o.Pool.IdleTimeout.Duration < 0 {
+		report("pool.idleTimeout must not be negative")
+	}
+	if o.Pool.LeaseLeakThreshold.Duration < 0 {
+		report("pool.leaseLeakThreshold must not be negative")
+	}
+
+	if o.Manager.SyncPeriod.Duration <= 0 {
+		report("manager.syncPeriod must be positive")
+	}
+	if o.Manager.LeaderElection && o.Manager.LeaderElectionID == "" {
+		report("manager.leaderElectionID is required when leader election is enabled")
+	}
+
+	switch o.Recording.Mode {
+	case "":
+	case recording.ModeRecord, recording.ModeReplay:
+		if o.Recording.File == "" {
+			report("recording.file is required in recording mode %s", o.Recording.Mode)
+		}
+	default:
+		report("recording.mode must be %q or %q, got %q", recording.ModeRecord, recording.ModeReplay, o.Recording.Mode)
+	}
+
+	switch o.Tracing.Exporter {
+	case tracing.ExporterNone, tracing.ExporterStdout, tracing.ExporterOTLP:
+	default:
+		report("tracing.exporter must be %q or %q, got %q", tracing.ExporterStdout, tracing.ExporterOTLP, o.Tracing.Exporter)
+	}
+
+	for name, l := range o.RateLimit.Providers {
+		if _, err := client.ParseRateLimit(l); err != nil {
+			report("rateLimit.providers.%s: %s", name, err)
+		}
+	}
+	for name, l := range o.RateLimit.ResourceTypes {
+		if _, err := client.ParseRateLimit(l); err != nil {
+			report("rateLimit.resourceTypes.%s: %s", name, err)
+		}
+	}
+
+	if o.Reconcilers.MaxConcurrentReconciles < 1 {
+		report("reconcilers.maxConcurrentReconciles must be at least 1, got %d", o.Reconcilers.MaxConcurrentReconciles)
+	}
+	if o.Reconcilers.PollInterval.Duration <= 0 {
+		report("reconcilers.pollInterval must be positive")
+	}
+	for name, r := range o.Reconcilers.ResourceTypes {
+		if r.MaxConcurrentReconciles < 0 {
+			report("reconcilers.resourceTypes.%s.maxConcurrentReconciles must not be negative", name)
+		}
+		if r.PollInterval.Duration < 0 {
+			report("reconcilers.resourceTypes.%s.pollInterval must not be negative", name)
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("Invalid options:\n  %s", strings.Join(problems, "\n  "))
+	}
+	return nil
+}
+
+// Dump returns the effective Options as YAML, in the format of the config
+// file.
+func (o *Options) Dump() (string, error) {
+	b, err := yaml.Marshal(o)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// RuntimeOptions returns the client.RuntimeOptions for the provider pool
+// and plugins.
+func (o *Options) RuntimeOptions() *client.RuntimeOptions {
+	ropts := client.NewRuntimeOptions().
+		WithPoolSize(o.Pool.MaxSize).
+		WithMinPoolSize(o.Pool.MinSize).
+		WithIdleTimeout(o.Pool.IdleTimeout.Duration).
+		WithLeaseLeakThreshold(o.Pool.LeaseLeakThreshold.Duration).
+		WithPluginMirrorDirectory(o.Plugins.MirrorDirectory).
+		WithPluginLockFile(o.Plugins.LockFile).
+		WithRecording(o.Recording.Mode, o.Recording.File).
+		WithLenientReplay(o.Recording.Lenient)
+	if len(o.Plugins.Directories) > 0 {
+		ropts.WithPluginDirectory(o.Plugins.Directories[0]).WithPluginDirectories(o.Plugins.Directories[1:]...)
+	}
+	for name, c := range o.Plugins.Versions {
+		ropts.WithVersionConstraint(name, c)
+	}
+	for name, level := range o.Plugins.LogLevels {
+		ropts.WithPluginLogLevel(name, level)
+	}
+	for name, v := range o.Plugins.TerraformVersions {
+		ropts.WithTerraformVersion(name, v)
+	}
+	// the limits were validated
+	for name, l := range o.RateLimit.Providers {
+		limit, _ := client.ParseRateLimit(l)
+		ropts.WithProviderRateLimit(name, limit)
+	}
+	for name, l := range o.RateLimit.ResourceTypes {
+		limit, _ := client.ParseRateLimit(l)
+		ropts.WithResourceRateLimit(name, limit)
+	}
+	return ropts
+}
+
+// ManagerOptions returns the controller manager options.
+func (o *Options) ManagerOptions() ctrl.Options {
+	syncPeriod := o.Manager.SyncPeriod.Duration
+	return ctrl.Options{
+		SyncPeriod:              &syncPeriod,
+		MetricsBindAddress:      o.Manager.MetricsAddress,
+		LeaderElection:          o.Manager.LeaderElection,
+		LeaderElectionID:        o.Manager.LeaderElectionID,
+		LeaderElectionNamespace: o.Manager.LeaderElectionNamespace,
+	}
+}
+
+// ReconcilerSettings returns the plugin.ReconcilerSettings every
+// ReconcilerConfigurer is given its options from.
+func (o *Options) ReconcilerSettings() plugin.ReconcilerSettings {
+	settings := plugin.ReconcilerSettings{
+		Default: plugin.ReconcilerOptions{
+			MaxConcurrentReconciles: o.Reconcilers.MaxConcurrentReconciles,
+			PollInterval:            o.Reconcilers.PollInterval.Duration,
+		},
+		ResourceTypes: make(map[string]plugin.ReconcilerOptions),
+	}
+	for name, r := range o.Reconcilers.ResourceTypes {
+		settings.ResourceTypes[name] = plugin.ReconcilerOptions{
+			MaxConcurrentReconciles: r.MaxConcurrentReconciles,
+			PollInterval:            r.PollInterval.Duration,
+		}
+	}
+	return settings
+}
@@ -0,0 +1,124 @@
+package options
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+func TestPrecedence(t *testing.T) {
+	dir, err := ioutil.TempDir("", "runtime-options")
+	if err != nil {
+		t.Fatalf("Unexpected error creating temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+	config := filepath.Join(dir, "config.yaml")
+	content := `
+plugins:
+  directories: [/from/file]
+  logLevels:
+    aws: DEBUG
+pool:
+  maxSize: 4
+  idleTimeout: 10m
+manager:
+  metricsAddress: ":9090"
+`
+	if err := ioutil.WriteFile(config, []byte(content), 0644); err != nil {
+		t.Fatalf("Unexpected error writing config: %s", err)
+	}
+	os.Setenv(envar("pool-max-size"), "6")
+	defer os.Unsetenv(envar("pool-max-size"))
+
+	app := kingpin.New("test", "")
+	flags := RegisterFlags(app)
+	_, err = app.Parse([]string{
+		"--config", config,
+		"--plugin-dir", "/a", "--plugin-dir", "/b",
+		"--plugin-log-level", "google=TRACE",
+		"--pool-idle-timeout", "1m",
+		"-d",
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error parsing flags: %s", err)
+	}
+	o, err := flags.Options()
+	if err != nil {
+		t.Fatalf("Unexpected error building options: %s", err)
+	}
+
+	if got := strings.Join(o.Plugins.Directories, ","); got != "/a,/b" {
+		t.Errorf("Expected repeated flags to replace the file's directories, got %s", got)
+	}
+	if o.Plugins.LogLevels["aws"] != "DEBUG" || o.Plugins.LogLevels["google"] != "TRACE" {
+		t.Errorf("Expected log levels from the file and flags to be merged, got %v", o.Plugins.LogLevels)
+	}
+	if o.Pool.MaxSize != 6 {
+		t.Errorf("Expected the environment to override the file's pool size, got %d", o.Pool.MaxSize)
+	}
+	if o.Pool.IdleTimeout.Duration != time.Minute {
+		t.Errorf("Expected the flag to override the file's idle timeout, got %s", o.Pool.IdleTimeout.Duration)
+	}
+	if o.Manager.MetricsAddress != ":9090" || o.Manager.SyncPeriod.Duration != time.Hour {
+		t.Errorf("Expected file values on top of defaults, got %+v", o.Manager)
+	}
+	if !o.Logging.Debug {
+		t.Errorf("Expected -d to enable debug logging")
+	}
+}
+
+func TestValidateReportsEveryProblem(t *testing.T) {
+	o := Default()
+	o.Pool.MaxSize = 0
+	o.Plugins.LogLevels = map[string]string{"google": "LOUD"}
+	o.Recording.Mode = "rewind"
+	o.Tracing.Exporter = "jaeger"
+	o.RateLimit.ResourceTypes = map[string]string{"google_compute_instance": "fast"}
+	o.Reconcilers.MaxConcurrentReconciles = 0
+	err := o.Validate()
+	if err == nil {
+		t.Fatalf("Expected invalid options to fail validation")
+	}
+	for _, field := range []string{"pool.maxSize", "plugins.logLevels.google", "recording.mode", "tracing.exporter", "rateLimit.resourceTypes.google_compute_instance", "reconcilers.maxConcurrentReconciles"} {
+		if !strings.Contains(err.Error(), field) {
+			t.Errorf("Expected a problem with %s to be reported, got:\n%s", field, err)
+		}
+	}
+	if err := Default().Validate(); err != nil {
+		t.Errorf("Expected the defaults to be valid, got %s", err)
+	}
+}
+
+func TestReconcilerSettings(t *testing.T) {
+	app := kingpin.New("test", "")
+	flags := RegisterFlags(app)
+	_, err := app.Parse([]string{
+		"--max-concurrent-reconciles", "4",
+		"--resource-max-concurrent-reconciles", "google_compute_instance=10",
+		"--resource-poll-interval", "google_compute_instance=5m",
+		"--resource-poll-interval", "google_storage_bucket=30s",
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error parsing flags: %s", err)
+	}
+	o, err := flags.Options()
+	if err != nil {
+		t.Fatalf("Unexpected error building options: %s", err)
+	}
+	settings := o.ReconcilerSettings()
+
+	if got := settings.For("google_compute_instance"); got.MaxConcurrentReconciles != 10 || got.PollInterval != 5*time.Minute {
+		t.Errorf("Expected both overrides of a resource type to apply, got %+v", got)
+	}
+	if got := settings.For("google_storage_bucket"); got.MaxConcurrentReconciles != 4 || got.PollInterval != 30*time.Second {
+		t.Errorf("Expected unset overrides to fall back to the default, got %+v", got)
+	}
+	if got := settings.For("google_compute_network"); got.MaxConcurrentReconciles != 4 || got.PollInterval != time.Minute {
+		t.Errorf("Expected resource types without overrides to use the default, got %+v", got)
+	}
+}
@@ -11,4 +11,7 @@ type MergeDescription struct {
 	StatusUpdated       bool
 	AnnotationsUpdated  bool
 	NeedsProviderUpdate bool
+	// LateInitializedPaths lists the attributes that were late-initialized,
+	// if the ResourceMerger reports them.
+	LateInitializedPaths []string
 }
@@ -1,11 +1,62 @@
 package plugin
 
 import (
+	"time"
+
 	"github.com/crossplane/crossplane-runtime/pkg/logging"
 	"github.com/crossplane/terraform-provider-runtime/pkg/client"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
 )
 
 type ReconcilerConfigurer interface {
-	ConfigureReconciler(ctrl.Manager, logging.Logger, *Index, *client.ProviderPool) error
+	ConfigureReconciler(ctrl.Manager, logging.Logger, *Index, *client.ProviderPool, ReconcilerOptions) error
+}
+
+// DefaultPollInterval is how often up to date resources are observed when
+// no poll interval is configured.
+var DefaultPollInterval = time.Minute
+
+// ReconcilerOptions are the runtime settings a ReconcilerConfigurer applies
+// to the controller it sets up.
+type ReconcilerOptions struct {
+	// MaxConcurrentReconciles is how many resources of the kind are
+	// reconciled at once.
+	MaxConcurrentReconciles int
+	// PollInterval is how often up to date resources are observed.
+	PollInterval time.Duration
+}
+
+// ControllerOptions returns the controller-runtime options for o.
+func (o ReconcilerOptions) ControllerOptions() controller.Options {
+	return controller.Options{MaxConcurrentReconciles: o.MaxConcurrentReconciles}
+}
+
+// ReconcilerSettings are the ReconcilerOptions of every resource type.
+type ReconcilerSettings struct {
+	// Default applies to every resource type.
+	Default ReconcilerOptions
+	// ResourceTypes overrides the set fields of Default per terraform
+	// resource type.
+	ResourceTypes map[string]ReconcilerOptions
+}
+
+// For returns the ReconcilerOptions of a terraform resource type.
+func (s ReconcilerSettings) For(resourceType string) ReconcilerOptions {
+	o := s.Default
+	if override, ok := s.ResourceTypes[resourceType]; ok {
+		if override.MaxConcurrentReconciles > 0 {
+			o.MaxConcurrentReconciles = override.MaxConcurrentReconciles
+		}
+		if override.PollInterval > 0 {
+			o.PollInterval = override.PollInterval
+		}
+	}
+	if o.MaxConcurrentReconciles < 1 {
+		o.MaxConcurrentReconciles = 1
+	}
+	if o.PollInterval <= 0 {
+		o.PollInterval = DefaultPollInterval
+	}
+	return o
 }
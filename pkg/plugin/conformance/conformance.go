@@ -0,0 +1,226 @@
+// Package conformance checks that the CtyEncoder, CtyDecoder and
+// ResourceMerger of an Implementation agree with each other and with the
+// provider schema. Providers run it against their generated Implementations
+// as a table test:
+//
+//	func TestConformance(t *testing.T) {
+//		conformance.Run(t,
+//			conformance.Case{Implementation: compute.Implementation(), Schema: computeSchema},
+//		)
+//	}
+package conformance
+
+import (
+	"fmt"
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+	"github.com/hashicorp/terraform/providers"
+	"github.com/zclconf/go-cty/cty"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/crossplane/terraform-provider-runtime/pkg/plugin"
+)
+
+// DefaultIterations is the number of random values each Case is checked
+// against when it does not set Iterations.
+const DefaultIterations = 25
+
+// Case is an Implementation to check, along with the provider schema for
+// its terraform resource.
+type Case struct {
+	// Name of the subtest, defaulting to the Implementation's GVK.
+	Name           string
+	Implementation *plugin.Implementation
+	Schema         *providers.Schema
+	// New returns an empty managed resource to decode into. It defaults to
+	// creating the Implementation's GVK from its SchemeBuilder.
+	New func() (resource.Managed, error)
+	// Iterations is the number of random values checked.
+	Iterations int
+	// Seed seeds the random values, so that failures are reproducible.
+	Seed int64
+}
+
+// Run checks every Case in its own subtest.
+func Run(t *testing.T, cases ...Case) {
+	for _, c := range cases {
+		c := c
+		name := c.Name
+		if name == "" {
+			name = c.Implementation.GVK.String()
+		}
+		t.Run(name, func(t *testing.T) {
+			if err := c.Check(); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+// Check runs all conformance checks for the Case against random values,
+// returning the first failure.
+func (c Case) Check() error {
+	impl := c.Implementation
+	if impl.CtyEncoder == nil || impl.CtyDecoder == nil || impl.ResourceMerger == nil {
+		return fmt.Errorf("Implementation for %s needs a CtyEncoder, CtyDecoder and ResourceMerger to be checked", impl.GVK)
+	}
+	newResource := c.New
+	if newResource == nil {
+		newResource = schemeNew(impl)
+	}
+	iterations := c.Iterations
+	if iterations <= 0 {
+		iterations = DefaultIterations
+	}
+	r := rand.New(rand.NewSource(c.Seed))
+	for i := 0; i < iterations; i++ {
+		v := RandomValue(r, c.Schema.Block)
+		if err := CheckRoundtrip(impl, c.Schema, newResource, v); err != nil {
+			return fmt.Errorf("iteration %d: %s", i, err)
+		}
+		observed := RandomValue(r, c.Schema.Block)
+		if err := CheckMergeIdempotent(impl, c.Schema, newResource, v, observed); err != nil {
+			return fmt.Errorf("iteration %d: %s", i, err)
+		}
+	}
+	return nil
+}
+
+// CheckRoundtrip decodes v, then checks that encoding conforms to the
+// schema's implied type, keeps the primitive values of v, and that decoding
+// and encoding again is lossless.
+func CheckRoundtrip(impl *plugin.Implementation, s *providers.Schema, newResource func() (resource.Managed, error), v cty.Value) error {
+	first, err := decodeEncode(impl, s, newResource, v)
+	if err != nil {
+		return err
+	}
+	if errs := first.Type().TestConformance(s.Block.ImpliedType()); len(errs) > 0 {
+		return fmt.Errorf("CtyEncoder output does not conform to the schema of %s: %v", impl.TerraformResourceName, errs)
+	}
+	if err := checkPrimitivesKept(v, first); err != nil {
+		return fmt.Errorf("decode->encode loses a value of %s: %s", impl.TerraformResourceName, err)
+	}
+	second, err := decodeEncode(impl, s, newResource, first)
+	if err != nil {
+		return err
+	}
+	if !first.RawEquals(second) {
+		return fmt.Errorf("encode->decode->encode is not a roundtrip for %s:\nfirst:  %s\nsecond: %s", impl.TerraformResourceName, first.GoString(), second.GoString())
+	}
+	return nil
+}
+
+// checkPrimitivesKept checks that every known string, number and bool in
+// v is found unchanged at the same path in encoded. Values inside sets are
+// skipped, since their paths change with their elements.
+func checkPrimitivesKept(v, encoded cty.Value) error {
+	return cty.Walk(v, func(path cty.Path, pv cty.Value) (bool, error) {
+		if pv.IsNull() || !pv.IsKnown() {
+			return false, nil
+		}
+		if pv.Type().IsSetType() {
+			return false, nil
+		}
+		if !pv.Type().IsPrimitiveType() {
+			return true, nil
+		}
+		ev, err := path.Apply(encoded)
+		if err != nil {
+			return false, fmt.Errorf("%s was %s, and is missing from the encoded value", formatPath(path), pv.GoString())
+		}
+		if !ev.RawEquals(pv) {
+			return false, fmt.Errorf("%s was %s, encoded as %s", formatPath(path), pv.GoString(), ev.GoString())
+		}
+		return false, nil
+	})
+}
+
+func formatPath(path cty.Path) string {
+	s := ""
+	for _, step := range path {
+		switch step := step.(type) {
+		case cty.GetAttrStep:
+			s += "." + step.Name
+		case cty.IndexStep:
+			s += "[" + step.Key.GoString() + "]"
+		}
+	}
+	return s
+}
+
+// CheckMergeIdempotent decodes local and observed, merges them, and checks
+// that merging the same observed state again changes nothing.
+func CheckMergeIdempotent(impl *plugin.Implementation, s *providers.Schema, newResource func() (resource.Managed, error), local, observed cty.Value) error {
+	localRes, err := decode(impl, s, newResource, local)
+	if err != nil {
+		return err
+	}
+	observedRes, err := decode(impl, s, newResource, observed)
+	if err != nil {
+		return err
+	}
+	mc := plugin.MergeContext{Schema: s, Encoder: impl.CtyEncoder, Decoder: impl.CtyDecoder}
+	first := plugin.MergeInContext(impl.ResourceMerger, localRes, observedRes, mc)
+	merged := localRes.DeepCopyObject()
+	second := plugin.MergeInContext(impl.ResourceMerger, localRes, observedRes, mc)
+	if second.LateInitializedSpec || second.StatusUpdated || second.AnnotationsUpdated {
+		return fmt.Errorf("MergeResources for %s is not idempotent, second merge reported %+v", impl.TerraformResourceName, second)
+	}
+	if second.NeedsProviderUpdate != first.NeedsProviderUpdate {
+		return fmt.Errorf("MergeResources for %s is not idempotent, NeedsProviderUpdate changed from %t to %t", impl.TerraformResourceName, first.NeedsProviderUpdate, second.NeedsProviderUpdate)
+	}
+	if !reflect.DeepEqual(merged, localRes) {
+		return fmt.Errorf("MergeResources for %s is not idempotent, second merge changed the resource", impl.TerraformResourceName)
+	}
+	return nil
+}
+
+func decode(impl *plugin.Implementation, s *providers.Schema, newResource func() (resource.Managed, error), v cty.Value) (resource.Managed, error) {
+	base, err := newResource()
+	if err != nil {
+		return nil, err
+	}
+	res, err := impl.CtyDecoder.DecodeCty(base, v, s)
+	if err != nil {
+		return nil, fmt.Errorf("CtyDecoder failed for %s: %s", impl.TerraformResourceName, err)
+	}
+	return res, nil
+}
+
+func decodeEncode(impl *plugin.Implementation, s *providers.Schema, newResource func() (resource.Managed, error), v cty.Value) (cty.Value, error) {
+	res, err := decode(impl, s, newResource, v)
+	if err != nil {
+		return cty.NilVal, err
+	}
+	encoded, err := impl.CtyEncoder.EncodeCty(res, s)
+	if err != nil {
+		return cty.NilVal, fmt.Errorf("CtyEncoder failed for %s: %s", impl.TerraformResourceName, err)
+	}
+	return encoded, nil
+}
+
+// schemeNew creates empty resources of the Implementation's GVK from the
+// scheme its SchemeBuilder registers.
+func schemeNew(impl *plugin.Implementation) func() (resource.Managed, error) {
+	return func() (resource.Managed, error) {
+		if impl.SchemeBuilder == nil {
+			return nil, fmt.Errorf("Implementation for %s has no SchemeBuilder, set Case.New", impl.GVK)
+		}
+		scheme := runtime.NewScheme()
+		if err := impl.SchemeBuilder.AddToScheme(scheme); err != nil {
+			return nil, err
+		}
+		obj, err := scheme.New(impl.GVK)
+		if err != nil {
+			return nil, err
+		}
+		res, ok := obj.(resource.Managed)
+		if !ok {
+			return nil, fmt.Errorf("%s is not a managed resource", impl.GVK)
+		}
+		return res, nil
+	}
+}
@@ -0,0 +1,137 @@
+package conformance
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+	"github.com/crossplane/crossplane-runtime/pkg/resource/fake"
+	"github.com/hashicorp/terraform/configs/configschema"
+	"github.com/hashicorp/terraform/providers"
+	"github.com/zclconf/go-cty/cty"
+	k8schema "k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/crossplane/terraform-provider-runtime/pkg/plugin"
+)
+
+var attributeNames = []string{"id", "name", "zone"}
+
+func schemaFixture() *providers.Schema {
+	return &providers.Schema{Block: &configschema.Block{
+		Attributes: map[string]*configschema.Attribute{
+			"id":   {Type: cty.String, Computed: true},
+			"name": {Type: cty.String, Required: true},
+			"zone": {Type: cty.String, Optional: true, Computed: true},
+		},
+	}}
+}
+
+// annotationResource keeps every attribute of the fake resource in an
+// annotation of the same name.
+type annotationResource struct {
+	zoneType cty.Type
+	// appendOnMerge makes MergeResources change the resource every time
+	appendOnMerge bool
+	// truncateZone makes DecodeCty keep only the first letter of the zone
+	truncateZone bool
+}
+
+func (a annotationResource) EncodeCty(r resource.Managed, s *providers.Schema) (cty.Value, error) {
+	attrs := make(map[string]cty.Value)
+	for _, name := range attributeNames {
+		attrs[name] = cty.NullVal(cty.String)
+		if v, ok := r.GetAnnotations()[name]; ok {
+			attrs[name] = cty.StringVal(v)
+		}
+	}
+	if a.zoneType != cty.NilType {
+		attrs["zone"] = cty.NullVal(a.zoneType)
+	}
+	return cty.ObjectVal(attrs), nil
+}
+
+func (a annotationResource) DecodeCty(r resource.Managed, v cty.Value, s *providers.Schema) (resource.Managed, error) {
+	decoded := r.DeepCopyObject().(resource.Managed)
+	annotations := map[string]string{}
+	for _, name := range attributeNames {
+		if attr := v.GetAttr(name); !attr.IsNull() {
+			annotations[name] = attr.AsString()
+		}
+	}
+	if zone := annotations["zone"]; a.truncateZone && len(zone) > 1 {
+		annotations["zone"] = zone[:1]
+	}
+	decoded.SetAnnotations(annotations)
+	return decoded, nil
+}
+
+func (a annotationResource) MergeResources(local resource.Managed, observed resource.Managed) plugin.MergeDescription {
+	md := plugin.MergeDescription{}
+	annotations := local.GetAnnotations()
+	if zone, ok := observed.GetAnnotations()["zone"]; ok && (annotations["zone"] == "" || a.appendOnMerge) {
+		annotations["zone"] += zone
+		md.LateInitializedSpec = true
+	}
+	local.SetAnnotations(annotations)
+	return md
+}
+
+func caseFixture(a annotationResource) Case {
+	return Case{
+		Implementation: &plugin.Implementation{
+			GVK:                   k8schema.FromAPIVersionAndKind("test.crossplane.io/v1alpha1", "FakeResource"),
+			TerraformResourceName: "fake_resource",
+			CtyEncoder:            a,
+			CtyDecoder:            a,
+			ResourceMerger:        a,
+		},
+		Schema: schemaFixture(),
+		New:    func() (resource.Managed, error) { return &fake.Managed{}, nil },
+	}
+}
+
+func TestConformingImplementation(t *testing.T) {
+	Run(t, caseFixture(annotationResource{}))
+}
+
+func TestNonConformingImplementations(t *testing.T) {
+	if err := caseFixture(annotationResource{zoneType: cty.Number}).Check(); err == nil || !strings.Contains(err.Error(), "does not conform") {
+		t.Errorf("Expected an encoder that produces the wrong type to fail the schema check, got %v", err)
+	}
+	if err := caseFixture(annotationResource{truncateZone: true}).Check(); err == nil || !strings.Contains(err.Error(), "loses a value") {
+		t.Errorf("Expected a decoder that drops part of a value to fail the roundtrip check, got %v", err)
+	}
+	if err := caseFixture(annotationResource{appendOnMerge: true}).Check(); err == nil || !strings.Contains(err.Error(), "not idempotent") {
+		t.Errorf("Expected a merger that changes the resource on every merge to fail the idempotency check, got %v", err)
+	}
+}
+
+func TestRandomValueConformsToNestedSchema(t *testing.T) {
+	nested := configschema.Block{Attributes: map[string]*configschema.Attribute{
+		"port":  {Type: cty.Number, Required: true},
+		"tags":  {Type: cty.Map(cty.String), Optional: true},
+		"cidrs": {Type: cty.Set(cty.String), Optional: true},
+	}}
+	block := &configschema.Block{
+		Attributes: map[string]*configschema.Attribute{
+			"labels": {Type: cty.List(cty.Object(map[string]cty.Type{"k": cty.String, "v": cty.Bool})), Optional: true},
+		},
+		BlockTypes: map[string]*configschema.NestedBlock{
+			"single": {Block: nested, Nesting: configschema.NestingSingle},
+			"list":   {Block: nested, Nesting: configschema.NestingList, MinItems: 1, MaxItems: 2},
+			"set":    {Block: nested, Nesting: configschema.NestingSet},
+			"map":    {Block: nested, Nesting: configschema.NestingMap},
+		},
+	}
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 50; i++ {
+		v := RandomValue(r, block)
+		if errs := v.Type().TestConformance(block.ImpliedType()); len(errs) > 0 {
+			t.Fatalf("Expected random value to conform to the schema, got %v", errs)
+		}
+		if n := v.GetAttr("list").LengthInt(); n < 1 || n > 2 {
+			t.Fatalf("Expected nested list length within MinItems and MaxItems, got %d", n)
+		}
+	}
+}
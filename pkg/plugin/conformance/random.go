@@ -0,0 +1,160 @@
+package conformance
+
+import (
+	"fmt"
+	"math/rand"
+
+	"github.com/hashicorp/terraform/configs/configschema"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// maxCollectionLength bounds the number of elements generated for
+// collections and repeated blocks.
+const maxCollectionLength = 3
+
+// RandomValue returns a random value conforming to the block's implied type.
+// Required attributes are always set, other attributes are sometimes null,
+// and repeated blocks respect their MinItems and MaxItems.
+func RandomValue(r *rand.Rand, block *configschema.Block) cty.Value {
+	attrs := make(map[string]cty.Value, len(block.Attributes)+len(block.BlockTypes))
+	for name, attr := range block.Attributes {
+		if !attr.Required && r.Intn(4) == 0 {
+			attrs[name] = cty.NullVal(attr.Type)
+			continue
+		}
+		attrs[name] = randomOfType(r, attr.Type)
+	}
+	for name, nested := range block.BlockTypes {
+		attrs[name] = randomNestedBlock(r, nested)
+	}
+	if len(attrs) == 0 {
+		return cty.EmptyObjectVal
+	}
+	return cty.ObjectVal(attrs)
+}
+
+func randomNestedBlock(r *rand.Rand, nested *configschema.NestedBlock) cty.Value {
+	ty := nested.Block.ImpliedType()
+	switch nested.Nesting {
+	case configschema.NestingSingle:
+		if nested.MinItems == 0 && r.Intn(4) == 0 {
+			return cty.NullVal(ty)
+		}
+		return RandomValue(r, &nested.Block)
+	case configschema.NestingGroup:
+		return RandomValue(r, &nested.Block)
+	}
+
+	n := collectionLength(r, nested.MinItems, nested.MaxItems)
+	switch nested.Nesting {
+	case configschema.NestingList:
+		if n == 0 {
+			return cty.ListValEmpty(ty)
+		}
+		elems := make([]cty.Value, n)
+		for i := range elems {
+			elems[i] = RandomValue(r, &nested.Block)
+		}
+		return cty.ListVal(elems)
+	case configschema.NestingSet:
+		if n == 0 {
+			return cty.SetValEmpty(ty)
+		}
+		elems := make([]cty.Value, n)
+		for i := range elems {
+			elems[i] = RandomValue(r, &nested.Block)
+		}
+		return cty.SetVal(elems)
+	case configschema.NestingMap:
+		if n == 0 {
+			return cty.MapValEmpty(ty)
+		}
+		elems := make(map[string]cty.Value, n)
+		for i := 0; i < n; i++ {
+			elems[fmt.Sprintf("key%d", i)] = RandomValue(r, &nested.Block)
+		}
+		return cty.MapVal(elems)
+	}
+	panic(fmt.Sprintf("unsupported block nesting mode %s", nested.Nesting))
+}
+
+func collectionLength(r *rand.Rand, min, max int) int {
+	if max <= 0 || max > maxCollectionLength {
+		max = maxCollectionLength
+	}
+	if min > max {
+		max = min
+	}
+	return min + r.Intn(max-min+1)
+}
+
+func randomOfType(r *rand.Rand, ty cty.Type) cty.Value {
+	switch {
+	case ty == cty.String || ty == cty.DynamicPseudoType:
+		return cty.StringVal(randomString(r))
+	case ty == cty.Number:
+		return cty.NumberFloatVal(float64(r.Int63n(1<<20)) / 16)
+	case ty == cty.Bool:
+		return cty.BoolVal(r.Intn(2) == 0)
+	case ty.IsListType():
+		n := collectionLength(r, 0, 0)
+		if n == 0 {
+			return cty.ListValEmpty(ty.ElementType())
+		}
+		elems := make([]cty.Value, n)
+		for i := range elems {
+			elems[i] = randomOfType(r, ty.ElementType())
+		}
+		return cty.ListVal(elems)
+	case ty.IsSetType():
+		n := collectionLength(r, 0, 0)
+		if n == 0 {
+			return cty.SetValEmpty(ty.ElementType())
+		}
+		elems := make([]cty.Value, n)
+		for i := range elems {
+			elems[i] = randomOfType(r, ty.ElementType())
+		}
+		return cty.SetVal(elems)
+	case ty.IsMapType():
+		n := collectionLength(r, 0, 0)
+		if n == 0 {
+			return cty.MapValEmpty(ty.ElementType())
+		}
+		elems := make(map[string]cty.Value, n)
+		for i := 0; i < n; i++ {
+			elems[fmt.Sprintf("key%d", i)] = randomOfType(r, ty.ElementType())
+		}
+		return cty.MapVal(elems)
+	case ty.IsObjectType():
+		attrTypes := ty.AttributeTypes()
+		if len(attrTypes) == 0 {
+			return cty.EmptyObjectVal
+		}
+		attrs := make(map[string]cty.Value, len(attrTypes))
+		for name, aty := range attrTypes {
+			attrs[name] = randomOfType(r, aty)
+		}
+		return cty.ObjectVal(attrs)
+	case ty.IsTupleType():
+		etys := ty.TupleElementTypes()
+		if len(etys) == 0 {
+			return cty.EmptyTupleVal
+		}
+		elems := make([]cty.Value, len(etys))
+		for i, ety := range etys {
+			elems[i] = randomOfType(r, ety)
+		}
+		return cty.TupleVal(elems)
+	}
+	panic(fmt.Sprintf("unsupported attribute type %s", ty.FriendlyName()))
+}
+
+func randomString(r *rand.Rand) string {
+	const letters = "abcdefghijklmnopqrstuvwxyz0123456789"
+	b := make([]byte, 1+r.Intn(12))
+	for i := range b {
+		b[i] = letters[r.Intn(len(letters))]
+	}
+	return string(b)
+}
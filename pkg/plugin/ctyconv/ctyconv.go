@@ -0,0 +1,132 @@
+// Package ctyconv holds the conversions between cty values and Go values
+// shared by generated CtyEncoders and CtyDecoders. Decoding functions treat
+// unknown values like nulls, since unknown values only appear in plans.
+package ctyconv
+
+import (
+	"github.com/zclconf/go-cty/cty"
+	ctyjson "github.com/zclconf/go-cty/cty/json"
+)
+
+// IsNull reports whether v holds no value, either because it is null or
+// because it is not known yet.
+func IsNull(v cty.Value) bool {
+	return v == cty.NilVal || !v.IsKnown() || v.IsNull()
+}
+
+// GetAttr returns the named attribute of the object v, or null if v is
+// null or has no such attribute.
+func GetAttr(v cty.Value, name string) cty.Value {
+	if IsNull(v) || !v.Type().IsObjectType() || !v.Type().HasAttribute(name) {
+		return cty.NullVal(cty.DynamicPseudoType)
+	}
+	return v.GetAttr(name)
+}
+
+// String returns the string in v, or "" if there is none.
+func String(v cty.Value) string {
+	if IsNull(v) {
+		return ""
+	}
+	return v.AsString()
+}
+
+// Int64 returns the number in v, truncated to an int64, or 0 if there is
+// none.
+func Int64(v cty.Value) int64 {
+	if IsNull(v) {
+		return 0
+	}
+	i, _ := v.AsBigFloat().Int64()
+	return i
+}
+
+// Float64 returns the number in v, or 0 if there is none. Generated types
+// hold numbers as float64, which is what providers convert them to.
+func Float64(v cty.Value) float64 {
+	if IsNull(v) {
+		return 0
+	}
+	f, _ := v.AsBigFloat().Float64()
+	return f
+}
+
+// Bool returns the bool in v, or false if there is none.
+func Bool(v cty.Value) bool {
+	if IsNull(v) {
+		return false
+	}
+	return v.True()
+}
+
+// Dynamic returns the value of a dynamically typed attribute as a string.
+// Strings are returned as they are, other values as JSON.
+func Dynamic(v cty.Value) string {
+	if IsNull(v) {
+		return ""
+	}
+	if v.Type() == cty.String {
+		return v.AsString()
+	}
+	b, err := ctyjson.Marshal(v, v.Type())
+	if err != nil {
+		return ""
+	}
+	return string(b)
+}
+
+// EmptyIfNull returns an empty collection of v's type if v is a null
+// collection. Terraform represents absent nested blocks as empty
+// collections rather than nulls.
+func EmptyIfNull(v cty.Value) cty.Value {
+	if !v.IsNull() {
+		return v
+	}
+	ty := v.Type()
+	switch {
+	case ty.IsListType():
+		return cty.ListValEmpty(ty.ElementType())
+	case ty.IsSetType():
+		return cty.SetValEmpty(ty.ElementType())
+	case ty.IsMapType():
+		return cty.MapValEmpty(ty.ElementType())
+	}
+	return v
+}
+
+// ElementType returns the element type of a collection type, or the
+// dynamic pseudo-type if ty is not a collection.
+func ElementType(ty cty.Type) cty.Type {
+	if ty.IsCollectionType() {
+		return ty.ElementType()
+	}
+	return cty.DynamicPseudoType
+}
+
+// Attribute returns the type of the named attribute of an object type.
+// Generated code skips attributes the provider's schema does not have, so
+// that it keeps working with other versions of the provider.
+func Attribute(ty cty.Type, name string) (cty.Type, bool) {
+	if !ty.IsObjectType() || !ty.HasAttribute(name) {
+		return cty.NilType, false
+	}
+	return ty.AttributeType(name), true
+}
+
+// Object returns an object of type ty with the given attributes, setting
+// the attributes that are missing to null. If ty is not an object type,
+// because the schema does not match the generated code, it returns null.
+func Object(ty cty.Type, attrs map[string]cty.Value) cty.Value {
+	if !ty.IsObjectType() {
+		return cty.NullVal(ty)
+	}
+	for name, aty := range ty.AttributeTypes() {
+		if _, ok := attrs[name]; !ok {
+			attrs[name] = EmptyIfNull(cty.NullVal(aty))
+		}
+	}
+	if len(attrs) == 0 {
+		return cty.EmptyObjectVal
+	}
+	return cty.ObjectVal(attrs)
+}
@@ -0,0 +1,29 @@
+package plugin
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// DebugHandler serves the Descriptions of every Implementation in idx as
+// JSON. The terraform query parameter selects a single one by terraform
+// resource type, eg ?terraform=google_compute_instance.
+func DebugHandler(idx *Index) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body interface{} = idx.Descriptions()
+		if tfName := r.URL.Query().Get("terraform"); tfName != "" {
+			gvk, ok := idx.GVKForTerraformName(tfName)
+			if !ok {
+				http.Error(w, "no Implementation for terraform resource "+tfName, http.StatusNotFound)
+				return
+			}
+			body, _ = idx.Describe(gvk)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(body); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	})
+}
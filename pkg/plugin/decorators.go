@@ -0,0 +1,111 @@
+package plugin
+
+import (
+	"github.com/crossplane/crossplane-runtime/pkg/logging"
+	xpresource "github.com/crossplane/crossplane-runtime/pkg/resource"
+	"github.com/hashicorp/terraform/providers"
+	"github.com/zclconf/go-cty/cty"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	"github.com/crossplane/terraform-provider-runtime/pkg/client"
+)
+
+// Decorators are middleware for the callbacks of an Implementation. Each
+// is passed the callback merged from the layers below its Implementation,
+// and returns the callback to use instead. A callback set in the same
+// Implementation is decorated too. Decorating a callback that none of the
+// layers below set is an error reported by BuildIndex.
+//
+// For example, to post-process the generated encoding:
+//
+//	Decorators: plugin.Decorators{
+//		CtyEncoder: func(next plugin.CtyEncoder) plugin.CtyEncoder {
+//			return plugin.CtyEncoderFunc(func(r resource.Managed, s *providers.Schema) (cty.Value, error) {
+//				v, err := next.EncodeCty(r, s)
+//				// tweak v
+//				return v, err
+//			})
+//		},
+//	}
+type Decorators struct {
+	CtyEncoder               func(CtyEncoder) CtyEncoder
+	CtyDecoder               func(CtyDecoder) CtyDecoder
+	ResourceMerger           func(ResourceMerger) ResourceMerger
+	ReconcilerConfigurer     func(ReconcilerConfigurer) ReconcilerConfigurer
+	ResourceYAMLMarshaller   func(ResourceYAMLMarshaller) ResourceYAMLMarshaller
+	ResourceYAMLUnmarshaller func(ResourceYAMLUnmarshaller) ResourceYAMLUnmarshaller
+}
+
+// CtyEncoderFunc is a function that satisfies CtyEncoder.
+type CtyEncoderFunc func(xpresource.Managed, *providers.Schema) (cty.Value, error)
+
+// EncodeCty calls f.
+func (f CtyEncoderFunc) EncodeCty(r xpresource.Managed, s *providers.Schema) (cty.Value, error) {
+	return f(r, s)
+}
+
+// CtyDecoderFunc is a function that satisfies CtyDecoder.
+type CtyDecoderFunc func(xpresource.Managed, cty.Value, *providers.Schema) (xpresource.Managed, error)
+
+// DecodeCty calls f.
+func (f CtyDecoderFunc) DecodeCty(r xpresource.Managed, v cty.Value, s *providers.Schema) (xpresource.Managed, error) {
+	return f(r, v, s)
+}
+
+// ResourceMergerFunc is a function that satisfies ResourceMerger. A
+// ResourceMerger decorator should use ContextResourceMergerFunc instead, so
+// that the MergeContext reaches the ResourceMerger it decorates.
+type ResourceMergerFunc func(local, observed xpresource.Managed) MergeDescription
+
+// MergeResources calls f.
+func (f ResourceMergerFunc) MergeResources(local, observed xpresource.Managed) MergeDescription {
+	return f(local, observed)
+}
+
+// ContextResourceMergerFunc is a function that satisfies
+// ContextResourceMerger. For example, to add to the merge below:
+//
+//	ResourceMerger: func(next plugin.ResourceMerger) plugin.ResourceMerger {
+//		return plugin.ContextResourceMergerFunc(func(local, observed resource.Managed, mc plugin.MergeContext) plugin.MergeDescription {
+//			md := plugin.MergeInContext(next, local, observed, mc)
+//			// compare more of local and observed
+//			return md
+//		})
+//	},
+type ContextResourceMergerFunc func(local, observed xpresource.Managed, mc MergeContext) MergeDescription
+
+// MergeResources calls f without a schema.
+func (f ContextResourceMergerFunc) MergeResources(local, observed xpresource.Managed) MergeDescription {
+	return f(local, observed, MergeContext{})
+}
+
+// MergeResourcesInContext calls f.
+func (f ContextResourceMergerFunc) MergeResourcesInContext(local, observed xpresource.Managed, mc MergeContext) MergeDescription {
+	return f(local, observed, mc)
+}
+
+// ReconcilerConfigurerFunc is a function that satisfies ReconcilerConfigurer.
+type ReconcilerConfigurerFunc func(ctrl.Manager, logging.Logger, *Index, *client.ProviderPool, ReconcilerOptions) error
+
+// ConfigureReconciler calls f.
+func (f ReconcilerConfigurerFunc) ConfigureReconciler(mgr ctrl.Manager, l logging.Logger, idx *Index, pool *client.ProviderPool, o ReconcilerOptions) error {
+	return f(mgr, l, idx, pool, o)
+}
+
+// ResourceYAMLMarshallerFunc is a function that satisfies
+// ResourceYAMLMarshaller.
+type ResourceYAMLMarshallerFunc func(xpresource.Managed) ([]byte, error)
+
+// MarshalResourceYAML calls f.
+func (f ResourceYAMLMarshallerFunc) MarshalResourceYAML(r xpresource.Managed) ([]byte, error) {
+	return f(r)
+}
+
+// ResourceYAMLUnmarshallerFunc is a function that satisfies
+// ResourceYAMLUnmarshaller.
+type ResourceYAMLUnmarshallerFunc func([]byte) (xpresource.Managed, error)
+
+// UnmarshalResourceYAML calls f.
+func (f ResourceYAMLUnmarshallerFunc) UnmarshalResourceYAML(b []byte) (xpresource.Managed, error) {
+	return f(b)
+}
@@ -0,0 +1,56 @@
+package plugin
+
+import (
+	"context"
+
+	"github.com/crossplane/crossplane-runtime/pkg/reconciler/managed"
+	xpresource "github.com/crossplane/crossplane-runtime/pkg/resource"
+
+	"github.com/crossplane/terraform-provider-runtime/pkg/client"
+)
+
+// ExternalCall is what an ExternalClientFns override is called with.
+type ExternalCall struct {
+	// Provider is the provider the ExternalClient borrowed for the call.
+	Provider *client.Provider
+	Invoker  *Invoker
+	// Default is the runtime's ExternalClient without overrides, which an
+	// override can delegate to.
+	Default managed.ExternalClient
+}
+
+// ExternalClientFns override the lifecycle operations of the runtime's
+// ExternalClient for a resource. Operations left nil run the default.
+type ExternalClientFns struct {
+	ObserveFn func(ctx context.Context, mg xpresource.Managed, call ExternalCall) (managed.ExternalObservation, error)
+	CreateFn  func(ctx context.Context, mg xpresource.Managed, call ExternalCall) (managed.ExternalCreation, error)
+	UpdateFn  func(ctx context.Context, mg xpresource.Managed, call ExternalCall) (managed.ExternalUpdate, error)
+	DeleteFn  func(ctx context.Context, mg xpresource.Managed, call ExternalCall) error
+}
+
+// Bind returns managed.ExternalClientFns calling the overrides with call.
+// Operations that are not overridden are left nil.
+func (fns ExternalClientFns) Bind(call ExternalCall) managed.ExternalClientFns {
+	bound := managed.ExternalClientFns{}
+	if fns.ObserveFn != nil {
+		bound.ObserveFn = func(ctx context.Context, mg xpresource.Managed) (managed.ExternalObservation, error) {
+			return fns.ObserveFn(ctx, mg, call)
+		}
+	}
+	if fns.CreateFn != nil {
+		bound.CreateFn = func(ctx context.Context, mg xpresource.Managed) (managed.ExternalCreation, error) {
+			return fns.CreateFn(ctx, mg, call)
+		}
+	}
+	if fns.UpdateFn != nil {
+		bound.UpdateFn = func(ctx context.Context, mg xpresource.Managed) (managed.ExternalUpdate, error) {
+			return fns.UpdateFn(ctx, mg, call)
+		}
+	}
+	if fns.DeleteFn != nil {
+		bound.DeleteFn = func(ctx context.Context, mg xpresource.Managed) error {
+			return fns.DeleteFn(ctx, mg, call)
+		}
+	}
+	return bound
+}
@@ -0,0 +1,97 @@
+package plugin
+
+import (
+	"fmt"
+
+	xpresource "github.com/crossplane/crossplane-runtime/pkg/resource"
+	"github.com/hashicorp/terraform/providers"
+	"github.com/zclconf/go-cty/cty"
+	k8schema "k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// Operation names the api operation a hook runs in.
+type Operation string
+
+// Operations hooks run in.
+const (
+	OperationPlan   Operation = "Plan"
+	OperationCreate Operation = "Create"
+	OperationRead   Operation = "Read"
+	OperationUpdate Operation = "Update"
+	OperationDelete Operation = "Delete"
+)
+
+// BeforeEncodeHook may change a resource before it is encoded for the
+// provider. It is passed a copy, so changes only affect what is sent.
+type BeforeEncodeHook func(op Operation, r xpresource.Managed) error
+
+// AfterEncodeHook may change the encoded resource sent to the provider,
+// returning the value to send instead.
+type AfterEncodeHook func(op Operation, r xpresource.Managed, v cty.Value, s *providers.Schema) (cty.Value, error)
+
+// AfterApplyHook inspects the resource decoded from the state the provider
+// returned after a Create or Update, along with the state itself. The
+// change has already been applied when it fails the operation.
+type AfterApplyHook func(op Operation, r xpresource.Managed, newState cty.Value) error
+
+// BeforeDeleteHook runs before a resource is deleted. Returning an error
+// vetoes the deletion.
+type BeforeDeleteHook func(r xpresource.Managed) error
+
+// Hooks run around the api operations on a resource. The hooks of every
+// layer Overlaid for a GVK run, those of lower layers first, and the first
+// to fail the operation stops it with a HookError.
+type Hooks struct {
+	BeforeEncode []BeforeEncodeHook
+	AfterEncode  []AfterEncodeHook
+	AfterApply   []AfterApplyHook
+	BeforeDelete []BeforeDeleteHook
+}
+
+// overlay returns the hooks of h followed by those of upper.
+func (h Hooks) overlay(upper Hooks) Hooks {
+	return Hooks{
+		BeforeEncode: append(append([]BeforeEncodeHook{}, h.BeforeEncode...), upper.BeforeEncode...),
+		AfterEncode:  append(append([]AfterEncodeHook{}, h.AfterEncode...), upper.AfterEncode...),
+		AfterApply:   append(append([]AfterApplyHook{}, h.AfterApply...), upper.AfterApply...),
+		BeforeDelete: append(append([]BeforeDeleteHook{}, h.BeforeDelete...), upper.BeforeDelete...),
+	}
+}
+
+// HookError is returned from an operation a hook failed.
+type HookError struct {
+	// Hook is the kind of hook that failed, eg BeforeEncode.
+	Hook      string
+	Operation Operation
+	GVK       k8schema.GroupVersionKind
+	Err       error
+}
+
+func (e *HookError) Error() string {
+	return fmt.Sprintf("%s hook failed %s of %s: %s", e.Hook, e.Operation, e.GVK.Kind, e.Err)
+}
+
+// Cause returns the error the hook returned.
+func (e *HookError) Cause() error {
+	return e.Err
+}
+
+// Unwrap returns the error the hook returned.
+func (e *HookError) Unwrap() error {
+	return e.Err
+}
+
+// IsHookError reports whether err, or its cause, is a HookError.
+func IsHookError(err error) bool {
+	for err != nil {
+		if _, ok := err.(*HookError); ok {
+			return true
+		}
+		c, ok := err.(interface{ Cause() error })
+		if !ok {
+			return false
+		}
+		err = c.Cause()
+	}
+	return false
+}
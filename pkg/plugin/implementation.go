@@ -1,6 +1,9 @@
 package plugin
 
 import (
+	"fmt"
+	"strings"
+
 	k8schema "k8s.io/apimachinery/pkg/runtime/schema"
 	"sigs.k8s.io/controller-runtime/pkg/scheme"
 )
@@ -9,7 +12,8 @@ import (
 // through the Overlay method, and then can generate a single Implementation
 // which is the result of merging all the layers into a single Implementation.
 // It does this by picking a non-nil value for each field
-// from the highest possible layer. Please see indexer_test.go for clarification.
+// from the highest possible layer, then applying the Decorators of the
+// layers above it. Please see indexer_test.go for clarification.
 type ImplementationMerger struct {
 	layers []*Implementation
 }
@@ -18,42 +22,232 @@ type ImplementationMerger struct {
 // a layers' non-empty fields always replace underlying layers' fields.
 // So last field Overlayed wins, eg call Overlay w/ the generated base
 // Implementation before the user-override Implementations.
-// I don't know if we really need an error; if we can assume generated
-// Implementations cover everything, we don't.
-// if that turns out to be a bad assumption, the error would give us
-// an escape hatch to fail on incomplete implementations at runtime.
+// The merged Implementation is returned along with an error if it is
+// missing a terraform resource name or a required callback, or if a layer
+// decorates a callback that no layer below it sets.
 func (mt *ImplementationMerger) Merge() (Implementation, error) {
-	merged := Implementation{}
-	for _, ft := range mt.layers {
+	merged, _, undecorated := mt.merge()
+	problems := make([]string, 0)
+	if missing := merged.missing(); len(missing) > 0 {
+		problems = append(problems, "is missing "+strings.Join(missing, ", "))
+	}
+	problems = append(problems, undecorated...)
+	if len(problems) > 0 {
+		return merged, fmt.Errorf("%s %s", merged.GVK, strings.Join(problems, ", "))
+	}
+	return merged, nil
+}
+
+// merge flattens the layers, tracing which layers each field of the result
+// was taken from and decorated by. Decorators of callbacks that are unset
+// below them are skipped, since the wrapper they return would hide that the
+// callback is missing; they are reported in undecorated.
+func (mt *ImplementationMerger) merge() (merged Implementation, trace map[string]*FieldProvenance, undecorated []string) {
+	trace = make(map[string]*FieldProvenance)
+	set := func(field string, layer int) {
+		trace[field] = &FieldProvenance{Field: field, Layer: layer}
+	}
+	decorate := func(field string, layer int, lowerSet bool) bool {
+		if !lowerSet {
+			undecorated = append(undecorated, fmt.Sprintf("decorates unset %s in layer %d", field, layer))
+			return false
+		}
+		if _, ok := trace[field]; !ok {
+			trace[field] = &FieldProvenance{Field: field, Layer: -1}
+		}
+		trace[field].DecoratedBy = append(trace[field].DecoratedBy, layer)
+		return true
+	}
+	for i, ft := range mt.layers {
 		if !ft.GVK.Empty() {
 			merged.GVK = ft.GVK
+			set("GVK", i)
 		}
 		if ft.TerraformResourceName != "" {
 			merged.TerraformResourceName = ft.TerraformResourceName
+			set("TerraformResourceName", i)
 		}
 		if ft.CtyEncoder != nil {
 			merged.CtyEncoder = ft.CtyEncoder
+			set("CtyEncoder", i)
 		}
 		if ft.CtyDecoder != nil {
 			merged.CtyDecoder = ft.CtyDecoder
+			set("CtyDecoder", i)
 		}
 		if ft.SchemeBuilder != nil {
 			merged.SchemeBuilder = ft.SchemeBuilder
+			set("SchemeBuilder", i)
 		}
 		if ft.ReconcilerConfigurer != nil {
 			merged.ReconcilerConfigurer = ft.ReconcilerConfigurer
+			set("ReconcilerConfigurer", i)
 		}
 		if ft.ResourceYAMLUnmarshaller != nil {
 			merged.ResourceYAMLUnmarshaller = ft.ResourceYAMLUnmarshaller
+			set("ResourceYAMLUnmarshaller", i)
 		}
 		if ft.ResourceYAMLMarshaller != nil {
 			merged.ResourceYAMLMarshaller = ft.ResourceYAMLMarshaller
+			set("ResourceYAMLMarshaller", i)
 		}
 		if ft.ResourceMerger != nil {
 			merged.ResourceMerger = ft.ResourceMerger
+			set("ResourceMerger", i)
+		}
+
+		if ft.ExternalClientFns.ObserveFn != nil {
+			merged.ExternalClientFns.ObserveFn = ft.ExternalClientFns.ObserveFn
+			set("ExternalClientFns.ObserveFn", i)
+		}
+		if ft.ExternalClientFns.CreateFn != nil {
+			merged.ExternalClientFns.CreateFn = ft.ExternalClientFns.CreateFn
+			set("ExternalClientFns.CreateFn", i)
+		}
+		if ft.ExternalClientFns.UpdateFn != nil {
+			merged.ExternalClientFns.UpdateFn = ft.ExternalClientFns.UpdateFn
+			set("ExternalClientFns.UpdateFn", i)
+		}
+		if ft.ExternalClientFns.DeleteFn != nil {
+			merged.ExternalClientFns.DeleteFn = ft.ExternalClientFns.DeleteFn
+			set("ExternalClientFns.DeleteFn", i)
+		}
+		merged.Hooks = merged.Hooks.overlay(ft.Hooks)
+
+		// decorators wrap everything below them, including callbacks
+		// replaced by their own layer
+		d := ft.Decorators
+		if d.CtyEncoder != nil && decorate("CtyEncoder", i, merged.CtyEncoder != nil) {
+			merged.CtyEncoder = d.CtyEncoder(merged.CtyEncoder)
+		}
+		if d.CtyDecoder != nil && decorate("CtyDecoder", i, merged.CtyDecoder != nil) {
+			merged.CtyDecoder = d.CtyDecoder(merged.CtyDecoder)
+		}
+		if d.ReconcilerConfigurer != nil && decorate("ReconcilerConfigurer", i, merged.ReconcilerConfigurer != nil) {
+			merged.ReconcilerConfigurer = d.ReconcilerConfigurer(merged.ReconcilerConfigurer)
+		}
+		if d.ResourceYAMLUnmarshaller != nil && decorate("ResourceYAMLUnmarshaller", i, merged.ResourceYAMLUnmarshaller != nil) {
+			merged.ResourceYAMLUnmarshaller = d.ResourceYAMLUnmarshaller(merged.ResourceYAMLUnmarshaller)
+		}
+		if d.ResourceYAMLMarshaller != nil && decorate("ResourceYAMLMarshaller", i, merged.ResourceYAMLMarshaller != nil) {
+			merged.ResourceYAMLMarshaller = d.ResourceYAMLMarshaller(merged.ResourceYAMLMarshaller)
+		}
+		if d.ResourceMerger != nil && decorate("ResourceMerger", i, merged.ResourceMerger != nil) {
+			merged.ResourceMerger = d.ResourceMerger(merged.ResourceMerger)
 		}
 	}
-	return merged, nil
+	return merged, trace, undecorated
+}
+
+// Describe reports which layer each field of the merged Implementation
+// came from, and which layers decorated it.
+func (mt *ImplementationMerger) Describe() Description {
+	merged, trace, _ := mt.merge()
+	d := Description{
+		GVK:                   merged.GVK,
+		TerraformResourceName: merged.TerraformResourceName,
+		Layers:                len(mt.layers),
+		Fields:                make([]FieldProvenance, 0, len(implementationFields)),
+	}
+	values := map[string]interface{}{
+		"SchemeBuilder":               merged.SchemeBuilder,
+		"ReconcilerConfigurer":        merged.ReconcilerConfigurer,
+		"ResourceMerger":              merged.ResourceMerger,
+		"CtyEncoder":                  merged.CtyEncoder,
+		"CtyDecoder":                  merged.CtyDecoder,
+		"ResourceYAMLMarshaller":      merged.ResourceYAMLMarshaller,
+		"ResourceYAMLUnmarshaller":    merged.ResourceYAMLUnmarshaller,
+		"ExternalClientFns.ObserveFn": merged.ExternalClientFns.ObserveFn,
+		"ExternalClientFns.CreateFn":  merged.ExternalClientFns.CreateFn,
+		"ExternalClientFns.UpdateFn":  merged.ExternalClientFns.UpdateFn,
+		"ExternalClientFns.DeleteFn":  merged.ExternalClientFns.DeleteFn,
+	}
+	for _, name := range implementationFields {
+		fp := FieldProvenance{Field: name, Layer: -1}
+		if traced, ok := trace[name]; ok {
+			fp = *traced
+		}
+		switch name {
+		case "GVK":
+			fp.Value = merged.GVK.String()
+		case "TerraformResourceName":
+			fp.Value = merged.TerraformResourceName
+		default:
+			if fp.Layer >= 0 || len(fp.DecoratedBy) > 0 {
+				fp.Value = fmt.Sprintf("%T", values[name])
+			}
+		}
+		d.Fields = append(d.Fields, fp)
+	}
+	return d
+}
+
+// implementationFields are the fields of an Implementation, in the order
+// they are described.
+var implementationFields = []string{
+	"GVK",
+	"TerraformResourceName",
+	"SchemeBuilder",
+	"ReconcilerConfigurer",
+	"ResourceMerger",
+	"CtyEncoder",
+	"CtyDecoder",
+	"ResourceYAMLMarshaller",
+	"ResourceYAMLUnmarshaller",
+	"ExternalClientFns.ObserveFn",
+	"ExternalClientFns.CreateFn",
+	"ExternalClientFns.UpdateFn",
+	"ExternalClientFns.DeleteFn",
+}
+
+// Description explains how the Implementation of a GVK was assembled from
+// the layers Overlaid for it.
+type Description struct {
+	GVK                   k8schema.GroupVersionKind `json:"gvk"`
+	TerraformResourceName string                    `json:"terraformResourceName"`
+	// Layers is the number of Implementations Overlaid for the GVK.
+	Layers int               `json:"layers"`
+	Fields []FieldProvenance `json:"fields"`
+}
+
+// FieldProvenance records the layer a field of a merged Implementation was
+// taken from.
+type FieldProvenance struct {
+	// Field is the name of the Implementation field, eg CtyEncoder.
+	Field string `json:"field"`
+	// Layer is the index of the winning layer, in the order they were
+	// Overlaid, or -1 if no layer set the field.
+	Layer int `json:"layer"`
+	// DecoratedBy lists the layers whose Decorators wrapped the field, in
+	// the order they were applied.
+	DecoratedBy []int `json:"decoratedBy,omitempty"`
+	// Value is the field's value, or the Go type of a callback.
+	Value string `json:"value,omitempty"`
+}
+
+// missing lists the fields the runtime needs that are unset. The YAML
+// callbacks are only used by the CLI, so they are optional.
+func (ft Implementation) missing() []string {
+	missing := make([]string, 0)
+	if ft.TerraformResourceName == "" {
+		missing = append(missing, "TerraformResourceName")
+	}
+	if ft.SchemeBuilder == nil {
+		missing = append(missing, "SchemeBuilder")
+	}
+	if ft.ReconcilerConfigurer == nil {
+		missing = append(missing, "ReconcilerConfigurer")
+	}
+	if ft.ResourceMerger == nil {
+		missing = append(missing, "ResourceMerger")
+	}
+	if ft.CtyEncoder == nil {
+		missing = append(missing, "CtyEncoder")
+	}
+	if ft.CtyDecoder == nil {
+		missing = append(missing, "CtyDecoder")
+	}
+	return missing
 }
 
 func (mt *ImplementationMerger) Overlay(ft *Implementation) {
@@ -104,4 +298,14 @@ type Implementation struct {
 	// function that can parse the []byte representation of a managed resource
 	// to a resource.Managed
 	ResourceYAMLUnmarshaller ResourceYAMLUnmarshaller
+	// ExternalClientFns replace the runtime's lifecycle operations for the
+	// resource. Each operation is merged separately.
+	ExternalClientFns ExternalClientFns
+	// Hooks run around the api operations on the resource. Unlike the
+	// callbacks, the hooks of every layer run.
+	Hooks Hooks
+	// Decorators wrap the callbacks merged from the layers below this one,
+	// so that an override can tweak generated behavior without replacing
+	// it.
+	Decorators Decorators
 }
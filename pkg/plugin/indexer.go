@@ -2,7 +2,10 @@ package plugin
 
 import (
 	"fmt"
+	"sort"
+	"strings"
 
+	"github.com/hashicorp/terraform/providers"
 	k8schema "k8s.io/apimachinery/pkg/runtime/schema"
 	"sigs.k8s.io/controller-runtime/pkg/scheme"
 )
@@ -26,6 +29,9 @@ func (i *Indexer) Overlay(ft *Implementation) error {
 	return nil
 }
 
+// BuildIndex merges the layers Overlaid for each GVK. Every merged
+// Implementation must be complete, and no two may share a terraform
+// resource name; all problems are reported in a single error.
 func (i *Indexer) BuildIndex() (*Index, error) {
 	idx := &Index{
 		reconcilerConfigurers: make([]ReconcilerConfigurer, 0),
@@ -33,27 +39,54 @@ func (i *Indexer) BuildIndex() (*Index, error) {
 		ftMap:                 make(map[k8schema.GroupVersionKind]Implementation),
 		gvkToTFName:           make(map[k8schema.GroupVersionKind]string),
 		tfNameToGVK:           make(map[string]k8schema.GroupVersionKind),
+		descriptions:          make(map[k8schema.GroupVersionKind]Description),
 	}
-	for gvk, mt := range i.gvkIndex {
-		merged, err := mt.Merge()
+	problems := make([]string, 0)
+	// sorted so that the report, and the order controllers are set up in,
+	// is stable
+	for _, gvk := range i.sortedGVKs() {
+		merged, err := i.gvkIndex[gvk].Merge()
 		if err != nil {
-			return nil, err
+			problems = append(problems, err.Error())
+			continue
+		}
+		if other, ok := idx.tfNameToGVK[merged.TerraformResourceName]; ok {
+			problems = append(problems, fmt.Sprintf("%s and %s both implement terraform resource %s", other, gvk, merged.TerraformResourceName))
+			continue
 		}
 		idx.ftMap[gvk] = merged
+		idx.descriptions[gvk] = i.gvkIndex[gvk].Describe()
 		idx.reconcilerConfigurers = append(idx.reconcilerConfigurers, merged.ReconcilerConfigurer)
 		idx.schemeBuilders = append(idx.schemeBuilders, merged.SchemeBuilder)
 		idx.gvkToTFName[gvk] = merged.TerraformResourceName
 		idx.tfNameToGVK[merged.TerraformResourceName] = gvk
 	}
+	if len(problems) > 0 {
+		return nil, fmt.Errorf("Invalid Implementations:\n  %s", strings.Join(problems, "\n  "))
+	}
 	return idx, nil
 }
 
+func (i *Indexer) sortedGVKs() []k8schema.GroupVersionKind {
+	gvks := make([]k8schema.GroupVersionKind, 0, len(i.gvkIndex))
+	for gvk := range i.gvkIndex {
+		gvks = append(gvks, gvk)
+	}
+	sortGVKs(gvks)
+	return gvks
+}
+
+func sortGVKs(gvks []k8schema.GroupVersionKind) {
+	sort.Slice(gvks, func(a, b int) bool { return gvks[a].String() < gvks[b].String() })
+}
+
 type Index struct {
 	ftMap                 map[k8schema.GroupVersionKind]Implementation
 	reconcilerConfigurers []ReconcilerConfigurer
 	schemeBuilders        []*scheme.Builder
 	gvkToTFName           map[k8schema.GroupVersionKind]string
 	tfNameToGVK           map[string]k8schema.GroupVersionKind
+	descriptions          map[k8schema.GroupVersionKind]Description
 }
 
 func (idx *Index) ReconcilerConfigurers() []ReconcilerConfigurer {
@@ -64,6 +97,56 @@ func (idx *Index) SchemeBuilders() []*scheme.Builder {
 	return idx.schemeBuilders
 }
 
+// GVKs lists the GVKs with an Implementation, sorted.
+func (idx *Index) GVKs() []k8schema.GroupVersionKind {
+	gvks := make([]k8schema.GroupVersionKind, 0, len(idx.ftMap))
+	for gvk := range idx.ftMap {
+		gvks = append(gvks, gvk)
+	}
+	sortGVKs(gvks)
+	return gvks
+}
+
+// GVKForTerraformName returns the GVK implementing a terraform resource
+// type, eg google_compute_instance.
+func (idx *Index) GVKForTerraformName(tfName string) (k8schema.GroupVersionKind, bool) {
+	gvk, ok := idx.tfNameToGVK[tfName]
+	return gvk, ok
+}
+
+// TerraformNameForGVK returns the terraform resource type a GVK implements.
+func (idx *Index) TerraformNameForGVK(gvk k8schema.GroupVersionKind) (string, bool) {
+	tfName, ok := idx.gvkToTFName[gvk]
+	return tfName, ok
+}
+
+// InvokerForTerraformName returns the Invoker for the GVK implementing a
+// terraform resource type.
+func (idx *Index) InvokerForTerraformName(tfName string) (*Invoker, error) {
+	gvk, ok := idx.tfNameToGVK[tfName]
+	if !ok {
+		return &Invoker{}, fmt.Errorf("Could not look up functable for terraform resource %s", tfName)
+	}
+	return idx.InvokerForGVK(gvk)
+}
+
+// Describe reports which layer each callback of a GVK's Implementation
+// came from.
+func (idx *Index) Describe(gvk k8schema.GroupVersionKind) (Description, bool) {
+	d, ok := idx.descriptions[gvk]
+	return d, ok
+}
+
+// Descriptions describes every Implementation, sorted by GVK.
+func (idx *Index) Descriptions() []Description {
+	gvks := idx.GVKs()
+	ds := make([]Description, len(gvks))
+	for i, gvk := range gvks {
+		ds[i] = idx.descriptions[gvk]
+	}
+	return ds
+}
+
 func (idx *Index) InvokerForGVK(gvk k8schema.GroupVersionKind) (*Invoker, error) {
 	ft, ok := idx.ftMap[gvk]
 	if !ok {
@@ -71,3 +154,19 @@ func (idx *Index) InvokerForGVK(gvk k8schema.GroupVersionKind) (*Invoker, error)
 	}
 	return &Invoker{ft: ft}, nil
 }
+
+// ValidateSchema checks that the provider has a resource type for every
+// Implementation in the Index, reporting every one it does not have.
+func (idx *Index) ValidateSchema(resourceTypes map[string]providers.Schema) error {
+	problems := make([]string, 0)
+	for tfName, gvk := range idx.tfNameToGVK {
+		if _, ok := resourceTypes[tfName]; !ok {
+			problems = append(problems, fmt.Sprintf("%s implements terraform resource %s, which the provider does not have", gvk, tfName))
+		}
+	}
+	if len(problems) > 0 {
+		sort.Strings(problems)
+		return fmt.Errorf("Implementations do not match the provider schema:\n  %s", strings.Join(problems, "\n  "))
+	}
+	return nil
+}
@@ -2,19 +2,52 @@ package plugin
 
 import (
 	"fmt"
+	"reflect"
+	"strings"
 	"testing"
 
+	"github.com/crossplane/crossplane-runtime/pkg/logging"
 	xpresource "github.com/crossplane/crossplane-runtime/pkg/resource"
 	"github.com/crossplane/crossplane-runtime/pkg/resource/fake"
 	"github.com/hashicorp/terraform/providers"
 	"github.com/zclconf/go-cty/cty"
 	k8schema "k8s.io/apimachinery/pkg/runtime/schema"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/scheme"
+
+	"github.com/crossplane/terraform-provider-runtime/pkg/client"
 )
 
 func gvkFixture() k8schema.GroupVersionKind {
 	return k8schema.FromAPIVersionAndKind("test.crossplane.io/v1alpha1", "FakeResource")
 }
 
+type nopReconcilerConfigurer struct{}
+
+func (nopReconcilerConfigurer) ConfigureReconciler(ctrl.Manager, logging.Logger, *Index, *client.ProviderPool, ReconcilerOptions) error {
+	return nil
+}
+
+type nopMerger struct{}
+
+func (nopMerger) MergeResources(xpresource.Managed, xpresource.Managed) MergeDescription {
+	return MergeDescription{}
+}
+
+// implementationFixture returns an Implementation with every required
+// callback, which fail if they are used.
+func implementationFixture(gvk k8schema.GroupVersionKind, tfName string) *Implementation {
+	return &Implementation{
+		GVK:                   gvk,
+		TerraformResourceName: tfName,
+		SchemeBuilder:         &scheme.Builder{GroupVersion: gvk.GroupVersion()},
+		ReconcilerConfigurer:  nopReconcilerConfigurer{},
+		ResourceMerger:        nopMerger{},
+		CtyEncoder:            &mockCtyEncoder{"encode"},
+		CtyDecoder:            &mockCtyDecoder{"decode"},
+	}
+}
+
 func TestIndexerAddThenLookup(t *testing.T) {
 	idxr := NewIndexer()
 	err := idxr.Overlay(&Implementation{})
@@ -22,9 +55,7 @@ func TestIndexerAddThenLookup(t *testing.T) {
 		t.Errorf("Expected error when attempting to index a functable with no gvk")
 	}
 	gvk := gvkFixture()
-	f := &Implementation{
-		GVK: gvk,
-	}
+	f := implementationFixture(gvk, "fake_resource")
 	err = idxr.Overlay(f)
 	if err != nil {
 		t.Errorf("Unexpected error calling Overlay with gvk=%s", gvk.String())
@@ -40,6 +71,14 @@ func TestIndexerAddThenLookup(t *testing.T) {
 	}
 }
 
+type mockCtyEncoder struct {
+	fakeError string
+}
+
+func (mock *mockCtyEncoder) EncodeCty(xpresource.Managed, *providers.Schema) (cty.Value, error) {
+	return cty.NilVal, fmt.Errorf("%s", mock.fakeError)
+}
+
 type mockCtyDecoder struct {
 	fakeError string
 }
@@ -60,11 +99,9 @@ func TestIndexerLayerMerging(t *testing.T) {
 	idxr := NewIndexer()
 
 	gvk := gvkFixture()
-	f1 := &Implementation{
-		GVK:                    gvk,
-		CtyDecoder:             &mockCtyDecoder{"f1"},
-		ResourceYAMLMarshaller: &mockYAMLMarshaller{"f1"},
-	}
+	f1 := implementationFixture(gvk, "fake_resource")
+	f1.CtyDecoder = &mockCtyDecoder{"f1"}
+	f1.ResourceYAMLMarshaller = &mockYAMLMarshaller{"f1"}
 	f2 := &Implementation{
 		GVK:        gvk,
 		CtyDecoder: &mockCtyDecoder{"f2"},
@@ -88,3 +125,151 @@ func TestIndexerLayerMerging(t *testing.T) {
 		t.Errorf("Unexpected error, expected to see f2 occlude f1, instead saw err=%s", err.Error())
 	}
 }
+
+func TestBuildIndexReportsEveryProblem(t *testing.T) {
+	idxr := NewIndexer()
+	incomplete := k8schema.FromAPIVersionAndKind("test.crossplane.io/v1alpha1", "Incomplete")
+	idxr.Overlay(&Implementation{GVK: incomplete, CtyEncoder: &mockCtyEncoder{"f1"}})
+	first := k8schema.FromAPIVersionAndKind("test.crossplane.io/v1alpha1", "First")
+	second := k8schema.FromAPIVersionAndKind("test.crossplane.io/v1alpha1", "Second")
+	idxr.Overlay(implementationFixture(first, "fake_resource"))
+	idxr.Overlay(implementationFixture(second, "fake_resource"))
+
+	_, err := idxr.BuildIndex()
+	if err == nil {
+		t.Fatal("Expected an error building an Index with incomplete and conflicting Implementations")
+	}
+	for _, want := range []string{
+		"Incomplete is missing TerraformResourceName, SchemeBuilder, ReconcilerConfigurer, ResourceMerger, CtyDecoder",
+		"Kind=First and test.crossplane.io/v1alpha1, Kind=Second both implement terraform resource fake_resource",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Expected the error to report %q, got:\n%s", want, err)
+		}
+	}
+}
+
+func TestIndexValidateSchema(t *testing.T) {
+	idxr := NewIndexer()
+	idxr.Overlay(implementationFixture(gvkFixture(), "fake_resource"))
+	idx, err := idxr.BuildIndex()
+	if err != nil {
+		t.Fatalf("Unexpected error calling BuildIndex: %s", err)
+	}
+	if err := idx.ValidateSchema(map[string]providers.Schema{"fake_resource": {}}); err != nil {
+		t.Errorf("Unexpected error validating a matching schema: %s", err)
+	}
+	err = idx.ValidateSchema(map[string]providers.Schema{"fake_other": {}})
+	if err == nil || !strings.Contains(err.Error(), "terraform resource fake_resource, which the provider does not have") {
+		t.Errorf("Expected an error for a resource missing from the schema, got %v", err)
+	}
+}
+
+func TestIndexIntrospection(t *testing.T) {
+	idxr := NewIndexer()
+	gvk := gvkFixture()
+	override := &Implementation{GVK: gvk, CtyDecoder: &mockCtyDecoder{"override"}}
+	idxr.Overlay(implementationFixture(gvk, "fake_resource"))
+	idxr.Overlay(override)
+	idx, err := idxr.BuildIndex()
+	if err != nil {
+		t.Fatalf("Unexpected error calling BuildIndex: %s", err)
+	}
+
+	if gvks := idx.GVKs(); len(gvks) != 1 || gvks[0] != gvk {
+		t.Errorf("Expected GVKs to list %s, got %v", gvk, gvks)
+	}
+	if got, ok := idx.GVKForTerraformName("fake_resource"); !ok || got != gvk {
+		t.Errorf("Expected fake_resource to map to %s, got %s", gvk, got)
+	}
+	if got, ok := idx.TerraformNameForGVK(gvk); !ok || got != "fake_resource" {
+		t.Errorf("Expected %s to map to fake_resource, got %q", gvk, got)
+	}
+	inv, err := idx.InvokerForTerraformName("fake_resource")
+	if err != nil || inv.GVK() != gvk {
+		t.Errorf("Unexpected Invoker for fake_resource, gvk=%s err=%v", inv.GVK(), err)
+	}
+	if _, err := idx.InvokerForTerraformName("fake_missing"); err == nil {
+		t.Errorf("Expected an error looking up a terraform resource without an Implementation")
+	}
+
+	d, ok := idx.Describe(gvk)
+	if !ok || d.Layers != 2 {
+		t.Fatalf("Expected a Description of 2 layers, got %+v", d)
+	}
+	want := map[string]FieldProvenance{
+		"GVK":                    {Field: "GVK", Layer: 1, Value: gvk.String()},
+		"CtyEncoder":             {Field: "CtyEncoder", Layer: 0, Value: "*plugin.mockCtyEncoder"},
+		"CtyDecoder":             {Field: "CtyDecoder", Layer: 1, Value: "*plugin.mockCtyDecoder"},
+		"ResourceYAMLMarshaller": {Field: "ResourceYAMLMarshaller", Layer: -1},
+	}
+	for _, fp := range d.Fields {
+		if w, ok := want[fp.Field]; ok && !reflect.DeepEqual(fp, w) {
+			t.Errorf("Expected provenance %+v, got %+v", w, fp)
+		}
+	}
+}
+
+func TestDecoratorsWrapLowerLayers(t *testing.T) {
+	idxr := NewIndexer()
+	gvk := gvkFixture()
+	base := implementationFixture(gvk, "fake_resource")
+	base.ResourceMerger = ResourceMergerFunc(func(local, observed xpresource.Managed) MergeDescription {
+		return MergeDescription{StatusUpdated: true}
+	})
+	// adds to what the generated merger did, rather than replacing it
+	withAnnotations := func(next ResourceMerger) ResourceMerger {
+		return ResourceMergerFunc(func(local, observed xpresource.Managed) MergeDescription {
+			md := next.MergeResources(local, observed)
+			md.AnnotationsUpdated = true
+			return md
+		})
+	}
+	wrapError := func(next CtyDecoder) CtyDecoder {
+		return CtyDecoderFunc(func(r xpresource.Managed, v cty.Value, s *providers.Schema) (xpresource.Managed, error) {
+			_, err := next.DecodeCty(r, v, s)
+			return nil, fmt.Errorf("wrapped %s", err)
+		})
+	}
+	idxr.Overlay(base)
+	idxr.Overlay(&Implementation{GVK: gvk, Decorators: Decorators{ResourceMerger: withAnnotations}})
+	idxr.Overlay(&Implementation{GVK: gvk, Decorators: Decorators{CtyDecoder: wrapError}})
+	idxr.Overlay(&Implementation{GVK: gvk, Decorators: Decorators{CtyDecoder: wrapError}})
+	idx, err := idxr.BuildIndex()
+	if err != nil {
+		t.Fatalf("Unexpected error calling BuildIndex: %s", err)
+	}
+	inv, _ := idx.InvokerForGVK(gvk)
+
+	md, _ := inv.MergeResources(&fake.Managed{}, &fake.Managed{}, nil)
+	if !md.StatusUpdated || !md.AnnotationsUpdated {
+		t.Errorf("Expected the decorated merger to keep the lower layer's result and add to it, got %+v", md)
+	}
+	if _, err := inv.DecodeCty(&fake.Managed{}, cty.NilVal, &providers.Schema{}); err == nil || err.Error() != "wrapped wrapped decode" {
+		t.Errorf("Expected decorators to apply in overlay order, got %v", err)
+	}
+	d, _ := idx.Describe(gvk)
+	for _, fp := range d.Fields {
+		if fp.Field == "CtyDecoder" && (fp.Layer != 0 || !reflect.DeepEqual(fp.DecoratedBy, []int{2, 3})) {
+			t.Errorf("Expected CtyDecoder from layer 0 decorated by layers 2 and 3, got %+v", fp)
+		}
+	}
+}
+
+func TestDecoratorOfUnsetCallbackIsReported(t *testing.T) {
+	idxr := NewIndexer()
+	gvk := gvkFixture()
+	base := implementationFixture(gvk, "fake_resource")
+	base.CtyEncoder = nil
+	passThrough := func(next CtyEncoder) CtyEncoder {
+		return CtyEncoderFunc(func(r xpresource.Managed, s *providers.Schema) (cty.Value, error) {
+			return next.EncodeCty(r, s)
+		})
+	}
+	idxr.Overlay(base)
+	idxr.Overlay(&Implementation{GVK: gvk, Decorators: Decorators{CtyEncoder: passThrough}})
+	_, err := idxr.BuildIndex()
+	if err == nil || !strings.Contains(err.Error(), "is missing CtyEncoder") || !strings.Contains(err.Error(), "decorates unset CtyEncoder in layer 1") {
+		t.Errorf("Expected BuildIndex to report the missing, decorated CtyEncoder, got %v", err)
+	}
+}
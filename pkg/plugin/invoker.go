@@ -3,10 +3,13 @@ package plugin
 import (
 	"fmt"
 
+	"github.com/crossplane/crossplane-runtime/pkg/logging"
 	xpresource "github.com/crossplane/crossplane-runtime/pkg/resource"
+	"github.com/crossplane/terraform-provider-runtime/pkg/client"
 	"github.com/hashicorp/terraform/providers"
 	"github.com/zclconf/go-cty/cty"
 	k8schema "k8s.io/apimachinery/pkg/runtime/schema"
+	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/scheme"
 )
 
@@ -22,6 +25,19 @@ func (a *Invoker) TerraformResourceName() string {
 	return a.ft.TerraformResourceName
 }
 
+// ExternalClientFns returns the lifecycle overrides of the Implementation.
+func (a *Invoker) ExternalClientFns() ExternalClientFns {
+	return a.ft.ExternalClientFns
+}
+
+// ConfigureReconciler sets up the controller of the Implementation with o.
+func (a *Invoker) ConfigureReconciler(mgr ctrl.Manager, l logging.Logger, idx *Index, pool *client.ProviderPool, o ReconcilerOptions) error {
+	if a.ft.ReconcilerConfigurer == nil {
+		return fmt.Errorf("Cannot lookup ReconcilerConfigurer for GVK=%s", a.ft.GVK.String())
+	}
+	return a.ft.ReconcilerConfigurer.ConfigureReconciler(mgr, l, idx, pool, o)
+}
+
 func (a *Invoker) EncodeCty(r xpresource.Managed, s *providers.Schema) (cty.Value, error) {
 	if a.ft.CtyEncoder == nil {
 		return cty.Value{}, fmt.Errorf("Cannot lookup EncodeCty for GVK=%s", a.ft.GVK.String())
@@ -57,9 +73,64 @@ func (a *Invoker) MarshalResourceYaml(r xpresource.Managed) ([]byte, error) {
 	return a.ft.ResourceYAMLMarshaller.MarshalResourceYAML(r)
 }
 
-func (a *Invoker) MergeResources(f xpresource.Managed, t xpresource.Managed) (MergeDescription, error) {
+// MergeResources merges t into f. ContextResourceMergers are passed the
+// schema s, which may be nil, and the Implementation's codec.
+func (a *Invoker) MergeResources(f xpresource.Managed, t xpresource.Managed, s *providers.Schema) (MergeDescription, error) {
 	if a.ft.ResourceMerger == nil {
 		return MergeDescription{}, fmt.Errorf("Cannot lookup MergeResources() for GVK=%s", a.ft.GVK.String())
 	}
-	return a.ft.ResourceMerger.MergeResources(f, t), nil
+	mc := MergeContext{Schema: s, Encoder: a.ft.CtyEncoder, Decoder: a.ft.CtyDecoder}
+	return MergeInContext(a.ft.ResourceMerger, f, t, mc), nil
+}
+
+// BeforeEncode runs the BeforeEncode hooks on a copy of r, returning the
+// copy to encode.
+func (a *Invoker) BeforeEncode(op Operation, r xpresource.Managed) (xpresource.Managed, error) {
+	if len(a.ft.Hooks.BeforeEncode) == 0 {
+		return r, nil
+	}
+	r = r.DeepCopyObject().(xpresource.Managed)
+	for _, h := range a.ft.Hooks.BeforeEncode {
+		if err := h(op, r); err != nil {
+			return nil, a.hookError("BeforeEncode", op, err)
+		}
+	}
+	return r, nil
+}
+
+// AfterEncode runs the AfterEncode hooks, returning the value to send to
+// the provider.
+func (a *Invoker) AfterEncode(op Operation, r xpresource.Managed, v cty.Value, s *providers.Schema) (cty.Value, error) {
+	for _, h := range a.ft.Hooks.AfterEncode {
+		var err error
+		if v, err = h(op, r, v, s); err != nil {
+			return cty.NilVal, a.hookError("AfterEncode", op, err)
+		}
+	}
+	return v, nil
+}
+
+// AfterApply runs the AfterApply hooks.
+func (a *Invoker) AfterApply(op Operation, r xpresource.Managed, newState cty.Value) error {
+	for _, h := range a.ft.Hooks.AfterApply {
+		if err := h(op, r, newState); err != nil {
+			return a.hookError("AfterApply", op, err)
+		}
+	}
+	return nil
+}
+
+// BeforeDelete runs the BeforeDelete hooks, any of which can veto the
+// deletion.
+func (a *Invoker) BeforeDelete(r xpresource.Managed) error {
+	for _, h := range a.ft.Hooks.BeforeDelete {
+		if err := h(r); err != nil {
+			return a.hookError("BeforeDelete", OperationDelete, err)
+		}
+	}
+	return nil
+}
+
+func (a *Invoker) hookError(hook string, op Operation, err error) error {
+	return &HookError{Hook: hook, Operation: op, GVK: a.ft.GVK, Err: err}
 }
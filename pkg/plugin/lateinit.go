@@ -0,0 +1,176 @@
+package plugin
+
+import (
+	"reflect"
+	"sort"
+
+	xpresource "github.com/crossplane/crossplane-runtime/pkg/resource"
+	"github.com/hashicorp/terraform/configs/configschema"
+	"github.com/hashicorp/terraform/providers"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// MergeContext is what a ContextResourceMerger is merged with, besides the
+// resources.
+type MergeContext struct {
+	Schema  *providers.Schema
+	Encoder CtyEncoder
+	Decoder CtyDecoder
+}
+
+// A ContextResourceMerger needs the provider schema, or the codec of its
+// Implementation, to merge resources. The Invoker calls
+// MergeResourcesInContext when it has a schema. Decorators should pass the
+// MergeContext on to the ResourceMerger they decorate, through
+// ContextResourceMergerFunc and MergeInContext.
+type ContextResourceMerger interface {
+	ResourceMerger
+	MergeResourcesInContext(local, observed xpresource.Managed, mc MergeContext) MergeDescription
+}
+
+// MergeInContext merges with m, in context if it supports it.
+func MergeInContext(m ResourceMerger, local, observed xpresource.Managed, mc MergeContext) MergeDescription {
+	if cm, ok := m.(ContextResourceMerger); ok && mc.Schema != nil {
+		return cm.MergeResourcesInContext(local, observed, mc)
+	}
+	return m.MergeResources(local, observed)
+}
+
+// A LateInitializer fills the attributes of a resource that are Optional
+// and Computed in the provider schema, and unset locally, with their
+// observed values. Attributes of nested blocks that are single objects are
+// filled too; collections of blocks are left alone.
+//
+// It can be the ResourceMerger of the lowest layer of an Implementation,
+// or decorate the ResourceMerger below it through LateInitialization, in
+// which case that ResourceMerger runs after it.
+type LateInitializer struct {
+	// Next merges the late-initialized resources. Without it, observed
+	// attributes are compared with local ones to detect needed updates.
+	Next ResourceMerger
+	// Ignore lists attribute paths that are never late-initialized, like
+	// zone or boot_disk.size.
+	Ignore map[string]bool
+}
+
+// NewLateInitializer returns a LateInitializer running before next, which
+// may be nil, and ignoring the given attribute paths.
+func NewLateInitializer(next ResourceMerger, ignore ...string) *LateInitializer {
+	li := &LateInitializer{Next: next, Ignore: make(map[string]bool, len(ignore))}
+	for _, path := range ignore {
+		li.Ignore[path] = true
+	}
+	return li
+}
+
+// LateInitialization returns a ResourceMerger decorator that
+// late-initializes resources before the decorated ResourceMerger runs.
+func LateInitialization(ignore ...string) func(ResourceMerger) ResourceMerger {
+	return func(next ResourceMerger) ResourceMerger {
+		return NewLateInitializer(next, ignore...)
+	}
+}
+
+// MergeResources cannot late-initialize without a schema, so it only runs
+// the next ResourceMerger.
+func (li *LateInitializer) MergeResources(local, observed xpresource.Managed) MergeDescription {
+	if li.Next == nil {
+		return MergeDescription{}
+	}
+	return li.Next.MergeResources(local, observed)
+}
+
+// MergeResourcesInContext late-initializes local from observed, then runs
+// the next ResourceMerger.
+func (li *LateInitializer) MergeResourcesInContext(local, observed xpresource.Managed, mc MergeContext) MergeDescription {
+	md := MergeDescription{}
+	localVal, lerr := mc.Encoder.EncodeCty(local, mc.Schema)
+	observedVal, oerr := mc.Encoder.EncodeCty(observed, mc.Schema)
+	if lerr == nil && oerr == nil {
+		filled, paths := li.fill(mc.Schema.Block, localVal, observedVal, "")
+		if len(paths) > 0 {
+			if decoded, err := mc.Decoder.DecodeCty(local, filled, mc.Schema); err == nil && replace(local, decoded) {
+				localVal = filled
+				sort.Strings(paths)
+				md.LateInitializedSpec = true
+				md.LateInitializedPaths = paths
+			}
+		}
+		if li.Next == nil {
+			md.NeedsProviderUpdate = !configEqual(mc.Schema.Block, localVal, observedVal)
+		}
+	}
+	if li.Next == nil {
+		return md
+	}
+	next := MergeInContext(li.Next, local, observed, mc)
+	next.LateInitializedSpec = next.LateInitializedSpec || md.LateInitializedSpec
+	next.LateInitializedPaths = append(md.LateInitializedPaths, next.LateInitializedPaths...)
+	return next
+}
+
+// fill returns local with the Optional and Computed attributes it does not
+// set taken from observed, along with the paths it filled.
+func (li *LateInitializer) fill(block *configschema.Block, local, observed cty.Value, prefix string) (cty.Value, []string) {
+	if local.IsNull() || observed.IsNull() || !local.IsKnown() || !observed.IsKnown() {
+		return local, nil
+	}
+	attrs := local.AsValueMap()
+	var paths []string
+	for name, attr := range block.Attributes {
+		path := prefix + name
+		if !attr.Optional || !attr.Computed || li.Ignore[path] {
+			continue
+		}
+		if attrs[name].IsNull() && !observed.GetAttr(name).IsNull() {
+			attrs[name] = observed.GetAttr(name)
+			paths = append(paths, path)
+		}
+	}
+	for name, nb := range block.BlockTypes {
+		if nb.Nesting != configschema.NestingSingle && nb.Nesting != configschema.NestingGroup {
+			continue
+		}
+		var filled []string
+		attrs[name], filled = li.fill(&nb.Block, attrs[name], observed.GetAttr(name), prefix+name+".")
+		paths = append(paths, filled...)
+	}
+	if len(paths) == 0 {
+		return local, nil
+	}
+	return cty.ObjectVal(attrs), paths
+}
+
+// configEqual compares the attributes of local and observed that users can
+// set, recursing into nested blocks.
+func configEqual(block *configschema.Block, local, observed cty.Value) bool {
+	if local.IsNull() || observed.IsNull() {
+		return local.IsNull() == observed.IsNull()
+	}
+	for name, attr := range block.Attributes {
+		if !attr.Optional && !attr.Required {
+			continue
+		}
+		if eq := local.GetAttr(name).Equals(observed.GetAttr(name)); !eq.IsKnown() || eq.False() {
+			return false
+		}
+	}
+	for name := range block.BlockTypes {
+		// blocks have no computed-only parts the provider could add
+		if eq := local.GetAttr(name).Equals(observed.GetAttr(name)); !eq.IsKnown() || eq.False() {
+			return false
+		}
+	}
+	return true
+}
+
+// replace overwrites the object dst points to with the one src points to,
+// reporting whether they were of the same type.
+func replace(dst, src xpresource.Managed) bool {
+	d, s := reflect.ValueOf(dst), reflect.ValueOf(src)
+	if d.Kind() != reflect.Ptr || d.Type() != s.Type() || s.IsNil() {
+		return false
+	}
+	d.Elem().Set(s.Elem())
+	return true
+}
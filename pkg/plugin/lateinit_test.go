@@ -0,0 +1,120 @@
+package plugin
+
+import (
+	"reflect"
+	"testing"
+
+	xpresource "github.com/crossplane/crossplane-runtime/pkg/resource"
+	"github.com/crossplane/crossplane-runtime/pkg/resource/fake"
+	"github.com/hashicorp/terraform/configs/configschema"
+	"github.com/hashicorp/terraform/providers"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// ctyManaged is a managed resource that is its own cty value.
+type ctyManaged struct {
+	fake.Managed
+	val cty.Value
+}
+
+type ctyManagedCodec struct{}
+
+func (ctyManagedCodec) EncodeCty(r xpresource.Managed, _ *providers.Schema) (cty.Value, error) {
+	return r.(*ctyManaged).val, nil
+}
+
+func (ctyManagedCodec) DecodeCty(_ xpresource.Managed, v cty.Value, _ *providers.Schema) (xpresource.Managed, error) {
+	return &ctyManaged{val: v}, nil
+}
+
+func TestLateInitializer(t *testing.T) {
+	s := &providers.Schema{Block: &configschema.Block{
+		Attributes: map[string]*configschema.Attribute{
+			"name":   {Type: cty.String, Required: true},
+			"zone":   {Type: cty.String, Optional: true, Computed: true},
+			"region": {Type: cty.String, Optional: true, Computed: true},
+			"id":     {Type: cty.String, Computed: true},
+		},
+		BlockTypes: map[string]*configschema.NestedBlock{
+			"boot_disk": {Nesting: configschema.NestingSingle, Block: configschema.Block{
+				Attributes: map[string]*configschema.Attribute{
+					"size": {Type: cty.Number, Optional: true, Computed: true},
+				},
+			}},
+		},
+	}}
+	disk := func(size cty.Value) cty.Value {
+		return cty.ObjectVal(map[string]cty.Value{"size": size})
+	}
+	local := &ctyManaged{val: cty.ObjectVal(map[string]cty.Value{
+		"name":      cty.StringVal("a"),
+		"zone":      cty.NullVal(cty.String),
+		"region":    cty.NullVal(cty.String),
+		"id":        cty.NullVal(cty.String),
+		"boot_disk": disk(cty.NullVal(cty.Number)),
+	})}
+	observed := &ctyManaged{val: cty.ObjectVal(map[string]cty.Value{
+		"name":      cty.StringVal("a"),
+		"zone":      cty.StringVal("z"),
+		"region":    cty.StringVal("r"),
+		"id":        cty.StringVal("1"),
+		"boot_disk": disk(cty.NumberIntVal(10)),
+	})}
+	mc := MergeContext{Schema: s, Encoder: ctyManagedCodec{}, Decoder: ctyManagedCodec{}}
+
+	md := MergeInContext(NewLateInitializer(nil, "region"), local, observed, mc)
+	if want := []string{"boot_disk.size", "zone"}; !reflect.DeepEqual(md.LateInitializedPaths, want) {
+		t.Errorf("filled %v, want %v", md.LateInitializedPaths, want)
+	}
+	if !md.LateInitializedSpec {
+		t.Error("expected LateInitializedSpec")
+	}
+	if got := local.val.GetAttr("zone"); !got.RawEquals(cty.StringVal("z")) {
+		t.Errorf("zone = %#v, want z", got)
+	}
+	if !local.val.GetAttr("region").IsNull() {
+		t.Error("region is opted out of late-initialization")
+	}
+	if !md.NeedsProviderUpdate {
+		t.Error("local and observed regions differ, an update is needed")
+	}
+
+	md = MergeInContext(NewLateInitializer(nil), local, observed, mc)
+	if !reflect.DeepEqual(md.LateInitializedPaths, []string{"region"}) || md.NeedsProviderUpdate {
+		t.Errorf("unexpected second merge %+v", md)
+	}
+}
+
+func TestLateInitializationUnderDecorators(t *testing.T) {
+	s := &providers.Schema{Block: &configschema.Block{
+		Attributes: map[string]*configschema.Attribute{
+			"zone": {Type: cty.String, Optional: true, Computed: true},
+		},
+	}}
+	gvk := gvkFixture()
+	base := implementationFixture(gvk, "fake_resource")
+	base.CtyEncoder, base.CtyDecoder = ctyManagedCodec{}, ctyManagedCodec{}
+	annotate := func(next ResourceMerger) ResourceMerger {
+		return ContextResourceMergerFunc(func(local, observed xpresource.Managed, mc MergeContext) MergeDescription {
+			md := MergeInContext(next, local, observed, mc)
+			md.AnnotationsUpdated = true
+			return md
+		})
+	}
+	idxr := NewIndexer()
+	idxr.Overlay(base)
+	idxr.Overlay(&Implementation{GVK: gvk, Decorators: Decorators{ResourceMerger: LateInitialization()}})
+	idxr.Overlay(&Implementation{GVK: gvk, Decorators: Decorators{ResourceMerger: annotate}})
+	idx, err := idxr.BuildIndex()
+	if err != nil {
+		t.Fatalf("Unexpected error from BuildIndex: %s", err)
+	}
+	inv, _ := idx.InvokerForGVK(gvk)
+
+	local := &ctyManaged{val: cty.ObjectVal(map[string]cty.Value{"zone": cty.NullVal(cty.String)})}
+	observed := &ctyManaged{val: cty.ObjectVal(map[string]cty.Value{"zone": cty.StringVal("z")})}
+	md, _ := inv.MergeResources(local, observed, s)
+	if !md.AnnotationsUpdated || !md.LateInitializedSpec {
+		t.Errorf("Expected the decorator to pass the MergeContext to the LateInitializer below it, got %+v", md)
+	}
+}
@@ -0,0 +1,137 @@
+// Package plugintest provides a complete Implementation of a fake managed
+// resource, for testing code that is driven by a plugin.Index. It pairs with
+// the fake provider in pkg/client/fake.
+package plugintest
+
+import (
+	"github.com/crossplane/crossplane-runtime/pkg/logging"
+	xpresource "github.com/crossplane/crossplane-runtime/pkg/resource"
+	xpfake "github.com/crossplane/crossplane-runtime/pkg/resource/fake"
+	"github.com/hashicorp/terraform/providers"
+	"github.com/zclconf/go-cty/cty"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8schema "k8s.io/apimachinery/pkg/runtime/schema"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/scheme"
+	"sigs.k8s.io/yaml"
+
+	"github.com/crossplane/terraform-provider-runtime/pkg/client"
+	"github.com/crossplane/terraform-provider-runtime/pkg/plugin"
+)
+
+// ResourceName is the terraform resource type of the fake resource.
+const ResourceName = "fake_resource"
+
+// NameAttribute is set from the name of the managed resource, if the
+// resource type's schema has it.
+const NameAttribute = "name"
+
+// GVK is the kind of the fake managed resource.
+var GVK = k8schema.FromAPIVersionAndKind("test.crossplane.io/v1alpha1", "FakeResource")
+
+// Resource stores the string attributes of the fake resource in
+// annotations, standing in for generated spec and status fields, and reads
+// and writes it as a manifest.
+type Resource struct{}
+
+// Implementation returns an Implementation of the fake resource with every
+// callback set to a Resource.
+func Implementation() *plugin.Implementation {
+	return &plugin.Implementation{
+		GVK:                      GVK,
+		TerraformResourceName:    ResourceName,
+		SchemeBuilder:            &scheme.Builder{GroupVersion: GVK.GroupVersion()},
+		ReconcilerConfigurer:     Resource{},
+		ResourceMerger:           Resource{},
+		CtyEncoder:               Resource{},
+		CtyDecoder:               Resource{},
+		ResourceYAMLMarshaller:   Resource{},
+		ResourceYAMLUnmarshaller: Resource{},
+	}
+}
+
+// EncodeCty sets every string attribute of the schema from the annotation
+// of the same name, and the name attribute from the resource's name.
+func (Resource) EncodeCty(r xpresource.Managed, s *providers.Schema) (cty.Value, error) {
+	attrs := map[string]cty.Value{"id": cty.NullVal(cty.String), "size": cty.NullVal(cty.String)}
+	if s != nil && s.Block != nil {
+		attrs = make(map[string]cty.Value, len(s.Block.Attributes))
+		for name, attr := range s.Block.Attributes {
+			attrs[name] = cty.NullVal(attr.Type)
+		}
+	}
+	for k := range attrs {
+		if k == NameAttribute {
+			attrs[k] = cty.StringVal(r.GetName())
+			continue
+		}
+		if v, ok := r.GetAnnotations()[k]; ok {
+			attrs[k] = cty.StringVal(v)
+		}
+	}
+	return cty.ObjectVal(attrs), nil
+}
+
+// DecodeCty replaces the annotations with the string attributes that are
+// set, other than the name.
+func (Resource) DecodeCty(r xpresource.Managed, v cty.Value, s *providers.Schema) (xpresource.Managed, error) {
+	decoded := r.DeepCopyObject().(xpresource.Managed)
+	annotations := map[string]string{}
+	for k, t := range v.Type().AttributeTypes() {
+		if attr := v.GetAttr(k); k != NameAttribute && t == cty.String && !attr.IsNull() && attr.IsKnown() {
+			annotations[k] = attr.AsString()
+		}
+	}
+	decoded.SetAnnotations(annotations)
+	return decoded, nil
+}
+
+// MergeResources late-initializes size, and reports that the provider needs
+// an update when the local size differs from the observed one.
+func (Resource) MergeResources(local xpresource.Managed, observed xpresource.Managed) plugin.MergeDescription {
+	md := plugin.MergeDescription{}
+	annotations := local.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	want, have := annotations["size"], observed.GetAnnotations()["size"]
+	switch {
+	case want == "" && have != "":
+		annotations["size"] = have
+		md.LateInitializedSpec = true
+	case want != have:
+		md.NeedsProviderUpdate = true
+	}
+	if id := observed.GetAnnotations()["id"]; annotations["id"] != id {
+		annotations["id"] = id
+		md.AnnotationsUpdated = true
+	}
+	local.SetAnnotations(annotations)
+	return md
+}
+
+type manifest struct {
+	metav1.TypeMeta `json:",inline"`
+	Metadata        metav1.ObjectMeta `json:"metadata"`
+}
+
+// UnmarshalResourceYAML reads a manifest of the fake resource.
+func (Resource) UnmarshalResourceYAML(b []byte) (xpresource.Managed, error) {
+	m := manifest{}
+	if err := yaml.Unmarshal(b, &m); err != nil {
+		return nil, err
+	}
+	return &xpfake.Managed{ObjectMeta: m.Metadata}, nil
+}
+
+// MarshalResourceYAML writes the name and annotations of r as a manifest.
+func (Resource) MarshalResourceYAML(r xpresource.Managed) ([]byte, error) {
+	m := manifest{Metadata: metav1.ObjectMeta{Name: r.GetName(), Annotations: r.GetAnnotations()}}
+	m.SetGroupVersionKind(GVK)
+	return yaml.Marshal(m)
+}
+
+// ConfigureReconciler is a no-op; the fixture is never run in a manager.
+func (Resource) ConfigureReconciler(ctrl.Manager, logging.Logger, *plugin.Index, *client.ProviderPool, plugin.ReconcilerOptions) error {
+	return nil
+}
@@ -1,13 +1,37 @@
 package plugin
 
 import (
+	"fmt"
+	"strings"
+
 	"github.com/crossplane/terraform-provider-runtime/pkg/client"
 	k8schema "k8s.io/apimachinery/pkg/runtime/schema"
 	"sigs.k8s.io/controller-runtime/pkg/scheme"
 )
 
 type ProviderInit struct {
+	// ProviderName is the terraform provider name, eg google
+	ProviderName  string
 	GVK           k8schema.GroupVersionKind
 	SchemeBuilder *scheme.Builder
 	Initializer   client.Initializer
 }
+
+// Validate checks that the ProviderInit can start providers, reporting
+// every missing field at once.
+func (p *ProviderInit) Validate() error {
+	if p == nil {
+		return fmt.Errorf("Invalid ProviderInit: it is nil")
+	}
+	problems := make([]string, 0)
+	if p.ProviderName == "" {
+		problems = append(problems, "ProviderName must name the terraform provider, eg google")
+	}
+	if p.Initializer == nil {
+		problems = append(problems, "Initializer must be set to spawn and configure providers")
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("Invalid ProviderInit:\n  %s", strings.Join(problems, "\n  "))
+	}
+	return nil
+}
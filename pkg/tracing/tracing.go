@@ -0,0 +1,122 @@
+// Package tracing traces the provider runtime with OpenTelemetry: reconciles,
+// provider pool borrows, api operations and the gRPC calls to provider
+// plugins. Spans go to the global TracerProvider, which Setup installs.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	sdkresource "go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.4.0"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+)
+
+// Exporters Setup can send spans to.
+const (
+	ExporterNone   = ""
+	ExporterStdout = "stdout"
+	ExporterOTLP   = "otlp"
+)
+
+// DefaultOTLPEndpoint is the local OpenTelemetry collector.
+const DefaultOTLPEndpoint = "localhost:4317"
+
+const instrumentationName = "github.com/crossplane/terraform-provider-runtime"
+
+// Attributes the runtime tags its spans with.
+const (
+	AttributeGVK           = attribute.Key("crossplane.gvk")
+	AttributeName          = attribute.Key("crossplane.name")
+	AttributeTerraformType = attribute.Key("terraform.type")
+)
+
+// Setup installs a TracerProvider exporting spans to exporter, and returns
+// the function flushing and stopping it. With ExporterNone spans are not
+// recorded at all.
+func Setup(ctx context.Context, exporter, endpoint, serviceName string) (func(context.Context) error, error) {
+	var exp sdktrace.SpanExporter
+	var err error
+	switch exporter {
+	case ExporterNone:
+		return func(context.Context) error { return nil }, nil
+	case ExporterStdout:
+		exp, err = stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+	case ExporterOTLP:
+		if endpoint == "" {
+			endpoint = DefaultOTLPEndpoint
+		}
+		exp, err = otlptracegrpc.New(ctx, otlptracegrpc.WithEndpoint(endpoint), otlptracegrpc.WithInsecure())
+	default:
+		return nil, fmt.Errorf("unknown tracing exporter %q", exporter)
+	}
+	if err != nil {
+		return nil, err
+	}
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exp),
+		sdktrace.WithResource(sdkresource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceNameKey.String(serviceName))),
+	)
+	otel.SetTracerProvider(tp)
+	return tp.Shutdown, nil
+}
+
+// Start starts a span under the span in ctx.
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(instrumentationName).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// End records err, if any, on span and ends it.
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// ResourceAttributes identify a managed resource.
+func ResourceAttributes(res resource.Managed) []attribute.KeyValue {
+	if res == nil {
+		return nil
+	}
+	return []attribute.KeyValue{
+		AttributeGVK.String(res.GetObjectKind().GroupVersionKind().String()),
+		AttributeName.String(res.GetName()),
+	}
+}
+
+// WithParent returns ctx under the span of parent, unless ctx already is
+// under a span. It keeps the deadline and values of ctx.
+func WithParent(ctx, parent context.Context) context.Context {
+	if parent == nil || trace.SpanContextFromContext(ctx).IsValid() {
+		return ctx
+	}
+	return trace.ContextWithSpanContext(ctx, trace.SpanContextFromContext(parent))
+}
+
+// DialOptions trace the gRPC calls of a client connection. Calls made
+// without a span, like the ones providers make with the context they were
+// dispensed with, are put under the span of parent().
+func DialOptions(parent func() context.Context) []grpc.DialOption {
+	unary := otelgrpc.UnaryClientInterceptor()
+	stream := otelgrpc.StreamClientInterceptor()
+	return []grpc.DialOption{
+		grpc.WithChainUnaryInterceptor(func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+			return unary(WithParent(ctx, parent()), method, req, reply, cc, invoker, opts...)
+		}),
+		grpc.WithChainStreamInterceptor(func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+			return stream(WithParent(ctx, parent()), desc, cc, method, streamer, opts...)
+		}),
+	}
+}